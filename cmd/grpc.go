@@ -1,8 +1,10 @@
 package cmd
 
 import (
+	"context"
+	"errors"
+
 	"github.com/dyammarcano/fullcycle_clean_architecture/internal/adapter/grpc"
-	"github.com/dyammarcano/fullcycle_clean_architecture/internal/repository"
 	"github.com/dyammarcano/fullcycle_clean_architecture/internal/usecase"
 	"github.com/spf13/cobra"
 )
@@ -11,13 +13,22 @@ var grpcCmd = &cobra.Command{
 	Use:   "grpc",
 	Short: "Start gRPC server",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		orderRepo, err := repository.NewOrderPostgresRepository()
+		orderRepo, err := newOrderRepository(cmd)
 		if err != nil {
 			return err
 		}
 
-		orderServer := grpc.NewGrpcOrderServer(usecase.NewOrderUseCase(orderRepo))
-		return orderServer.Start()
+		relay, err := newOutboxRelay(orderRepo, orderRepo)
+		if err != nil {
+			return errors.Join(err, orderRepo.Close())
+		}
+
+		worker, err := newWebhookWorker(orderRepo)
+		if err != nil {
+			return errors.Join(err, relay.Shutdown(context.Background()), orderRepo.Close())
+		}
+
+		return runServers(cmd.Context(), orderRepo, grpc.NewGrpcOrderServer(usecase.NewOrderUseCase(orderRepo)), relay, worker)
 	},
 }
 
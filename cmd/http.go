@@ -1,8 +1,10 @@
 package cmd
 
 import (
+	"context"
+	"errors"
+
 	"github.com/dyammarcano/fullcycle_clean_architecture/internal/adapter/http"
-	"github.com/dyammarcano/fullcycle_clean_architecture/internal/repository"
 	"github.com/dyammarcano/fullcycle_clean_architecture/internal/usecase"
 	"github.com/spf13/cobra"
 )
@@ -11,13 +13,22 @@ var httpCmd = &cobra.Command{
 	Use:   "http",
 	Short: "Start HTTP server",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		orderRepo, err := repository.NewOrderPostgresRepository()
+		orderRepo, err := newOrderRepository(cmd)
 		if err != nil {
 			return err
 		}
 
-		orderServer := http.NewHttpOrderServer(usecase.NewOrderUseCase(orderRepo))
-		return orderServer.Start()
+		relay, err := newOutboxRelay(orderRepo, orderRepo)
+		if err != nil {
+			return errors.Join(err, orderRepo.Close())
+		}
+
+		worker, err := newWebhookWorker(orderRepo)
+		if err != nil {
+			return errors.Join(err, relay.Shutdown(context.Background()), orderRepo.Close())
+		}
+
+		return runServers(cmd.Context(), orderRepo, http.NewHttpOrderServer(usecase.NewOrderUseCase(orderRepo), usecase.NewWebhookUseCase(orderRepo)), relay, worker)
 	},
 }
 
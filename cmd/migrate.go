@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/dyammarcano/fullcycle_clean_architecture/internal/repository"
+	"github.com/spf13/cobra"
+)
+
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Manage the database schema of the storage backend",
+}
+
+var migrateUpCmd = &cobra.Command{
+	Use:   "up",
+	Short: "Apply every pending migration",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return withMigrator(cmd, func(m *repository.Migrator) error {
+			return m.Up()
+		})
+	},
+}
+
+var migrateDownCmd = &cobra.Command{
+	Use:   "down [N]",
+	Short: "Revert the last N migrations, or all of them when N is omitted",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		steps := 0
+		if len(args) == 1 {
+			n, err := strconv.Atoi(args[0])
+			if err != nil || n <= 0 {
+				return fmt.Errorf("invalid number of migrations %q", args[0])
+			}
+
+			steps = n
+		}
+
+		return withMigrator(cmd, func(m *repository.Migrator) error {
+			return m.Down(steps)
+		})
+	},
+}
+
+var migrateGotoCmd = &cobra.Command{
+	Use:   "goto VERSION",
+	Short: "Migrate up or down to VERSION",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		version, err := strconv.ParseUint(args[0], 10, 64)
+		if err != nil || version == 0 {
+			return fmt.Errorf("invalid version %q", args[0])
+		}
+
+		return withMigrator(cmd, func(m *repository.Migrator) error {
+			return m.Goto(uint(version))
+		})
+	},
+}
+
+var migrateForceCmd = &cobra.Command{
+	Use:   "force VERSION",
+	Short: "Set VERSION and clear the dirty flag without running migrations",
+	Long: "Set the schema version and clear the dirty flag without running any migration. " +
+		"Use it after fixing by hand a migration that failed halfway; -1 means no migration applied.",
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		version, err := strconv.Atoi(args[0])
+		if err != nil || version < -1 {
+			return fmt.Errorf("invalid version %q", args[0])
+		}
+
+		return withMigrator(cmd, func(m *repository.Migrator) error {
+			return m.Force(version)
+		})
+	},
+}
+
+var migrateStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the schema version and the pending migrations",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return withMigrator(cmd, func(m *repository.Migrator) error {
+			status, err := m.Status()
+			if err != nil {
+				return err
+			}
+
+			out := cmd.OutOrStdout()
+			_, _ = fmt.Fprintf(out, "driver: %s\nversion: %d\ndirty: %t\n\n", m.Driver(), status.Version, status.Dirty)
+
+			for _, migration := range status.Migrations {
+				state := "pending"
+				if migration.Applied {
+					state = "applied"
+				}
+
+				_, _ = fmt.Fprintf(out, "%06d  %-8s %s\n", migration.Version, state, migration.Name)
+			}
+
+			return nil
+		})
+	},
+}
+
+var migrateCreateCmd = &cobra.Command{
+	Use:   "create NAME",
+	Short: "Create empty up and down migration files",
+	Long: "Create empty up and down migration files numbered after the last migration of the " +
+		"storage backend. Run it from the module root; the files are embedded on the next build.",
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir, err := cmd.Flags().GetString("dir")
+		if err != nil {
+			return err
+		}
+
+		if dir == "" {
+			storage, err := cmd.Flags().GetString("storage")
+			if err != nil {
+				return err
+			}
+
+			if dir, err = repository.MigrationsDir(storage); err != nil {
+				return err
+			}
+		}
+
+		paths, err := repository.CreateMigration(dir, args[0])
+		for _, path := range paths {
+			_, _ = fmt.Fprintln(cmd.OutOrStdout(), path)
+		}
+
+		return err
+	},
+}
+
+// withMigrator runs fn with a migrator for the backend selected by --storage or db.driver
+func withMigrator(cmd *cobra.Command, fn func(m *repository.Migrator) error) error {
+	storage, err := cmd.Flags().GetString("storage")
+	if err != nil {
+		return err
+	}
+
+	m, err := repository.NewMigrator(storage)
+	if err != nil {
+		return err
+	}
+
+	if err = fn(m); err != nil {
+		_ = m.Close()
+		return err
+	}
+
+	return m.Close()
+}
+
+func init() {
+	migrateCreateCmd.Flags().String("dir", "", "directory of the migrations (default is the directory of the --storage backend)")
+
+	migrateCmd.AddCommand(migrateUpCmd, migrateDownCmd, migrateGotoCmd, migrateForceCmd, migrateStatusCmd, migrateCreateCmd)
+	rootCmd.AddCommand(migrateCmd)
+}
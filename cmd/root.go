@@ -1,10 +1,20 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"strings"
 
+	"github.com/dyammarcano/fullcycle_clean_architecture/internal/adapter/publisher"
+	"github.com/dyammarcano/fullcycle_clean_architecture/internal/adapter/webhook"
+	"github.com/dyammarcano/fullcycle_clean_architecture/internal/domain"
+	"github.com/dyammarcano/fullcycle_clean_architecture/internal/repository"
+	"github.com/dyammarcano/fullcycle_clean_architecture/internal/usecase"
+	"github.com/dyammarcano/fullcycle_clean_architecture/pkg/events"
 	"github.com/dyammarcano/fullcycle_clean_architecture/pkg/parameters"
 	"github.com/inovacc/config"
 
@@ -26,6 +36,7 @@ func init() {
 	cobra.OnInitialize(initConfig)
 
 	rootCmd.PersistentFlags().StringP("config", "c", "config.yaml", "config file (default is $binary_path/config.yaml)")
+	rootCmd.PersistentFlags().String("storage", "", fmt.Sprintf("storage backend, overrides db.driver (%s)", strings.Join(repository.Drivers(), ", ")))
 }
 
 // initConfig reads in the config file and ENV variables if set.
@@ -40,3 +51,121 @@ func initConfig() {
 		log.Fatalf("Failed to load config: %v", err)
 	}
 }
+
+// newOrderRepository builds the storage backend selected by --storage or db.driver.
+// Its order repository, outbox and webhooks are wired to their consumers separately.
+func newOrderRepository(cmd *cobra.Command) (repository.Store, error) {
+	storage, err := cmd.Flags().GetString("storage")
+	if err != nil {
+		return nil, err
+	}
+
+	return repository.New(storage)
+}
+
+// newOutboxRelay builds the relay that delivers the events stored in outbox to the
+// transport selected by events.publisher, tuned by events.relay, and queues them
+// in webhooks for the subscribed webhooks
+func newOutboxRelay(outbox domain.EventOutbox, webhooks domain.WebhookRepository) (server, error) {
+	cfg, err := config.GetServiceConfig[*parameters.Service]()
+	if err != nil {
+		return nil, err
+	}
+
+	transport, err := newEventPublisher(cfg.Events)
+	if err != nil {
+		return nil, err
+	}
+
+	pub := publisher.Fanout{transport, usecase.NewWebhookUseCase(webhooks)}
+
+	relay := cfg.Events.Relay
+
+	return relayServer{
+		OutboxRelay: usecase.NewOutboxRelay(outbox, pub, usecase.RelayOptions{
+			Interval:   relay.Interval,
+			BatchSize:  relay.BatchSize,
+			Lease:      relay.Lease,
+			MinBackoff: relay.MinBackoff,
+			MaxBackoff: relay.MaxBackoff,
+		}),
+		publisher: pub,
+	}, nil
+}
+
+// newWebhookWorker builds the worker that sends the deliveries queued in webhooks,
+// tuned by events.webhooks. The lease must outlast the request timeout, otherwise
+// another worker could claim a delivery that is still being sent.
+func newWebhookWorker(webhooks domain.WebhookRepository) (server, error) {
+	cfg, err := config.GetServiceConfig[*parameters.Service]()
+	if err != nil {
+		return nil, err
+	}
+
+	options := cfg.Events.Webhooks
+
+	timeout, lease := options.Timeout, options.Lease
+	if timeout <= 0 {
+		timeout = webhook.DefaultTimeout
+	}
+
+	if lease <= 0 {
+		lease = usecase.DefaultWebhookLease
+	}
+
+	if lease <= timeout {
+		return nil, fmt.Errorf("events.webhooks.lease (%s) must be longer than events.webhooks.timeout (%s)", lease, timeout)
+	}
+
+	sender := webhook.NewSender(cfg.Events.GetSource(), timeout)
+
+	return usecase.NewWebhookWorker(webhooks, sender, usecase.WebhookOptions{
+		Interval:    options.Interval,
+		BatchSize:   options.BatchSize,
+		Lease:       lease,
+		MinBackoff:  options.MinBackoff,
+		MaxBackoff:  options.MaxBackoff,
+		MaxAttempts: options.MaxAttempts,
+	}), nil
+}
+
+// newEventPublisher connects to the transport of the order events
+func newEventPublisher(cfg parameters.Events) (domain.EventPublisher, error) {
+	var (
+		transport events.Publisher
+		err       error
+	)
+
+	switch cfg.Publisher {
+	case "", "log":
+		return publisher.Log{}, nil
+	case "amqp":
+		transport, err = events.NewAMQPPublisher(events.AMQPOptions{URL: cfg.AMQP.URL, Exchange: cfg.AMQP.Exchange})
+	case "nats":
+		transport, err = events.NewNATSPublisher(events.NATSOptions{URL: cfg.NATS.URL, SubjectPrefix: cfg.NATS.SubjectPrefix})
+	default:
+		return nil, fmt.Errorf("unknown events.publisher %q (log, amqp, nats)", cfg.Publisher)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return publisher.CloudEvents{Publisher: transport, Source: cfg.GetSource()}, nil
+}
+
+// relayServer runs an outbox relay and closes its publisher once the relay stopped
+type relayServer struct {
+	*usecase.OutboxRelay
+	publisher domain.EventPublisher
+}
+
+func (s relayServer) Shutdown(ctx context.Context) error {
+	err := s.OutboxRelay.Shutdown(ctx)
+
+	if closer, ok := s.publisher.(io.Closer); ok {
+		err = errors.Join(err, closer.Close())
+	}
+
+	return err
+}
@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/dyammarcano/fullcycle_clean_architecture/internal/adapter/grpc"
+	"github.com/dyammarcano/fullcycle_clean_architecture/internal/adapter/http"
+	"github.com/dyammarcano/fullcycle_clean_architecture/internal/domain"
+	"github.com/dyammarcano/fullcycle_clean_architecture/internal/usecase"
+	"github.com/dyammarcano/fullcycle_clean_architecture/pkg/parameters"
+	"github.com/inovacc/config"
+	"github.com/spf13/cobra"
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Start HTTP (REST and GraphQL) and gRPC servers sharing one repository",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		orderRepo, err := newOrderRepository(cmd)
+		if err != nil {
+			return err
+		}
+
+		relay, err := newOutboxRelay(orderRepo, orderRepo)
+		if err != nil {
+			return errors.Join(err, orderRepo.Close())
+		}
+
+		worker, err := newWebhookWorker(orderRepo)
+		if err != nil {
+			// The relay was never started, so this only closes its publisher
+			return errors.Join(err, relay.Shutdown(context.Background()), orderRepo.Close())
+		}
+
+		useCase := usecase.NewOrderUseCase(orderRepo)
+
+		return runServers(cmd.Context(), orderRepo, http.NewHttpOrderServer(useCase, usecase.NewWebhookUseCase(orderRepo)), grpc.NewGrpcOrderServer(useCase), relay, worker)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+}
+
+// server is a transport, or a worker such as the outbox relay, that runs until it is shut down
+type server interface {
+	Start() error
+	Shutdown(ctx context.Context) error
+}
+
+// runServers starts every server and blocks until SIGINT or SIGTERM is received or
+// a server fails. In-flight requests are then drained within the configured
+// shutdown timeout and the repository is closed.
+func runServers(ctx context.Context, repo domain.OrderRepository, servers ...server) error {
+	cfg, err := config.GetServiceConfig[*parameters.Service]()
+	if err != nil {
+		return err
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	failed := make(chan error, len(servers))
+	for _, srv := range servers {
+		go func() {
+			if err := srv.Start(); err != nil {
+				failed <- err
+			}
+		}()
+	}
+
+	var runErr error
+	select {
+	case <-ctx.Done():
+		slog.Info("Shutting down", slog.String("timeout", cfg.GetShutdownTimeout().String()))
+	case runErr = <-failed:
+		slog.Error(">>> Server failed, shutting down: ", slog.String("error", runErr.Error()))
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.GetShutdownTimeout())
+	defer cancel()
+
+	errs := make(chan error, len(servers))
+	for _, srv := range servers {
+		go func() {
+			errs <- srv.Shutdown(shutdownCtx)
+		}()
+	}
+
+	for range servers {
+		runErr = errors.Join(runErr, <-errs)
+	}
+
+	return errors.Join(runErr, repo.Close())
+}
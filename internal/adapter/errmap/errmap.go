@@ -0,0 +1,111 @@
+// Package errmap translates domain error kinds into transport specific codes,
+// so REST, gRPC and GraphQL report the same failure in the same way.
+package errmap
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/dyammarcano/fullcycle_clean_architecture/internal/domain"
+	"google.golang.org/grpc/codes"
+)
+
+// InternalMessage replaces the message of errors that do not belong to a known kind,
+// so driver and infrastructure details are not exposed to clients
+const InternalMessage = "internal error"
+
+// ProblemTypeBase prefixes the problem type slug of RFC 7807 responses
+const ProblemTypeBase = "/problems/"
+
+// StatusClientClosedRequest is the non standard HTTP status used when the client
+// went away before the request completed
+const StatusClientClosedRequest = 499
+
+type mapping struct {
+	kind    error
+	http    int
+	grpc    codes.Code
+	graphql string
+	problem string
+}
+
+// mappings are matched in order, so specific errors come before their kind
+var mappings = []mapping{
+	{kind: domain.ErrOrderVersionMismatch, http: http.StatusPreconditionFailed, grpc: codes.Aborted, graphql: "CONFLICT", problem: "version-mismatch"},
+	{kind: domain.ErrOrderStatusChanged, http: http.StatusConflict, grpc: codes.Aborted, graphql: "CONFLICT", problem: "conflict"},
+	{kind: domain.ErrNotFound, http: http.StatusNotFound, grpc: codes.NotFound, graphql: "NOT_FOUND", problem: "not-found"},
+	{kind: domain.ErrConflict, http: http.StatusConflict, grpc: codes.AlreadyExists, graphql: "CONFLICT", problem: "conflict"},
+	{kind: domain.ErrFailedPrecondition, http: http.StatusConflict, grpc: codes.FailedPrecondition, graphql: "FAILED_PRECONDITION", problem: "failed-precondition"},
+	{kind: domain.ErrValidation, http: http.StatusBadRequest, grpc: codes.InvalidArgument, graphql: "BAD_USER_INPUT", problem: "validation"},
+	{kind: domain.ErrUnavailable, http: http.StatusServiceUnavailable, grpc: codes.Unavailable, graphql: "UNAVAILABLE", problem: "unavailable"},
+	{kind: context.DeadlineExceeded, http: http.StatusGatewayTimeout, grpc: codes.DeadlineExceeded, graphql: "DEADLINE_EXCEEDED", problem: "deadline-exceeded"},
+	{kind: context.Canceled, http: StatusClientClosedRequest, grpc: codes.Canceled, graphql: "CANCELLED", problem: "cancelled"},
+}
+
+var internal = mapping{http: http.StatusInternalServerError, grpc: codes.Internal, graphql: "INTERNAL_SERVER_ERROR", problem: "internal"}
+
+func lookup(err error) mapping {
+	for _, m := range mappings {
+		if errors.Is(err, m.kind) {
+			return m
+		}
+	}
+
+	return internal
+}
+
+// IsKnown reports whether err belongs to one of the domain error kinds or is a
+// context cancellation or deadline error
+func IsKnown(err error) bool {
+	return lookup(err).kind != nil
+}
+
+// Message returns the text that is safe to show to clients for err
+func Message(err error) string {
+	if !IsKnown(err) {
+		return InternalMessage
+	}
+
+	return err.Error()
+}
+
+// HTTPStatus returns the HTTP status code for err
+func HTTPStatus(err error) int {
+	return lookup(err).http
+}
+
+// HTTPTitle returns the short HTTP status text for err
+func HTTPTitle(err error) string {
+	status := HTTPStatus(err)
+	if status == StatusClientClosedRequest {
+		return "Client Closed Request"
+	}
+
+	return http.StatusText(status)
+}
+
+// GRPCCode returns the gRPC status code for err
+func GRPCCode(err error) codes.Code {
+	return lookup(err).grpc
+}
+
+// GraphQLCode returns the GraphQL extension code for err
+func GraphQLCode(err error) string {
+	return lookup(err).graphql
+}
+
+// ProblemType returns the RFC 7807 problem type URI for err
+func ProblemType(err error) string {
+	return ProblemTypeBase + lookup(err).problem
+}
+
+// FieldErrors returns the field level violations carried by err, if any
+func FieldErrors(err error) []domain.FieldError {
+	var validationErr *domain.ValidationError
+	if errors.As(err, &validationErr) {
+		return validationErr.Fields
+	}
+
+	return nil
+}
@@ -0,0 +1,53 @@
+package errmap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/dyammarcano/fullcycle_clean_architecture/internal/domain"
+	"google.golang.org/grpc/codes"
+)
+
+func TestMapping(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		http    int
+		grpc    codes.Code
+		graphql string
+		message string
+	}{
+		{"not found", domain.ErrOrderNotFound, http.StatusNotFound, codes.NotFound, "NOT_FOUND", "order not found"},
+		{"conflict", domain.ErrOrderAlreadyExists, http.StatusConflict, codes.AlreadyExists, "CONFLICT", "order already exists"},
+		{"status changed", domain.ErrOrderStatusChanged, http.StatusConflict, codes.Aborted, "CONFLICT", "order status changed concurrently"},
+		{"version mismatch", domain.ErrOrderVersionMismatch, http.StatusPreconditionFailed, codes.Aborted, "CONFLICT", "order was modified by another request"},
+		{"validation", fmt.Errorf("%w: bad id", domain.ErrValidation), http.StatusBadRequest, codes.InvalidArgument, "BAD_USER_INPUT", "validation failed: bad id"},
+		{"unavailable", domain.WrapError(domain.ErrUnavailable, "database unavailable", errors.New("dial tcp")), http.StatusServiceUnavailable, codes.Unavailable, "UNAVAILABLE", "database unavailable"},
+		{"deadline", fmt.Errorf("query orders: %w", context.DeadlineExceeded), http.StatusGatewayTimeout, codes.DeadlineExceeded, "DEADLINE_EXCEEDED", "query orders: context deadline exceeded"},
+		{"cancelled", context.Canceled, StatusClientClosedRequest, codes.Canceled, "CANCELLED", "context canceled"},
+		{"internal", errors.New("pq: secret detail"), http.StatusInternalServerError, codes.Internal, "INTERNAL_SERVER_ERROR", InternalMessage},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HTTPStatus(tt.err); got != tt.http {
+				t.Errorf("HTTPStatus() = %d, want %d", got, tt.http)
+			}
+
+			if got := GRPCCode(tt.err); got != tt.grpc {
+				t.Errorf("GRPCCode() = %v, want %v", got, tt.grpc)
+			}
+
+			if got := GraphQLCode(tt.err); got != tt.graphql {
+				t.Errorf("GraphQLCode() = %s, want %s", got, tt.graphql)
+			}
+
+			if got := Message(tt.err); got != tt.message {
+				t.Errorf("Message() = %q, want %q", got, tt.message)
+			}
+		})
+	}
+}
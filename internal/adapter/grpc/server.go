@@ -2,48 +2,325 @@ package grpc
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"log/slog"
+	"math"
 	"net"
 
+	"github.com/dyammarcano/fullcycle_clean_architecture/internal/adapter/errmap"
+	"github.com/dyammarcano/fullcycle_clean_architecture/internal/domain"
+	"github.com/dyammarcano/fullcycle_clean_architecture/internal/entity"
 	"github.com/dyammarcano/fullcycle_clean_architecture/internal/usecase"
 	"github.com/dyammarcano/fullcycle_clean_architecture/pkg/grpc/pb"
 	"github.com/dyammarcano/fullcycle_clean_architecture/pkg/parameters"
 	"github.com/inovacc/config"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 type OrderServer struct {
 	pb.UnimplementedOrderServiceServer
 
 	UseCase *usecase.OrderUseCase
+
+	server *grpc.Server
+	// closing is closed by Shutdown to end the WatchOrders streams, which would
+	// otherwise keep GracefulStop waiting
+	closing     <-chan struct{}
+	stopStreams context.CancelFunc
 }
 
 func (s *OrderServer) ListOrders(ctx context.Context, req *pb.ListOrdersRequest) (*pb.ListOrdersResponse, error) {
-	orders, err := s.UseCase.ListOrders()
+	sortBy, descending, err := domain.ParseOrderSort(req.GetOrderBy())
+	if err != nil {
+		return nil, toStatus(domain.NewValidationError(domain.FieldError{Field: "orderBy", Rule: "sort", Message: err.Error()}))
+	}
+
+	query := domain.ListOrdersQuery{
+		Filter:     domain.OrderFilter{ItemContains: req.GetItemContains()},
+		SortBy:     sortBy,
+		Descending: descending,
+		PageSize:   int(req.GetPageSize()),
+	}
+
+	if req.GetMinAmount() != nil {
+		minAmount := fromProtoMoney(req.GetMinAmount())
+		query.Filter.MinAmount = &minAmount
+	}
+
+	if req.GetMaxAmount() != nil {
+		maxAmount := fromProtoMoney(req.GetMaxAmount())
+		query.Filter.MaxAmount = &maxAmount
+	}
+
+	page, err := s.UseCase.ListOrders(ctx, query, req.GetPageToken())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	grpcOrders := make([]*pb.Order, 0, len(page.Orders))
+	for _, order := range page.Orders {
+		grpcOrder, err := toProto(order)
+		if err != nil {
+			return nil, err
+		}
+
+		grpcOrders = append(grpcOrders, grpcOrder)
+	}
+
+	return &pb.ListOrdersResponse{Orders: grpcOrders, NextPageToken: page.NextPageToken}, nil
+}
+
+func (s *OrderServer) CreateOrder(ctx context.Context, req *pb.CreateOrderRequest) (*pb.Order, error) {
+	order := &domain.Order{
+		Item:  req.GetItem(),
+		Lines: fromProtoLines(req.GetLines()),
+	}
+
+	created, err := s.UseCase.CreateOrder(ctx, order.Bytes())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return toProto(created)
+}
+
+func (s *OrderServer) GetOrder(ctx context.Context, req *pb.GetOrderRequest) (*pb.Order, error) {
+	if err := entity.ValidateID(int(req.GetId())); err != nil {
+		return nil, toStatus(err)
+	}
+
+	order, err := s.UseCase.GetOrderByID(ctx, int(req.GetId()))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return toProto(order)
+}
+
+func (s *OrderServer) UpdateOrder(ctx context.Context, req *pb.UpdateOrderRequest) (*pb.Order, error) {
+	if err := entity.ValidateID(int(req.GetId())); err != nil {
+		return nil, toStatus(err)
+	}
+
+	order := &domain.Order{
+		Item:    req.GetItem(),
+		Lines:   fromProtoLines(req.GetLines()),
+		Version: int(req.GetVersion()),
+	}
+
+	updated, err := s.UseCase.UpdateOrder(ctx, int(req.GetId()), order.Bytes())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return toProto(updated)
+}
+
+func (s *OrderServer) DeleteOrder(ctx context.Context, req *pb.DeleteOrderRequest) (*pb.DeleteOrderResponse, error) {
+	if err := entity.ValidateID(int(req.GetId())); err != nil {
+		return nil, toStatus(err)
+	}
+
+	if err := s.UseCase.DeleteOrder(ctx, int(req.GetId()), int(req.GetVersion())); err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &pb.DeleteOrderResponse{}, nil
+}
+
+func (s *OrderServer) TransitionOrder(ctx context.Context, req *pb.TransitionOrderRequest) (*pb.Order, error) {
+	if err := entity.ValidateID(int(req.GetId())); err != nil {
+		return nil, toStatus(err)
+	}
+
+	next, ok := fromProtoStatus[req.GetStatus()]
+	if !ok {
+		return nil, toStatus(domain.NewValidationError(domain.FieldError{
+			Field:   "status",
+			Rule:    "enum",
+			Message: "must be a known order status",
+		}))
+	}
+
+	order, err := s.UseCase.TransitionOrder(ctx, int(req.GetId()), next, req.GetReason())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return toProto(order)
+}
+
+func (s *OrderServer) GetOrderHistory(ctx context.Context, req *pb.GetOrderHistoryRequest) (*pb.GetOrderHistoryResponse, error) {
+	if err := entity.ValidateID(int(req.GetId())); err != nil {
+		return nil, toStatus(err)
+	}
+
+	history, err := s.UseCase.ListStatusHistory(ctx, int(req.GetId()))
 	if err != nil {
-		return nil, err
+		return nil, toStatus(err)
 	}
 
-	grpcOrders := make([]*pb.Order, 0, len(orders))
-	for _, order := range orders {
-		grpcOrders = append(grpcOrders, &pb.Order{
-			Id:     int32(order.ID),
-			Item:   order.Item,
-			Amount: order.Amount,
+	transitions := make([]*pb.StatusTransition, 0, len(history))
+	for _, t := range history {
+		transitions = append(transitions, &pb.StatusTransition{
+			From:   toProtoStatus[t.From],
+			To:     toProtoStatus[t.To],
+			Reason: t.Reason,
+			At:     timestamppb.New(t.At),
 		})
 	}
 
-	return &pb.ListOrdersResponse{Orders: grpcOrders}, nil
+	return &pb.GetOrderHistoryResponse{Transitions: transitions}, nil
+}
+
+var toProtoStatus = map[domain.OrderStatus]pb.OrderStatus{
+	domain.StatusPending:   pb.OrderStatus_ORDER_STATUS_PENDING,
+	domain.StatusConfirmed: pb.OrderStatus_ORDER_STATUS_CONFIRMED,
+	domain.StatusPaid:      pb.OrderStatus_ORDER_STATUS_PAID,
+	domain.StatusShipped:   pb.OrderStatus_ORDER_STATUS_SHIPPED,
+	domain.StatusDelivered: pb.OrderStatus_ORDER_STATUS_DELIVERED,
+	domain.StatusCancelled: pb.OrderStatus_ORDER_STATUS_CANCELLED,
+	domain.StatusRefunded:  pb.OrderStatus_ORDER_STATUS_REFUNDED,
+}
+
+var fromProtoStatus = func() map[pb.OrderStatus]domain.OrderStatus {
+	m := make(map[pb.OrderStatus]domain.OrderStatus, len(toProtoStatus))
+	for k, v := range toProtoStatus {
+		m[v] = k
+	}
+
+	return m
+}()
+
+// toProto converts a domain order into its protobuf representation. An ID or a
+// version beyond the int32 fields of the API is OutOfRange rather than wrapped.
+func toProto(order *domain.Order) (*pb.Order, error) {
+	if order.ID > math.MaxInt32 || order.Version > math.MaxInt32 {
+		return nil, status.Errorf(codes.OutOfRange, "order %d version %d does not fit the int32 fields of the gRPC API", order.ID, order.Version)
+	}
+
+	return &pb.Order{
+		Id:        int32(order.ID),
+		Item:      order.Item,
+		Amount:    toProtoMoney(order.Amount),
+		Lines:     toProtoLines(order.Lines),
+		Status:    toProtoStatus[order.Status],
+		CreatedAt: timestamppb.New(order.CreatedAt),
+		UpdatedAt: timestamppb.New(order.UpdatedAt),
+		Version:   int32(order.Version),
+	}, nil
+}
+
+// toProtoLines converts domain order lines into their protobuf representation
+func toProtoLines(lines []domain.OrderLine) []*pb.OrderLine {
+	pbLines := make([]*pb.OrderLine, 0, len(lines))
+	for _, line := range lines {
+		pbLines = append(pbLines, &pb.OrderLine{
+			Sku:         line.SKU,
+			Description: line.Description,
+			Quantity:    int32(line.Quantity),
+			UnitPrice:   toProtoMoney(line.UnitPrice),
+		})
+	}
+
+	return pbLines
+}
+
+// fromProtoLines converts protobuf order lines into domain order lines
+func fromProtoLines(pbLines []*pb.OrderLine) []domain.OrderLine {
+	lines := make([]domain.OrderLine, 0, len(pbLines))
+	for _, line := range pbLines {
+		lines = append(lines, domain.OrderLine{
+			SKU:         line.GetSku(),
+			Description: line.GetDescription(),
+			Quantity:    int(line.GetQuantity()),
+			UnitPrice:   fromProtoMoney(line.GetUnitPrice()),
+		})
+	}
+
+	return lines
+}
+
+// toProtoMoney converts a domain amount into its protobuf representation
+func toProtoMoney(m domain.Money) *pb.Money {
+	return &pb.Money{
+		Units:        m.Units,
+		CurrencyCode: m.Currency,
+	}
+}
+
+// fromProtoMoney converts a protobuf amount into a domain amount, defaulting the currency
+func fromProtoMoney(m *pb.Money) domain.Money {
+	currency := m.GetCurrencyCode()
+	if currency == "" {
+		currency = domain.DefaultCurrency
+	}
+
+	return domain.Money{Units: m.GetUnits(), Currency: currency}
+}
+
+// toStatus translates use case errors into gRPC status errors. Errors that already
+// carry a gRPC status are returned as they are.
+func toStatus(err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	if !errmap.IsKnown(err) {
+		slog.Error("gRPC request failed", slog.String("error", err.Error()))
+	}
+
+	st := status.New(errmap.GRPCCode(err), errmap.Message(err))
+
+	fields := errmap.FieldErrors(err)
+	if len(fields) == 0 {
+		return st.Err()
+	}
+
+	badRequest := &errdetails.BadRequest{}
+	for _, f := range fields {
+		badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       f.Field,
+			Description: f.Message,
+			Reason:      f.Rule,
+		})
+	}
+
+	detailed, detailErr := st.WithDetails(badRequest)
+	if detailErr != nil {
+		return st.Err()
+	}
+
+	return detailed.Err()
 }
 
 func NewGrpcOrderServer(useCase *usecase.OrderUseCase) *OrderServer {
-	orderServer := &OrderServer{UseCase: useCase}
+	streams, stopStreams := context.WithCancel(context.Background())
+
+	orderServer := &OrderServer{
+		UseCase:     useCase,
+		server:      grpc.NewServer(),
+		closing:     streams.Done(),
+		stopStreams: stopStreams,
+	}
+
+	pb.RegisterOrderServiceServer(orderServer.server, orderServer)
+
+	// Enable gRPC server reflection for tools like evans or grpcurl
+	reflection.Register(orderServer.server)
+
 	return orderServer
 }
 
+// Start serves gRPC on the configured port until Shutdown is called. It returns
+// nil once the server was shut down.
 func (s *OrderServer) Start() error {
 	cfg, err := config.GetServiceConfig[*parameters.Service]()
 	if err != nil {
@@ -55,13 +332,34 @@ func (s *OrderServer) Start() error {
 		return err
 	}
 
-	grpcServer := grpc.NewServer()
-	pb.RegisterOrderServiceServer(grpcServer, s)
+	slog.Info("gRPC server is running on port", slog.String("port", fmt.Sprintf(":%d", cfg.Grpc.Port)))
 
-	// Enable gRPC server reflection for tools like evans or grpcurl
-	reflection.Register(grpcServer)
+	if err = s.server.Serve(lis); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+		return err
+	}
 
-	slog.Info("gRPC server is running on port", slog.String("port", fmt.Sprintf(":%d", cfg.Grpc.Port)))
+	return nil
+}
+
+// Shutdown stops accepting calls and waits for the running ones to finish. When
+// ctx is done first the remaining calls are cancelled.
+func (s *OrderServer) Shutdown(ctx context.Context) error {
+	s.stopStreams()
+
+	stopped := make(chan struct{})
 
-	return grpcServer.Serve(lis)
+	go func() {
+		s.server.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		s.server.Stop()
+		<-stopped
+
+		return ctx.Err()
+	}
 }
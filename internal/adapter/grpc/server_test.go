@@ -0,0 +1,89 @@
+package grpc
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/dyammarcano/fullcycle_clean_architecture/internal/domain"
+	"github.com/dyammarcano/fullcycle_clean_architecture/internal/repository"
+	"github.com/dyammarcano/fullcycle_clean_architecture/internal/usecase"
+	"github.com/dyammarcano/fullcycle_clean_architecture/pkg/grpc/pb"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestOrderServer(t *testing.T) {
+	repo, err := repository.NewOrderMemoryRepository()
+	if err != nil {
+		t.Fatalf("Error creating repository")
+	}
+
+	server := NewGrpcOrderServer(usecase.NewOrderUseCase(repo))
+	ctx := context.Background()
+
+	created, err := server.CreateOrder(ctx, &pb.CreateOrderRequest{Item: "Bag", Lines: []*pb.OrderLine{
+		{Sku: "BAG-1", Quantity: 1, UnitPrice: &pb.Money{Units: 200, CurrencyCode: "USD"}},
+	}})
+	if err != nil {
+		t.Fatalf("Error creating order: %v", err)
+	}
+
+	if _, err = server.GetOrder(ctx, &pb.GetOrderRequest{Id: created.GetId()}); err != nil {
+		t.Errorf("Error getting order: %v", err)
+	}
+
+	updated, err := server.UpdateOrder(ctx, &pb.UpdateOrderRequest{Id: created.GetId(), Item: "Bag", Lines: []*pb.OrderLine{
+		{Sku: "BAG-1", Quantity: 2, UnitPrice: &pb.Money{Units: 200, CurrencyCode: "USD"}},
+		{Sku: "TAG-1", Quantity: 1, UnitPrice: &pb.Money{Units: 150}},
+	}})
+	if err != nil {
+		t.Errorf("Error updating order: %v", err)
+	}
+
+	if updated.GetAmount().GetUnits() != 550 {
+		t.Errorf("Expected 550 units, got %v", updated.GetAmount().GetUnits())
+	}
+
+	if _, err = server.DeleteOrder(ctx, &pb.DeleteOrderRequest{Id: created.GetId()}); err != nil {
+		t.Errorf("Error deleting order: %v", err)
+	}
+
+	if _, err = server.GetOrder(ctx, &pb.GetOrderRequest{Id: created.GetId()}); status.Code(err) != codes.NotFound {
+		t.Errorf("Expected NotFound, got %v", status.Code(err))
+	}
+
+	if _, err = server.DeleteOrder(ctx, &pb.DeleteOrderRequest{Id: 0}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument, got %v", status.Code(err))
+	}
+
+	_, err = server.CreateOrder(ctx, &pb.CreateOrderRequest{Item: "", Lines: []*pb.OrderLine{{Sku: "BAG-1", Quantity: 1, UnitPrice: &pb.Money{Units: -1}}}})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("Expected InvalidArgument, got %v", status.Code(err))
+	}
+
+	details := status.Convert(err).Details()
+	if len(details) != 1 {
+		t.Fatalf("Expected field violations, got %v", details)
+	}
+
+	if badRequest, ok := details[0].(*errdetails.BadRequest); !ok || len(badRequest.GetFieldViolations()) != 2 {
+		t.Errorf("Expected two field violations, got %v", details[0])
+	}
+}
+
+func TestToProtoOutOfRange(t *testing.T) {
+	for _, order := range []*domain.Order{
+		{ID: math.MaxInt32 + 1, Version: 1},
+		{ID: 1, Version: math.MaxInt32 + 1},
+	} {
+		if _, err := toProto(order); status.Code(toStatus(err)) != codes.OutOfRange {
+			t.Errorf("Order %d version %d: expected OutOfRange, got %v", order.ID, order.Version, err)
+		}
+	}
+
+	if got, err := toProto(&domain.Order{ID: math.MaxInt32, Version: 1}); err != nil || got.GetId() != math.MaxInt32 {
+		t.Errorf("Expected the largest int32 ID to convert, got %v (%v)", got, err)
+	}
+}
@@ -0,0 +1,214 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/dyammarcano/fullcycle_clean_architecture/internal/domain"
+	"github.com/dyammarcano/fullcycle_clean_architecture/internal/entity"
+	"github.com/dyammarcano/fullcycle_clean_architecture/internal/usecase"
+	"github.com/dyammarcano/fullcycle_clean_architecture/pkg/grpc/pb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var toProtoChangeType = map[domain.EventType]pb.OrderChangeType{
+	domain.EventOrderCreated:       pb.OrderChangeType_ORDER_CHANGE_TYPE_CREATED,
+	domain.EventOrderUpdated:       pb.OrderChangeType_ORDER_CHANGE_TYPE_UPDATED,
+	domain.EventOrderDeleted:       pb.OrderChangeType_ORDER_CHANGE_TYPE_DELETED,
+	domain.EventOrderRestored:      pb.OrderChangeType_ORDER_CHANGE_TYPE_RESTORED,
+	domain.EventOrderStatusChanged: pb.OrderChangeType_ORDER_CHANGE_TYPE_STATUS_CHANGED,
+}
+
+var fromProtoChangeType = func() map[pb.OrderChangeType]domain.EventType {
+	m := make(map[pb.OrderChangeType]domain.EventType, len(toProtoChangeType))
+	for k, v := range toProtoChangeType {
+		m[v] = k
+	}
+
+	return m
+}()
+
+// WatchOrders streams the changes matching the request, after the snapshot of the
+// current orders when it is asked for. Each message is sent once the client has
+// room for it, so a slow client holds back only its own stream; one that falls
+// behind the change buffer gets FAILED_PRECONDITION and resumes with a snapshot.
+// The stream ends with UNAVAILABLE when the server shuts down.
+func (s *OrderServer) WatchOrders(req *pb.WatchOrdersRequest, stream pb.OrderService_WatchOrdersServer) error {
+	filter, err := changeFilter(req)
+	if err != nil {
+		return toStatus(err)
+	}
+
+	sub, err := s.UseCase.WatchChanges(filter, req.GetResumeToken())
+	if err != nil {
+		return toStatus(err)
+	}
+
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+
+	go func() {
+		select {
+		case <-s.closing:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	// The snapshot is read after subscribing, so no change is missed in between;
+	// a change made meanwhile may arrive after an order that already includes it
+	if req.GetIncludeSnapshot() && req.GetResumeToken() == "" {
+		if err = s.sendSnapshot(ctx, stream, filter.OrderIDs, sub.Cursor()); err != nil {
+			return s.watchStatus(ctx, err)
+		}
+	}
+
+	for {
+		change, err := sub.Next(ctx)
+		if err != nil {
+			return s.watchStatus(ctx, err)
+		}
+
+		msg, err := toProtoChange(change)
+		if err != nil {
+			return toStatus(err)
+		}
+
+		if err = stream.Send(msg); err != nil {
+			return s.watchStatus(ctx, err)
+		}
+	}
+}
+
+// sendSnapshot sends the watched orders, every order when orderIDs is empty, and
+// the SNAPSHOT_COMPLETE marker, all resuming at cursor
+func (s *OrderServer) sendSnapshot(ctx context.Context, stream pb.OrderService_WatchOrdersServer, orderIDs []int, cursor string) error {
+	send := func(order *domain.Order) error {
+		grpcOrder, err := toProto(order)
+		if err != nil {
+			return err
+		}
+
+		return stream.Send(&pb.OrderChange{
+			Type:        pb.OrderChangeType_ORDER_CHANGE_TYPE_SNAPSHOT,
+			Order:       grpcOrder,
+			ResumeToken: cursor,
+		})
+	}
+
+	if len(orderIDs) > 0 {
+		for _, id := range orderIDs {
+			order, err := s.UseCase.GetOrderByID(ctx, id)
+			if errors.Is(err, domain.ErrOrderNotFound) {
+				continue
+			}
+
+			if err != nil {
+				return err
+			}
+
+			if err = send(order); err != nil {
+				return err
+			}
+		}
+	} else {
+		pageToken := ""
+
+		for {
+			page, err := s.UseCase.ListOrders(ctx, domain.ListOrdersQuery{PageSize: entity.MaxPageSize}, pageToken)
+			if err != nil {
+				return err
+			}
+
+			for _, order := range page.Orders {
+				if err = send(order); err != nil {
+					return err
+				}
+			}
+
+			if pageToken = page.NextPageToken; pageToken == "" {
+				break
+			}
+		}
+	}
+
+	return stream.Send(&pb.OrderChange{Type: pb.OrderChangeType_ORDER_CHANGE_TYPE_SNAPSHOT_COMPLETE, ResumeToken: cursor})
+}
+
+// watchStatus returns the status that ends a watch failed with err
+func (s *OrderServer) watchStatus(ctx context.Context, err error) error {
+	select {
+	case <-s.closing:
+		return status.Error(codes.Unavailable, "server is shutting down, resume the watch")
+	default:
+	}
+
+	if ctx.Err() != nil {
+		return status.FromContextError(ctx.Err()).Err()
+	}
+
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	return toStatus(err)
+}
+
+// changeFilter reads the order IDs and change types of a watch request
+func changeFilter(req *pb.WatchOrdersRequest) (usecase.ChangeFilter, error) {
+	var filter usecase.ChangeFilter
+
+	for _, id := range req.GetOrderIds() {
+		filter.OrderIDs = append(filter.OrderIDs, int(id))
+	}
+
+	for i, t := range req.GetTypes() {
+		eventType, ok := fromProtoChangeType[t]
+		if !ok {
+			return filter, domain.NewValidationError(domain.FieldError{
+				Field:   fmt.Sprintf("types[%d]", i),
+				Rule:    "one_of",
+				Message: "must be CREATED, UPDATED, DELETED, RESTORED or STATUS_CHANGED",
+			})
+		}
+
+		filter.Types = append(filter.Types, eventType)
+	}
+
+	return filter, nil
+}
+
+// toProtoChange converts a change of the feed into its protobuf representation
+func toProtoChange(change usecase.Change) (*pb.OrderChange, error) {
+	data, err := change.Event.OrderData()
+	if err != nil {
+		return nil, err
+	}
+
+	msg := &pb.OrderChange{
+		Type:        toProtoChangeType[change.Event.Type],
+		EventId:     change.Event.ID,
+		OccurredAt:  timestamppb.New(change.Event.OccurredAt),
+		ResumeToken: change.Cursor(),
+	}
+
+	if data.Order != nil {
+		if msg.Order, err = toProto(data.Order); err != nil {
+			return nil, err
+		}
+	}
+
+	if t := data.Transition; t != nil {
+		msg.Transition = &pb.StatusTransition{
+			From:   toProtoStatus[t.From],
+			To:     toProtoStatus[t.To],
+			Reason: t.Reason,
+			At:     timestamppb.New(t.At),
+		}
+	}
+
+	return msg, nil
+}
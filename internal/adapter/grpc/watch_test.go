@@ -0,0 +1,144 @@
+package grpc
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/dyammarcano/fullcycle_clean_architecture/internal/repository"
+	"github.com/dyammarcano/fullcycle_clean_architecture/internal/usecase"
+	"github.com/dyammarcano/fullcycle_clean_architecture/pkg/grpc/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestWatchOrders(t *testing.T) {
+	repo, err := repository.NewOrderMemoryRepository()
+	if err != nil {
+		t.Fatalf("Error creating repository")
+	}
+
+	server := NewGrpcOrderServer(usecase.NewOrderUseCase(repo))
+
+	lis := bufconn.Listen(1 << 20)
+	go func() { _ = server.server.Serve(lis) }()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Error dialing: %v", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	client := pb.NewOrderServiceClient(conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	lines := []*pb.OrderLine{{Sku: "BAG-1", Quantity: 1, UnitPrice: &pb.Money{Units: 200, CurrencyCode: "USD"}}}
+
+	existing, err := client.CreateOrder(ctx, &pb.CreateOrderRequest{Item: "Bag", Lines: lines})
+	if err != nil {
+		t.Fatalf("Error creating order: %v", err)
+	}
+
+	watch, err := client.WatchOrders(ctx, &pb.WatchOrdersRequest{IncludeSnapshot: true})
+	if err != nil {
+		t.Fatalf("Error watching: %v", err)
+	}
+
+	recv := func(stream pb.OrderService_WatchOrdersClient) *pb.OrderChange {
+		t.Helper()
+
+		change, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("Error receiving change: %v", err)
+		}
+
+		return change
+	}
+
+	if snapshot := recv(watch); snapshot.GetType() != pb.OrderChangeType_ORDER_CHANGE_TYPE_SNAPSHOT || snapshot.GetOrder().GetId() != existing.GetId() {
+		t.Fatalf("Expected the existing order as snapshot, got %v", snapshot)
+	}
+
+	complete := recv(watch)
+	if complete.GetType() != pb.OrderChangeType_ORDER_CHANGE_TYPE_SNAPSHOT_COMPLETE || complete.GetResumeToken() == "" {
+		t.Fatalf("Expected the end of the snapshot, got %v", complete)
+	}
+
+	if _, err = client.TransitionOrder(ctx, &pb.TransitionOrderRequest{Id: existing.GetId(), Status: pb.OrderStatus_ORDER_STATUS_CONFIRMED, Reason: "stock"}); err != nil {
+		t.Fatalf("Error confirming order: %v", err)
+	}
+
+	changed := recv(watch)
+	if changed.GetType() != pb.OrderChangeType_ORDER_CHANGE_TYPE_STATUS_CHANGED || changed.GetEventId() == 0 ||
+		changed.GetOrder().GetStatus() != pb.OrderStatus_ORDER_STATUS_CONFIRMED || changed.GetTransition().GetReason() != "stock" {
+		t.Fatalf("Unexpected status change %v", changed)
+	}
+
+	created, err := client.CreateOrder(ctx, &pb.CreateOrderRequest{Item: "Hat", Lines: lines})
+	if err != nil {
+		t.Fatalf("Error creating order: %v", err)
+	}
+
+	if _, err = client.DeleteOrder(ctx, &pb.DeleteOrderRequest{Id: created.GetId()}); err != nil {
+		t.Fatalf("Error deleting order: %v", err)
+	}
+
+	// Resuming after the snapshot replays every change since, here filtered
+	resumed, err := client.WatchOrders(ctx, &pb.WatchOrdersRequest{
+		ResumeToken: complete.GetResumeToken(),
+		OrderIds:    []int32{created.GetId()},
+		Types:       []pb.OrderChangeType{pb.OrderChangeType_ORDER_CHANGE_TYPE_DELETED},
+	})
+	if err != nil {
+		t.Fatalf("Error resuming: %v", err)
+	}
+
+	if deleted := recv(resumed); deleted.GetType() != pb.OrderChangeType_ORDER_CHANGE_TYPE_DELETED || deleted.GetOrder().GetId() != created.GetId() {
+		t.Fatalf("Expected the deletion of the new order, got %v", deleted)
+	}
+
+	expired, _ := client.WatchOrders(ctx, &pb.WatchOrdersRequest{ResumeToken: "1"})
+	if _, err = expired.Recv(); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("Resume with an expired token = %v, want FailedPrecondition", err)
+	}
+
+	invalid, _ := client.WatchOrders(ctx, &pb.WatchOrdersRequest{Types: []pb.OrderChangeType{pb.OrderChangeType_ORDER_CHANGE_TYPE_SNAPSHOT}})
+	if _, err = invalid.Recv(); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Watch with a snapshot filter = %v, want InvalidArgument", err)
+	}
+
+	cancelled, cancelWatch := context.WithCancel(ctx)
+
+	stream, err := client.WatchOrders(cancelled, &pb.WatchOrdersRequest{})
+	if err != nil {
+		t.Fatalf("Error watching: %v", err)
+	}
+
+	cancelWatch()
+
+	if _, err = stream.Recv(); status.Code(err) != codes.Canceled {
+		t.Errorf("Recv after cancelling = %v, want Canceled", err)
+	}
+
+	if err = server.Shutdown(ctx); err != nil {
+		t.Fatalf("Error shutting down: %v", err)
+	}
+
+	for {
+		if _, err = watch.Recv(); err != nil {
+			break
+		}
+	}
+
+	if status.Code(err) != codes.Unavailable {
+		t.Errorf("Watch after shutdown = %v, want Unavailable", err)
+	}
+}
@@ -0,0 +1,110 @@
+package http
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dyammarcano/fullcycle_clean_architecture/internal/adapter/errmap"
+	"github.com/dyammarcano/fullcycle_clean_architecture/internal/domain"
+	"github.com/dyammarcano/fullcycle_clean_architecture/pkg/logger"
+	"github.com/dyammarcano/fullcycle_clean_architecture/pkg/parameters"
+	"github.com/dyammarcano/fullcycle_clean_architecture/pkg/util"
+)
+
+// requireAdmin lets through only requests bearing the admin token. While no token
+// is configured the admin routes answer 404, as if they did not exist.
+func (s *OrderServer) requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.adminToken == "" {
+			http.NotFound(w, r)
+			return
+		}
+
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.adminToken)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+			util.HelperProblem(w, r, &util.Problem{
+				Type:      errmap.ProblemTypeBase + "unauthenticated",
+				Status:    http.StatusUnauthorized,
+				Detail:    "a valid admin bearer token is required",
+				RequestID: logger.RequestID(r.Context()),
+			})
+
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// ListDeletedOrdersHandler answers one page of soft-deleted orders. It takes the
+// filter, sort and pagination parameters of ListOrdersHandler.
+func (s *OrderServer) ListDeletedOrdersHandler(w http.ResponseWriter, r *http.Request) {
+	query, err := listOrdersQuery(r.URL.Query())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	page, err := s.UseCase.ListDeletedOrders(r.Context(), query, r.URL.Query().Get("page_token"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeOrderPage(w, r, page)
+}
+
+// RestoreOrderHandler brings back a soft-deleted order and answers it
+func (s *OrderServer) RestoreOrderHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	order, err := s.UseCase.RestoreOrder(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeOrder(w, r, order)
+}
+
+// purgeResult is the response of PurgeDeletedOrdersHandler
+type purgeResult struct {
+	Purged int `json:"purged"`
+}
+
+// PurgeDeletedOrdersHandler permanently removes the orders soft-deleted longer
+// ago than the older_than duration (for example 720h), or the configured retention
+func (s *OrderServer) PurgeDeletedOrdersHandler(w http.ResponseWriter, r *http.Request) {
+	retention := s.purgeRetention
+	if retention <= 0 {
+		retention = parameters.DefaultSoftDeleteRetention
+	}
+
+	if olderThan := r.URL.Query().Get("older_than"); olderThan != "" {
+		var err error
+		if retention, err = time.ParseDuration(olderThan); err != nil {
+			writeError(w, r, domain.NewValidationError(domain.FieldError{
+				Field:   "olderThan",
+				Rule:    "duration",
+				Message: "must be a duration such as 720h",
+			}))
+
+			return
+		}
+	}
+
+	purged, err := s.UseCase.PurgeDeletedOrders(r.Context(), retention)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	util.HelperJSON(w, r, purgeResult{Purged: purged})
+}
@@ -0,0 +1,89 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dyammarcano/fullcycle_clean_architecture/internal/domain"
+)
+
+func TestAdminHandlers(t *testing.T) {
+	s := newTestServer(t)
+	s.adminToken = "secret"
+
+	for _, item := range []string{"Bag", "Hat"} {
+		body := `{"item":"` + item + `","lines":[{"sku":"SKU-1","quantity":1,"unitPrice":{"value":"1.00","currency":"USD"}}]}`
+		rec := httptest.NewRecorder()
+		s.CreateOrderHandler(rec, httptest.NewRequest(http.MethodPost, "/order", strings.NewReader(body)))
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Error creating order: %s", rec.Body)
+		}
+	}
+
+	for _, id := range []string{"1", "2"} {
+		req := httptest.NewRequest(http.MethodDelete, "/order/"+id, nil)
+		req.SetPathValue("id", id)
+		s.DeleteOrderHandler(httptest.NewRecorder(), req)
+	}
+
+	admin := func(handler http.HandlerFunc, method, target, id, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, nil)
+		req.SetPathValue("id", id)
+
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		rec := httptest.NewRecorder()
+		s.requireAdmin(handler)(rec, req)
+
+		return rec
+	}
+
+	rec := admin(s.ListDeletedOrdersHandler, http.MethodGet, "/admin/order/deleted", "", "wrong")
+	if problem := decodeProblem(t, rec); problem.Status != http.StatusUnauthorized || rec.Header().Get("WWW-Authenticate") == "" {
+		t.Errorf("Unexpected response for a wrong token: %+v", problem)
+	}
+
+	rec = admin(s.ListDeletedOrdersHandler, http.MethodGet, "/admin/order/deleted?page_size=1", "", "secret")
+
+	var orders []domain.Order
+	if err := json.Unmarshal(rec.Body.Bytes(), &orders); err != nil || len(orders) != 1 || orders[0].DeletedAt == nil {
+		t.Fatalf("Unexpected deleted orders %s", rec.Body)
+	}
+
+	if !strings.Contains(rec.Header().Get("Link"), "page_token=") {
+		t.Errorf("Expected a link to the next page, got %q", rec.Header().Get("Link"))
+	}
+
+	rec = admin(s.RestoreOrderHandler, http.MethodPost, "/admin/order/1/restore", "1", "secret")
+
+	var restored domain.Order
+	if err := json.Unmarshal(rec.Body.Bytes(), &restored); err != nil || restored.ID != 1 || restored.DeletedAt != nil {
+		t.Errorf("Unexpected restored order %s", rec.Body)
+	}
+
+	rec = admin(s.PurgeDeletedOrdersHandler, http.MethodDelete, "/admin/order/deleted?older_than=forever", "", "secret")
+	if problem := decodeProblem(t, rec); problem.Status != http.StatusBadRequest || problem.Errors[0].Field != "olderThan" {
+		t.Errorf("Unexpected problem for an invalid duration: %+v", problem)
+	}
+
+	rec = admin(s.PurgeDeletedOrdersHandler, http.MethodDelete, "/admin/order/deleted", "", "secret")
+	if strings.TrimSpace(rec.Body.String()) != `{"purged":0}` {
+		t.Errorf("Purge with the default retention = %s", rec.Body)
+	}
+
+	rec = admin(s.PurgeDeletedOrdersHandler, http.MethodDelete, "/admin/order/deleted?older_than=0s", "", "secret")
+	if strings.TrimSpace(rec.Body.String()) != `{"purged":1}` {
+		t.Errorf("Purge of every deleted order = %s", rec.Body)
+	}
+
+	s.adminToken = ""
+	if rec = admin(s.ListDeletedOrdersHandler, http.MethodGet, "/admin/order/deleted", "", "secret"); rec.Code != http.StatusNotFound {
+		t.Errorf("Admin routes without a token = %d, want 404", rec.Code)
+	}
+}
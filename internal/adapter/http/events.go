@@ -0,0 +1,186 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/dyammarcano/fullcycle_clean_architecture/internal/domain"
+	"github.com/dyammarcano/fullcycle_clean_architecture/internal/usecase"
+	"github.com/dyammarcano/fullcycle_clean_architecture/pkg/parameters"
+)
+
+// retryMillis is the reconnection delay suggested to EventSource clients
+const retryMillis = 3000
+
+// OrderEventsHandler streams the order changes as server-sent events: the event
+// name is the event type, the data the order event and the id a cursor. A client
+// reconnecting with Last-Event-ID (or the last_event_id parameter) resumes after
+// that change; when it is no longer buffered a reset event, whose id is the new
+// position, tells the client to reload the orders. The order_id and type
+// parameters, repeated or comma separated, filter the changes. A comment line is
+// sent after every heartbeat interval without changes.
+func (s *OrderServer) OrderEventsHandler(w http.ResponseWriter, r *http.Request) {
+	filter, err := changeFilter(r.URL.Query())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("last_event_id")
+	}
+
+	sub, err := s.UseCase.WatchChanges(filter, lastEventID)
+
+	reset := errors.Is(err, domain.ErrChangesExpired)
+	if reset {
+		sub, err = s.UseCase.WatchChanges(filter, "")
+	}
+
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	stream := eventStream{w: w, rc: http.NewResponseController(w)}
+	stream.printf("retry: %d\n\n", retryMillis)
+
+	if reset {
+		stream.reset(sub.Cursor())
+	}
+
+	if stream.flush() != nil {
+		return
+	}
+
+	// The stream ends with the request or when the server shuts down
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	go func() {
+		select {
+		case <-s.closing:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	heartbeat := s.heartbeat
+	if heartbeat <= 0 {
+		heartbeat = parameters.DefaultHeartbeat
+	}
+
+	for {
+		next, cancelNext := context.WithTimeout(ctx, heartbeat)
+		change, err := sub.Next(next)
+		cancelNext()
+
+		switch {
+		case err == nil:
+			stream.change(change)
+		case errors.Is(err, domain.ErrChangesExpired):
+			// The client fell behind the buffer: it starts over from the latest change
+			sub, _ = s.UseCase.WatchChanges(filter, "")
+			stream.reset(sub.Cursor())
+		case ctx.Err() == nil:
+			stream.printf(": heartbeat\n\n")
+		default:
+			return
+		}
+
+		if stream.flush() != nil {
+			return
+		}
+	}
+}
+
+// eventStream writes server-sent events, keeping the first write error
+type eventStream struct {
+	w   io.Writer
+	rc  *http.ResponseController
+	err error
+}
+
+func (e *eventStream) printf(format string, args ...any) {
+	if e.err == nil {
+		_, e.err = fmt.Fprintf(e.w, format, args...)
+	}
+}
+
+// change writes an order change; the JSON encoding of the event fits in one data line
+func (e *eventStream) change(change usecase.Change) {
+	data, err := json.Marshal(change.Event)
+	if err != nil {
+		e.err = err
+		return
+	}
+
+	e.printf("id: %s\nevent: %s\ndata: %s\n\n", change.Cursor(), change.Event.Type, data)
+}
+
+// reset tells the client that changes were missed and moves its Last-Event-ID to cursor
+func (e *eventStream) reset(cursor string) {
+	data, _ := json.Marshal(map[string]string{"reason": domain.ErrChangesExpired.Error()})
+
+	e.printf("id: %s\nevent: reset\ndata: %s\n\n", cursor, data)
+}
+
+func (e *eventStream) flush() error {
+	if e.err != nil {
+		return e.err
+	}
+
+	return e.rc.Flush()
+}
+
+// changeFilter reads the order_id and type parameters of GET /order/events
+func changeFilter(params url.Values) (usecase.ChangeFilter, error) {
+	var filter usecase.ChangeFilter
+
+	for _, value := range splitParams(params["order_id"]) {
+		id, err := strconv.Atoi(value)
+		if err != nil {
+			return filter, domain.NewValidationError(domain.FieldError{
+				Field:   "orderIds",
+				Rule:    "integer",
+				Message: "must be a list of order IDs",
+			})
+		}
+
+		filter.OrderIDs = append(filter.OrderIDs, id)
+	}
+
+	for _, value := range splitParams(params["type"]) {
+		filter.Types = append(filter.Types, domain.EventType(value))
+	}
+
+	return filter, nil
+}
+
+// splitParams returns the non empty items of repeated, comma separated parameters
+func splitParams(values []string) []string {
+	var items []string
+
+	for _, value := range values {
+		for item := range strings.SplitSeq(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+	}
+
+	return items
+}
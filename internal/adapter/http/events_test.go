@@ -0,0 +1,147 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dyammarcano/fullcycle_clean_architecture/internal/domain"
+	"github.com/dyammarcano/fullcycle_clean_architecture/pkg/logger"
+)
+
+// sseClient reads the events of a server-sent events response
+type sseClient struct {
+	resp       *http.Response
+	reader     *bufio.Reader
+	heartbeats int
+}
+
+func openEvents(t *testing.T, url, lastEventID string) *sseClient {
+	t.Helper()
+
+	req, _ := http.NewRequest(http.MethodGet, url, nil)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Error opening stream: %v", err)
+	}
+
+	t.Cleanup(func() { _ = resp.Body.Close() })
+
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Expected an event stream, got %d %q", resp.StatusCode, ct)
+	}
+
+	return &sseClient{resp: resp, reader: bufio.NewReader(resp.Body)}
+}
+
+// next returns the fields of the next event, counting the heartbeat comments before it
+func (c *sseClient) next(t *testing.T) map[string]string {
+	t.Helper()
+
+	fields := map[string]string{}
+
+	for {
+		line, err := c.reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Error reading stream: %v", err)
+		}
+
+		line = strings.TrimSuffix(line, "\n")
+
+		switch {
+		case line == "" && len(fields) > 0:
+			return fields
+		case strings.HasPrefix(line, ":"):
+			c.heartbeats++
+		case line != "":
+			name, value, _ := strings.Cut(line, ": ")
+			fields[name] = value
+		}
+	}
+}
+
+func TestOrderEventsHandler(t *testing.T) {
+	ctx := context.Background()
+
+	s := newTestServer(t)
+	s.heartbeat = 20 * time.Millisecond
+
+	closing := make(chan struct{})
+	s.closing = closing
+
+	server := httptest.NewServer(logger.Middleware(http.HandlerFunc(s.OrderEventsHandler)))
+	defer server.Close()
+
+	stream := openEvents(t, server.URL+"/order/events?type=order.created,order.deleted", "")
+	if retry := stream.next(t); retry["retry"] == "" {
+		t.Fatalf("Expected a retry delay first, got %v", retry)
+	}
+
+	created, err := s.UseCase.CreateOrder(ctx, []byte(`{"item":"Bag","lines":[{"sku":"BAG-1","quantity":1,"unitPrice":{"value":"10.00","currency":"USD"}}]}`))
+	if err != nil {
+		t.Fatalf("Error creating order: %v", err)
+	}
+
+	first := stream.next(t)
+
+	var event domain.Event
+	if err = json.Unmarshal([]byte(first["data"]), &event); err != nil || first["event"] != "order.created" || event.OrderID != created.ID || event.ID == 0 {
+		t.Fatalf("Unexpected first event %v", first)
+	}
+
+	if _, err = s.UseCase.UpdateOrder(ctx, created.ID, []byte(`{"item":"Hat","lines":[{"sku":"HAT-1","quantity":1,"unitPrice":{"value":"5.00","currency":"USD"}}]}`)); err != nil {
+		t.Fatalf("Error updating order: %v", err)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+
+	if err = s.UseCase.DeleteOrder(ctx, created.ID, 0); err != nil {
+		t.Fatalf("Error deleting order: %v", err)
+	}
+
+	if deleted := stream.next(t); deleted["event"] != "order.deleted" {
+		t.Fatalf("Expected the update to be filtered out, got %v", deleted)
+	}
+
+	if stream.heartbeats == 0 {
+		t.Error("Expected heartbeats while the stream was idle")
+	}
+
+	resumed := openEvents(t, server.URL+"/order/events", first["id"])
+	resumed.next(t)
+
+	for _, want := range []string{"order.updated", "order.deleted"} {
+		if got := resumed.next(t); got["event"] != want {
+			t.Fatalf("Resumed event = %v, want %s", got, want)
+		}
+	}
+
+	stale := openEvents(t, server.URL+"/order/events", "1")
+	stale.next(t)
+
+	if reset := stale.next(t); reset["event"] != "reset" || reset["id"] == "" {
+		t.Fatalf("Expected a reset for an expired Last-Event-ID, got %v", reset)
+	}
+
+	rec := httptest.NewRecorder()
+	s.OrderEventsHandler(rec, httptest.NewRequest(http.MethodGet, "/order/events?type=order.paid&order_id=0", nil))
+
+	if problem := decodeProblem(t, rec); problem.Status != http.StatusBadRequest || len(problem.Errors) != 2 {
+		t.Errorf("Unexpected problem for invalid filters: %+v", problem)
+	}
+
+	close(closing)
+
+	if _, err = stream.reader.ReadString('\x00'); err == nil || !strings.Contains(err.Error(), "EOF") {
+		t.Errorf("Expected the stream to end on shutdown, got %v", err)
+	}
+}
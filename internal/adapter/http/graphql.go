@@ -0,0 +1,730 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/dyammarcano/fullcycle_clean_architecture/internal/adapter/errmap"
+	"github.com/dyammarcano/fullcycle_clean_architecture/internal/domain"
+	"github.com/dyammarcano/fullcycle_clean_architecture/internal/entity"
+	"github.com/dyammarcano/fullcycle_clean_architecture/internal/usecase"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/handler"
+)
+
+// graphQLError carries an extension code, and field violations for validation
+// failures, into the GraphQL errors array
+type graphQLError struct {
+	err    error
+	code   string
+	fields []domain.FieldError
+}
+
+func (e *graphQLError) Error() string {
+	return e.err.Error()
+}
+
+func (e *graphQLError) Unwrap() error {
+	return e.err
+}
+
+func (e *graphQLError) Extensions() map[string]any {
+	extensions := map[string]any{"code": e.code}
+	if len(e.fields) > 0 {
+		extensions["fields"] = e.fields
+	}
+
+	return extensions
+}
+
+// toGraphQLError translates use case errors into GraphQL errors with extension codes
+func toGraphQLError(err error) error {
+	if !errmap.IsKnown(err) {
+		slog.Error("GraphQL request failed", slog.String("error", err.Error()))
+
+		return &graphQLError{err: errors.New(errmap.InternalMessage), code: errmap.GraphQLCode(err)}
+	}
+
+	return &graphQLError{err: err, code: errmap.GraphQLCode(err), fields: errmap.FieldErrors(err)}
+}
+
+// orderIDArg reads and checks the id argument of a field
+func orderIDArg(params graphql.ResolveParams) (int, error) {
+	id, _ := params.Args["id"].(int)
+	if err := entity.ValidateID(id); err != nil {
+		return 0, toGraphQLError(err)
+	}
+
+	return id, nil
+}
+
+// orderInputArg reads the input argument of a mutation into a domain order
+func orderInputArg(params graphql.ResolveParams) (*domain.Order, error) {
+	input, _ := params.Args["input"].(map[string]any)
+	order := &domain.Order{}
+
+	if item, ok := input["item"].(string); ok {
+		order.Item = item
+	}
+
+	lines, _ := input["lines"].([]any)
+	for i, raw := range lines {
+		lineInput, _ := raw.(map[string]any)
+		line := domain.OrderLine{}
+
+		line.SKU, _ = lineInput["sku"].(string)
+		line.Description, _ = lineInput["description"].(string)
+		line.Quantity, _ = lineInput["quantity"].(int)
+
+		money, err := moneyInput(lineInput["unitPrice"])
+		if err != nil {
+			return nil, toGraphQLError(domain.NewValidationError(domain.FieldError{
+				Field:   fmt.Sprintf("lines[%d].unitPrice", i),
+				Rule:    "money",
+				Message: err.Error(),
+			}))
+		}
+
+		line.UnitPrice = money
+		order.Lines = append(order.Lines, line)
+	}
+
+	return order, nil
+}
+
+// moneyInput reads a MoneyInput value into a domain amount
+func moneyInput(raw any) (domain.Money, error) {
+	input, _ := raw.(map[string]any)
+	value, _ := input["value"].(string)
+	currency, _ := input["currency"].(string)
+
+	return domain.ParseMoney(value, currency)
+}
+
+// ordersQueryArgs reads the filter, sort and page size arguments of the orders query
+func ordersQueryArgs(params graphql.ResolveParams) (domain.ListOrdersQuery, error) {
+	query := domain.ListOrdersQuery{}
+	query.PageSize, _ = params.Args["first"].(int)
+
+	if orderBy, ok := params.Args["orderBy"].(map[string]any); ok {
+		query.SortBy, _ = orderBy["field"].(domain.OrderSortField)
+		query.Descending, _ = orderBy["descending"].(bool)
+	}
+
+	filter, _ := params.Args["filter"].(map[string]any)
+	query.Filter.ItemContains, _ = filter["item"].(string)
+
+	for _, bound := range []struct {
+		field  string
+		amount **domain.Money
+	}{{"minAmount", &query.Filter.MinAmount}, {"maxAmount", &query.Filter.MaxAmount}} {
+		raw, ok := filter[bound.field]
+		if !ok || raw == nil {
+			continue
+		}
+
+		amount, err := moneyInput(raw)
+		if err != nil {
+			return query, toGraphQLError(domain.NewValidationError(domain.FieldError{
+				Field:   "filter." + bound.field,
+				Rule:    "money",
+				Message: err.Error(),
+			}))
+		}
+
+		*bound.amount = &amount
+	}
+
+	return query, nil
+}
+
+// decimalType is an exact decimal number carried as a string so no precision is lost
+var decimalType = graphql.NewScalar(graphql.ScalarConfig{
+	Name:        "Decimal",
+	Description: "Exact decimal number encoded as a string, such as \"100.50\"",
+	Serialize: func(value any) any {
+		switch v := value.(type) {
+		case string:
+			return v
+		case domain.Money:
+			return v.Decimal()
+		}
+
+		return nil
+	},
+	ParseValue: func(value any) any {
+		switch v := value.(type) {
+		case string:
+			return v
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64)
+		}
+
+		return nil
+	},
+	ParseLiteral: func(valueAST ast.Value) any {
+		switch v := valueAST.(type) {
+		case *ast.StringValue:
+			return v.Value
+		case *ast.IntValue:
+			return v.Value
+		case *ast.FloatValue:
+			return v.Value
+		}
+
+		return nil
+	},
+})
+
+func NewGraphQL(useCase *usecase.OrderUseCase) http.Handler {
+	return newGraphQLHandler(newGraphQLSchema(useCase))
+}
+
+// newGraphQLHandler serves the queries and mutations of schema over HTTP, with the Playground
+func newGraphQLHandler(schema *graphql.Schema) http.Handler {
+	return handler.New(&handler.Config{
+		Schema:     schema,
+		Pretty:     true,
+		Playground: true,
+	})
+}
+
+// newGraphQLSchema builds the orders schema: queries, mutations and the subscriptions
+// served over WebSocket
+func newGraphQLSchema(useCase *usecase.OrderUseCase) *graphql.Schema {
+	moneyType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Money",
+		Fields: graphql.Fields{
+			"value": &graphql.Field{
+				Type: decimalType,
+				Resolve: func(params graphql.ResolveParams) (any, error) {
+					money, _ := params.Source.(domain.Money)
+					return money.Decimal(), nil
+				},
+			},
+			"currency": &graphql.Field{
+				Type: graphql.String,
+			},
+		},
+	})
+
+	moneyInputType := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "MoneyInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"value": &graphql.InputObjectFieldConfig{
+				Type: graphql.NewNonNull(decimalType),
+			},
+			"currency": &graphql.InputObjectFieldConfig{
+				Type:         graphql.String,
+				DefaultValue: domain.DefaultCurrency,
+			},
+		},
+	})
+
+	orderLineType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "OrderLine",
+		Fields: graphql.Fields{
+			"sku": &graphql.Field{
+				Type: graphql.String,
+			},
+			"description": &graphql.Field{
+				Type: graphql.String,
+			},
+			"quantity": &graphql.Field{
+				Type: graphql.Int,
+			},
+			"unitPrice": &graphql.Field{
+				Type: moneyType,
+			},
+			"total": &graphql.Field{
+				Type: moneyType,
+				Resolve: func(params graphql.ResolveParams) (any, error) {
+					line, _ := params.Source.(domain.OrderLine)
+					return line.Total()
+				},
+			},
+		},
+	})
+
+	orderLineInputType := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "OrderLineInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"sku": &graphql.InputObjectFieldConfig{
+				Type: graphql.NewNonNull(graphql.String),
+			},
+			"description": &graphql.InputObjectFieldConfig{
+				Type: graphql.String,
+			},
+			"quantity": &graphql.InputObjectFieldConfig{
+				Type: graphql.NewNonNull(graphql.Int),
+			},
+			"unitPrice": &graphql.InputObjectFieldConfig{
+				Type: graphql.NewNonNull(moneyInputType),
+			},
+		},
+	})
+
+	statusValues := graphql.EnumValueConfigMap{}
+	for _, status := range domain.OrderStatuses {
+		statusValues[strings.ToUpper(string(status))] = &graphql.EnumValueConfig{Value: status}
+	}
+
+	orderStatusType := graphql.NewEnum(graphql.EnumConfig{
+		Name:   "OrderStatus",
+		Values: statusValues,
+	})
+
+	statusTransitionType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "StatusTransition",
+		Fields: graphql.Fields{
+			"from": &graphql.Field{
+				Type: orderStatusType,
+			},
+			"to": &graphql.Field{
+				Type: orderStatusType,
+			},
+			"reason": &graphql.Field{
+				Type: graphql.String,
+			},
+			"at": &graphql.Field{
+				Type: graphql.DateTime,
+			},
+		},
+	})
+
+	orderType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Order",
+		Fields: graphql.Fields{
+			"id": &graphql.Field{
+				Type: graphql.Int,
+			},
+			"item": &graphql.Field{
+				Type: graphql.String,
+			},
+			"status": &graphql.Field{
+				Type: orderStatusType,
+			},
+			"version": &graphql.Field{
+				Type: graphql.Int,
+			},
+			"createdAt": &graphql.Field{
+				Type: graphql.DateTime,
+			},
+			"updatedAt": &graphql.Field{
+				Type: graphql.DateTime,
+			},
+			"history": &graphql.Field{
+				Type:        graphql.NewList(statusTransitionType),
+				Description: "Status transitions of the order, oldest first",
+				Resolve: func(params graphql.ResolveParams) (any, error) {
+					order, _ := params.Source.(*domain.Order)
+					if order == nil {
+						return nil, nil
+					}
+
+					history, err := useCase.ListStatusHistory(params.Context, order.ID)
+					if err != nil {
+						return nil, toGraphQLError(err)
+					}
+
+					return history, nil
+				},
+			},
+			"lines": &graphql.Field{
+				Type: graphql.NewList(orderLineType),
+			},
+			"amount": &graphql.Field{
+				Type:        moneyType,
+				Description: "Order total computed from the lines",
+			},
+		},
+	})
+
+	sortFieldValues := graphql.EnumValueConfigMap{}
+	for _, field := range domain.OrderSortFields {
+		sortFieldValues[strings.ToUpper(string(field))] = &graphql.EnumValueConfig{Value: field}
+	}
+
+	orderSortFieldType := graphql.NewEnum(graphql.EnumConfig{
+		Name:   "OrderSortField",
+		Values: sortFieldValues,
+	})
+
+	orderSortInputType := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "OrderSort",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"field": &graphql.InputObjectFieldConfig{
+				Type: graphql.NewNonNull(orderSortFieldType),
+			},
+			"descending": &graphql.InputObjectFieldConfig{
+				Type:         graphql.Boolean,
+				DefaultValue: false,
+			},
+		},
+	})
+
+	orderFilterInputType := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "OrderFilter",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"item": &graphql.InputObjectFieldConfig{
+				Type:        graphql.String,
+				Description: "Keeps orders whose item contains the text, ignoring case",
+			},
+			"minAmount": &graphql.InputObjectFieldConfig{
+				Type: moneyInputType,
+			},
+			"maxAmount": &graphql.InputObjectFieldConfig{
+				Type: moneyInputType,
+			},
+		},
+	})
+
+	pageInfoType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "PageInfo",
+		Fields: graphql.Fields{
+			"hasNextPage": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Boolean),
+			},
+			"endCursor": &graphql.Field{
+				Type: graphql.String,
+			},
+		},
+	})
+
+	orderEdgeType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "OrderEdge",
+		Fields: graphql.Fields{
+			"cursor": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.String),
+			},
+			"node": &graphql.Field{
+				Type: orderType,
+			},
+		},
+	})
+
+	orderConnectionType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "OrderConnection",
+		Fields: graphql.Fields{
+			"edges": &graphql.Field{
+				Type: graphql.NewList(orderEdgeType),
+			},
+			"pageInfo": &graphql.Field{
+				Type: graphql.NewNonNull(pageInfoType),
+			},
+		},
+	})
+
+	orderInputType := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "OrderInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"item": &graphql.InputObjectFieldConfig{
+				Type: graphql.NewNonNull(graphql.String),
+			},
+			"lines": &graphql.InputObjectFieldConfig{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(orderLineInputType))),
+			},
+		},
+	})
+
+	rootQuery := graphql.NewObject(graphql.ObjectConfig{
+		Name: "RootQuery",
+		Fields: graphql.Fields{
+			"listOrders": &graphql.Field{
+				Type:              graphql.NewList(orderType),
+				DeprecationReason: "Use orders, which is paginated",
+				Resolve: func(params graphql.ResolveParams) (any, error) {
+					query := domain.ListOrdersQuery{PageSize: entity.MaxPageSize}
+					orders := make([]*domain.Order, 0)
+
+					for token := ""; ; {
+						page, err := useCase.ListOrders(params.Context, query, token)
+						if err != nil {
+							return nil, toGraphQLError(err)
+						}
+
+						orders = append(orders, page.Orders...)
+
+						if token = page.NextPageToken; token == "" {
+							return orders, nil
+						}
+					}
+				},
+			},
+			"orders": &graphql.Field{
+				Type:        orderConnectionType,
+				Description: "Relay style connection over the orders",
+				Args: graphql.FieldConfigArgument{
+					"first":   &graphql.ArgumentConfig{Type: graphql.Int},
+					"after":   &graphql.ArgumentConfig{Type: graphql.String},
+					"filter":  &graphql.ArgumentConfig{Type: orderFilterInputType},
+					"orderBy": &graphql.ArgumentConfig{Type: orderSortInputType},
+				},
+				Resolve: func(params graphql.ResolveParams) (any, error) {
+					query, err := ordersQueryArgs(params)
+					if err != nil {
+						return nil, err
+					}
+
+					after, _ := params.Args["after"].(string)
+
+					page, err := useCase.ListOrders(params.Context, query, after)
+					if err != nil {
+						return nil, toGraphQLError(err)
+					}
+
+					edges := make([]map[string]any, 0, len(page.Orders))
+					for _, order := range page.Orders {
+						edges = append(edges, map[string]any{
+							"cursor": domain.EncodePageToken(query, domain.CursorFor(order, query.SortBy)),
+							"node":   order,
+						})
+					}
+
+					pageInfo := map[string]any{"hasNextPage": page.HasMore}
+					if len(edges) > 0 {
+						pageInfo["endCursor"] = edges[len(edges)-1]["cursor"]
+					}
+
+					return map[string]any{"edges": edges, "pageInfo": pageInfo}, nil
+				},
+			},
+			"order": &graphql.Field{
+				Type: orderType,
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				},
+				Resolve: func(params graphql.ResolveParams) (any, error) {
+					id, err := orderIDArg(params)
+					if err != nil {
+						return nil, err
+					}
+
+					order, err := useCase.GetOrderByID(params.Context, id)
+					if err != nil {
+						return nil, toGraphQLError(err)
+					}
+
+					return order, nil
+				},
+			},
+		},
+	})
+
+	rootMutation := graphql.NewObject(graphql.ObjectConfig{
+		Name: "RootMutation",
+		Fields: graphql.Fields{
+			"createOrder": &graphql.Field{
+				Type: orderType,
+				Args: graphql.FieldConfigArgument{
+					"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(orderInputType)},
+				},
+				Resolve: func(params graphql.ResolveParams) (any, error) {
+					order, err := orderInputArg(params)
+					if err != nil {
+						return nil, err
+					}
+
+					created, err := useCase.CreateOrder(params.Context, order.Bytes())
+					if err != nil {
+						return nil, toGraphQLError(err)
+					}
+
+					return created, nil
+				},
+			},
+			"updateOrder": &graphql.Field{
+				Type: orderType,
+				Args: graphql.FieldConfigArgument{
+					"id":    &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+					"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(orderInputType)},
+					"version": &graphql.ArgumentConfig{
+						Type:        graphql.Int,
+						Description: "When set, the update fails with CONFLICT unless the order still has this version",
+					},
+				},
+				Resolve: func(params graphql.ResolveParams) (any, error) {
+					id, err := orderIDArg(params)
+					if err != nil {
+						return nil, err
+					}
+
+					order, err := orderInputArg(params)
+					if err != nil {
+						return nil, err
+					}
+
+					order.Version, _ = params.Args["version"].(int)
+
+					updated, err := useCase.UpdateOrder(params.Context, id, order.Bytes())
+					if err != nil {
+						return nil, toGraphQLError(err)
+					}
+
+					return updated, nil
+				},
+			},
+			"transitionOrder": &graphql.Field{
+				Type: orderType,
+				Args: graphql.FieldConfigArgument{
+					"id":     &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+					"status": &graphql.ArgumentConfig{Type: graphql.NewNonNull(orderStatusType)},
+					"reason": &graphql.ArgumentConfig{Type: graphql.String},
+				},
+				Resolve: func(params graphql.ResolveParams) (any, error) {
+					id, err := orderIDArg(params)
+					if err != nil {
+						return nil, err
+					}
+
+					next, _ := params.Args["status"].(domain.OrderStatus)
+					reason, _ := params.Args["reason"].(string)
+
+					order, err := useCase.TransitionOrder(params.Context, id, next, reason)
+					if err != nil {
+						return nil, toGraphQLError(err)
+					}
+
+					return order, nil
+				},
+			},
+			"deleteOrder": &graphql.Field{
+				Type: graphql.Boolean,
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+					"version": &graphql.ArgumentConfig{
+						Type:        graphql.Int,
+						Description: "When set, the delete fails with CONFLICT unless the order still has this version",
+					},
+				},
+				Resolve: func(params graphql.ResolveParams) (any, error) {
+					id, err := orderIDArg(params)
+					if err != nil {
+						return nil, err
+					}
+
+					version, _ := params.Args["version"].(int)
+
+					if err = useCase.DeleteOrder(params.Context, id, version); err != nil {
+						return nil, toGraphQLError(err)
+					}
+
+					return true, nil
+				},
+			},
+		},
+	})
+
+	// Each subscription field follows the order changes of its types, optionally
+	// restricted to some orders
+	subscribe := func(types ...domain.EventType) graphql.FieldResolveFn {
+		return func(params graphql.ResolveParams) (any, error) {
+			filter := usecase.ChangeFilter{Types: types}
+
+			ids, _ := params.Args["ids"].([]any)
+			for _, id := range ids {
+				orderID, _ := id.(int)
+				filter.OrderIDs = append(filter.OrderIDs, orderID)
+			}
+
+			sub, err := useCase.WatchChanges(filter, "")
+			if err != nil {
+				return nil, toGraphQLError(err)
+			}
+
+			return changePayloads(params.Context, sub), nil
+		}
+	}
+
+	changeField := func(description string, types ...domain.EventType) *graphql.Field {
+		return &graphql.Field{
+			Type:        orderType,
+			Description: description,
+			Args: graphql.FieldConfigArgument{
+				"ids": &graphql.ArgumentConfig{
+					Type:        graphql.NewList(graphql.NewNonNull(graphql.Int)),
+					Description: "Restricts the notifications to these orders",
+				},
+			},
+			Subscribe: subscribe(types...),
+			Resolve:   resolveChange,
+		}
+	}
+
+	rootSubscription := graphql.NewObject(graphql.ObjectConfig{
+		Name: "RootSubscription",
+		Fields: graphql.Fields{
+			"orderCreated": changeField("Order created", domain.EventOrderCreated),
+			"orderUpdated": changeField("Order after an update or a status transition", domain.EventOrderUpdated, domain.EventOrderStatusChanged),
+			"orderDeleted": changeField("Order as it was when deleted", domain.EventOrderDeleted),
+		},
+	})
+
+	schema, _ := graphql.NewSchema(graphql.SchemaConfig{
+		Query:        rootQuery,
+		Mutation:     rootMutation,
+		Subscription: rootSubscription,
+	})
+
+	return &schema
+}
+
+// changePayloads feeds a subscription field with the events of sub until ctx ends.
+// A failure, such as the subscriber falling behind the change buffer, is sent as the
+// last payload so the client gets it as an error.
+func changePayloads(ctx context.Context, sub *usecase.ChangeSubscription) chan any {
+	payloads := make(chan any)
+
+	go func() {
+		defer close(payloads)
+
+		for {
+			var payload any
+
+			change, err := sub.Next(ctx)
+			switch {
+			case err == nil:
+				payload = change.Event
+			case ctx.Err() != nil:
+				return
+			default:
+				payload = err
+			}
+
+			select {
+			case payloads <- payload:
+			case <-ctx.Done():
+				return
+			}
+
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	return payloads
+}
+
+// resolveChange returns the order carried by a subscription payload
+func resolveChange(params graphql.ResolveParams) (any, error) {
+	switch payload := params.Source.(type) {
+	case error:
+		return nil, toGraphQLError(payload)
+	case domain.Event:
+		data, err := payload.OrderData()
+		if err != nil {
+			return nil, toGraphQLError(err)
+		}
+
+		return data.Order, nil
+	}
+
+	return nil, nil
+}
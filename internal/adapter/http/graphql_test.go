@@ -0,0 +1,144 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dyammarcano/fullcycle_clean_architecture/internal/repository"
+	"github.com/dyammarcano/fullcycle_clean_architecture/internal/usecase"
+)
+
+type graphQLResponse struct {
+	Data   map[string]json.RawMessage `json:"data"`
+	Errors []struct {
+		Message    string         `json:"message"`
+		Extensions map[string]any `json:"extensions"`
+	} `json:"errors"`
+}
+
+func doGraphQL(t *testing.T, h http.Handler, query string) graphQLResponse {
+	t.Helper()
+
+	body, _ := json.Marshal(map[string]string{"query": query})
+	req := httptest.NewRequest(http.MethodPost, "/graphql", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	var resp graphQLResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Error decoding response: %v", err)
+	}
+
+	return resp
+}
+
+func TestGraphQL(t *testing.T) {
+	repo, err := repository.NewOrderMemoryRepository()
+	if err != nil {
+		t.Fatalf("Error creating repository")
+	}
+
+	h := NewGraphQL(usecase.NewOrderUseCase(repo))
+
+	resp := doGraphQL(t, h, `mutation { createOrder(input: {item: "Bag", lines: [{sku: "BAG-1", quantity: 1, unitPrice: {value: "2.00"}}]}) { id item amount { value currency } } }`)
+	if len(resp.Errors) != 0 {
+		t.Fatalf("Error creating order: %v", resp.Errors)
+	}
+
+	resp = doGraphQL(t, h, `mutation { updateOrder(id: 1, input: {item: "Bag", lines: [{sku: "BAG-1", quantity: 3, unitPrice: {value: 33.50, currency: "EUR"}}]}) { id amount { value currency } } }`)
+	if len(resp.Errors) != 0 {
+		t.Errorf("Error updating order: %v", resp.Errors)
+	}
+
+	resp = doGraphQL(t, h, `{ order(id: 1) { id item amount { value currency } } }`)
+	if len(resp.Errors) != 0 {
+		t.Errorf("Error getting order: %v", resp.Errors)
+	}
+
+	var order struct {
+		Amount struct {
+			Value    string `json:"value"`
+			Currency string `json:"currency"`
+		} `json:"amount"`
+	}
+	if err := json.Unmarshal(resp.Data["order"], &order); err != nil || order.Amount.Value != "100.50" || order.Amount.Currency != "EUR" {
+		t.Errorf("Unexpected order: %s", resp.Data["order"])
+	}
+
+	resp = doGraphQL(t, h, `mutation { transitionOrder(id: 1, status: CONFIRMED, reason: "stock checked") { status history { from to reason } } }`)
+	if len(resp.Errors) != 0 {
+		t.Errorf("Error transitioning order: %v", resp.Errors)
+	}
+
+	if got := strings.Join(strings.Fields(string(resp.Data["transitionOrder"])), ""); !strings.Contains(got, `"status":"CONFIRMED"`) ||
+		!strings.Contains(got, `"reason":"stockchecked"`) {
+		t.Errorf("Unexpected transition result: %s", got)
+	}
+
+	resp = doGraphQL(t, h, `mutation { transitionOrder(id: 1, status: DELIVERED) { status } }`)
+	if len(resp.Errors) != 1 || resp.Errors[0].Extensions["code"] != "FAILED_PRECONDITION" {
+		t.Errorf("Expected FAILED_PRECONDITION error, got %v", resp.Errors)
+	}
+
+	resp = doGraphQL(t, h, `mutation { deleteOrder(id: 1) }`)
+	if len(resp.Errors) != 0 {
+		t.Errorf("Error deleting order: %v", resp.Errors)
+	}
+
+	resp = doGraphQL(t, h, `{ order(id: 1) { id } }`)
+	if len(resp.Errors) != 1 || resp.Errors[0].Extensions["code"] != "NOT_FOUND" {
+		t.Errorf("Expected NOT_FOUND error, got %v", resp.Errors)
+	}
+}
+
+func TestGraphQLOrdersConnection(t *testing.T) {
+	repo, err := repository.NewOrderMemoryRepository()
+	if err != nil {
+		t.Fatalf("Error creating repository")
+	}
+
+	h := NewGraphQL(usecase.NewOrderUseCase(repo))
+
+	for _, price := range []string{"5.00", "1.00", "3.00"} {
+		resp := doGraphQL(t, h, `mutation { createOrder(input: {item: "Bag", lines: [{sku: "BAG-1", quantity: 1, unitPrice: {value: "`+price+`"}}]}) { id } }`)
+		if len(resp.Errors) != 0 {
+			t.Fatalf("Error creating order: %v", resp.Errors)
+		}
+	}
+
+	var connection struct {
+		Edges []struct {
+			Cursor string `json:"cursor"`
+			Node   struct {
+				ID int `json:"id"`
+			} `json:"node"`
+		} `json:"edges"`
+		PageInfo struct {
+			HasNextPage bool   `json:"hasNextPage"`
+			EndCursor   string `json:"endCursor"`
+		} `json:"pageInfo"`
+	}
+
+	query := `{ orders(first: 2, after: "%s", orderBy: {field: AMOUNT}, filter: {minAmount: {value: "2"}}) { edges { cursor node { id } } pageInfo { hasNextPage endCursor } } }`
+
+	resp := doGraphQL(t, h, strings.Replace(query, `after: "%s", `, "", 1))
+	if err := json.Unmarshal(resp.Data["orders"], &connection); err != nil || len(resp.Errors) != 0 {
+		t.Fatalf("Error listing orders: %v %v", err, resp.Errors)
+	}
+
+	if len(connection.Edges) != 2 || connection.Edges[0].Node.ID != 3 || connection.Edges[1].Node.ID != 1 ||
+		connection.PageInfo.HasNextPage || connection.PageInfo.EndCursor != connection.Edges[1].Cursor {
+		t.Errorf("Unexpected connection: %s", resp.Data["orders"])
+	}
+
+	resp = doGraphQL(t, h, strings.Replace(query, "%s", connection.Edges[0].Cursor, 1))
+	if err := json.Unmarshal(resp.Data["orders"], &connection); err != nil || len(connection.Edges) != 1 || connection.Edges[0].Node.ID != 1 {
+		t.Errorf("Unexpected page after cursor: %s %v", resp.Data["orders"], resp.Errors)
+	}
+}
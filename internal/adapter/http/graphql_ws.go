@@ -0,0 +1,349 @@
+package http
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dyammarcano/fullcycle_clean_architecture/pkg/parameters"
+	"github.com/gorilla/websocket"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+)
+
+// graphQLWSProtocol is the WebSocket subprotocol of GraphQL over WebSocket, as
+// described in https://github.com/enisdenjo/graphql-ws/blob/master/PROTOCOL.md
+const graphQLWSProtocol = "graphql-transport-ws"
+
+const (
+	// connectionInitTimeout is how long a client has to send connection_init
+	connectionInitTimeout = 10 * time.Second
+	// maxGraphQLMessageSize is the largest message accepted from a client
+	maxGraphQLMessageSize = 64 << 10
+	// wsWriteTimeout bounds every write to a client
+	wsWriteTimeout = 10 * time.Second
+)
+
+// Close codes defined by the protocol
+const (
+	closeBadRequest       = 4400
+	closeUnauthorized     = 4401
+	closeForbidden        = 4403
+	closeBadSubprotocol   = 4406
+	closeInitTimeout      = 4408
+	closeSubscriberExists = 4409
+	closeTooManyInits     = 4429
+)
+
+// Message types of the protocol
+const (
+	msgConnectionInit = "connection_init"
+	msgConnectionAck  = "connection_ack"
+	msgPing           = "ping"
+	msgPong           = "pong"
+	msgSubscribe      = "subscribe"
+	msgNext           = "next"
+	msgError          = "error"
+	msgComplete       = "complete"
+)
+
+var graphQLUpgrader = websocket.Upgrader{Subprotocols: []string{graphQLWSProtocol}}
+
+type wsMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+type subscribePayload struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+// graphQLConn is a graphql-transport-ws connection and its running operations
+type graphQLConn struct {
+	ws     *websocket.Conn
+	schema *graphql.Schema
+
+	writeMu sync.Mutex
+
+	mu         sync.Mutex
+	operations map[string]context.CancelFunc
+	running    sync.WaitGroup
+}
+
+// GraphQLWebSocketHandler serves the GraphQL operations, subscriptions included,
+// over the graphql-transport-ws protocol. When a subscription token is configured
+// the connection_init payload must carry it as {"authorization": "Bearer <token>"}.
+// The server pings every heartbeat interval and drops a client that has not
+// answered within twice that; the connections close with 1001 when the server
+// shuts down.
+func (s *OrderServer) GraphQLWebSocketHandler(w http.ResponseWriter, r *http.Request) {
+	ws, err := graphQLUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader already answered the request
+		return
+	}
+
+	defer func() { _ = ws.Close() }()
+
+	if ws.Subprotocol() != graphQLWSProtocol {
+		closeWebSocket(ws, closeBadSubprotocol, "Subprotocol not acceptable")
+		return
+	}
+
+	heartbeat := s.heartbeat
+	if heartbeat <= 0 {
+		heartbeat = parameters.DefaultHeartbeat
+	}
+
+	conn := &graphQLConn{ws: ws, schema: s.graphQLSchema, operations: map[string]context.CancelFunc{}}
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer func() {
+		cancel()
+		conn.running.Wait()
+	}()
+
+	go func() {
+		ticker := time.NewTicker(heartbeat)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if conn.send("", msgPing, nil) != nil {
+					_ = ws.Close()
+					return
+				}
+			case <-s.closing:
+				closeWebSocket(ws, websocket.CloseGoingAway, "server is shutting down")
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	ws.SetReadLimit(maxGraphQLMessageSize)
+	_ = ws.SetReadDeadline(time.Now().Add(connectionInitTimeout))
+
+	acknowledged := false
+
+	for {
+		_, data, err := ws.ReadMessage()
+		if err != nil {
+			var netErr net.Error
+			if !acknowledged && errors.As(err, &netErr) && netErr.Timeout() {
+				closeWebSocket(ws, closeInitTimeout, "Connection initialisation timeout")
+			}
+
+			return
+		}
+
+		var msg wsMessage
+		if err = json.Unmarshal(data, &msg); err != nil {
+			closeWebSocket(ws, closeBadRequest, "Invalid message received")
+			return
+		}
+
+		switch msg.Type {
+		case msgConnectionInit:
+			if acknowledged {
+				closeWebSocket(ws, closeTooManyInits, "Too many initialisation requests")
+				return
+			}
+
+			if !s.authorizeSubscriber(msg.Payload) {
+				closeWebSocket(ws, closeForbidden, "Forbidden")
+				return
+			}
+
+			acknowledged = true
+			_ = conn.send("", msgConnectionAck, nil)
+		case msgPing:
+			_ = conn.send("", msgPong, nil)
+		case msgPong:
+		case msgSubscribe:
+			if !acknowledged {
+				closeWebSocket(ws, closeUnauthorized, "Unauthorized")
+				return
+			}
+
+			if code, reason := conn.subscribe(ctx, msg); code != 0 {
+				closeWebSocket(ws, code, reason)
+				return
+			}
+		case msgComplete:
+			conn.release(msg.ID)
+		default:
+			closeWebSocket(ws, closeBadRequest, fmt.Sprintf("Invalid message type %q", msg.Type))
+			return
+		}
+
+		if acknowledged {
+			_ = ws.SetReadDeadline(time.Now().Add(2 * heartbeat))
+		}
+	}
+}
+
+// authorizeSubscriber checks the token carried by a connection_init payload
+func (s *OrderServer) authorizeSubscriber(payload json.RawMessage) bool {
+	if s.subscriptionToken == "" {
+		return true
+	}
+
+	var init struct {
+		Authorization string `json:"authorization"`
+	}
+	_ = json.Unmarshal(payload, &init)
+
+	token, ok := strings.CutPrefix(init.Authorization, "Bearer ")
+
+	return ok && subtle.ConstantTimeCompare([]byte(token), []byte(s.subscriptionToken)) == 1
+}
+
+// subscribe starts the operation of a subscribe message, or returns the close code
+// and reason the message calls for
+func (c *graphQLConn) subscribe(ctx context.Context, msg wsMessage) (int, string) {
+	var payload subscribePayload
+	if msg.ID == "" || json.Unmarshal(msg.Payload, &payload) != nil || payload.Query == "" {
+		return closeBadRequest, "Invalid subscribe message"
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.operations[msg.ID]; ok {
+		return closeSubscriberExists, fmt.Sprintf("Subscriber for %s already exists", msg.ID)
+	}
+
+	opCtx, cancel := context.WithCancel(ctx)
+	c.operations[msg.ID] = cancel
+
+	c.running.Add(1)
+
+	go func() {
+		defer c.running.Done()
+		c.execute(opCtx, msg.ID, payload)
+	}()
+
+	return 0, ""
+}
+
+// execute runs an operation, sending its results until it completes or the client
+// completes it. Queries and mutations send a single result.
+func (c *graphQLConn) execute(ctx context.Context, id string, payload subscribePayload) {
+	doc, err := parser.Parse(parser.ParseParams{Source: source.NewSource(&source.Source{
+		Body: []byte(payload.Query),
+		Name: "GraphQL request",
+	})})
+	if err != nil {
+		c.fail(id, gqlerrors.FormatErrors(err))
+		return
+	}
+
+	if result := graphql.ValidateDocument(c.schema, doc, nil); !result.IsValid {
+		c.fail(id, result.Errors)
+		return
+	}
+
+	params := graphql.ExecuteParams{
+		Schema:        *c.schema,
+		AST:           doc,
+		OperationName: payload.OperationName,
+		Args:          payload.Variables,
+		Context:       ctx,
+	}
+
+	if operationType(doc, payload.OperationName) == ast.OperationTypeSubscription {
+		// The results are drained even once the operation is cancelled, so the
+		// executor does not block on them
+		for result := range graphql.ExecuteSubscription(params) {
+			if ctx.Err() == nil {
+				_ = c.send(id, msgNext, result)
+			}
+		}
+	} else {
+		_ = c.send(id, msgNext, graphql.Execute(params))
+	}
+
+	if c.release(id) {
+		_ = c.send(id, msgComplete, nil)
+	}
+}
+
+// fail ends an operation that could not be executed with an error message
+func (c *graphQLConn) fail(id string, errs []gqlerrors.FormattedError) {
+	if c.release(id) {
+		_ = c.send(id, msgError, errs)
+	}
+}
+
+// release cancels and forgets an operation, telling whether it was still running
+func (c *graphQLConn) release(id string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cancel, ok := c.operations[id]
+	if ok {
+		cancel()
+		delete(c.operations, id)
+	}
+
+	return ok
+}
+
+// send writes a message; the writes of the operations and the keep-alive are serialized
+func (c *graphQLConn) send(id, msgType string, payload any) error {
+	msg := wsMessage{ID: id, Type: msgType}
+
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+
+		msg.Payload = data
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	_ = c.ws.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+
+	return c.ws.WriteJSON(msg)
+}
+
+// operationType returns the type of the operation a request executes
+func operationType(doc *ast.Document, operationName string) string {
+	for _, definition := range doc.Definitions {
+		operation, ok := definition.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+
+		if operationName == "" || (operation.Name != nil && operation.Name.Value == operationName) {
+			return operation.Operation
+		}
+	}
+
+	return ""
+}
+
+// closeWebSocket sends a close frame and closes the connection
+func closeWebSocket(ws *websocket.Conn, code int, reason string) {
+	_ = ws.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(wsWriteTimeout))
+	_ = ws.Close()
+}
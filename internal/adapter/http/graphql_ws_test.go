@@ -0,0 +1,260 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dyammarcano/fullcycle_clean_architecture/pkg/logger"
+	"github.com/gorilla/websocket"
+)
+
+// wsClient speaks graphql-transport-ws, answering the pings of the server
+type wsClient struct {
+	conn     *websocket.Conn
+	writeMu  sync.Mutex
+	messages chan wsMessage
+	pings    atomic.Int32
+	err      error
+}
+
+func dialGraphQL(t *testing.T, url string) *wsClient {
+	t.Helper()
+
+	dialer := websocket.Dialer{Subprotocols: []string{graphQLWSProtocol}}
+
+	conn, _, err := dialer.Dial("ws"+strings.TrimPrefix(url, "http")+"/graphql", nil)
+	if err != nil {
+		t.Fatalf("Error dialing: %v", err)
+	}
+
+	t.Cleanup(func() { _ = conn.Close() })
+
+	c := &wsClient{conn: conn, messages: make(chan wsMessage, 16)}
+
+	go func() {
+		defer close(c.messages)
+
+		for {
+			var msg wsMessage
+			if c.err = conn.ReadJSON(&msg); c.err != nil {
+				return
+			}
+
+			if msg.Type == msgPing {
+				c.pings.Add(1)
+				_ = c.write(wsMessage{Type: msgPong})
+
+				continue
+			}
+
+			c.messages <- msg
+		}
+	}()
+
+	return c
+}
+
+func (c *wsClient) send(t *testing.T, id, msgType string, payload any) {
+	msg := wsMessage{ID: id, Type: msgType}
+	msg.Payload, _ = json.Marshal(payload)
+
+	if err := c.write(msg); err != nil {
+		t.Errorf("Error sending %s: %v", msgType, err)
+	}
+}
+
+func (c *wsClient) write(msg wsMessage) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	return c.conn.WriteJSON(msg)
+}
+
+func (c *wsClient) subscribe(t *testing.T, id, query string) {
+	c.send(t, id, msgSubscribe, map[string]string{"query": query})
+}
+
+// next returns the next message other than a ping
+func (c *wsClient) next(t *testing.T) wsMessage {
+	t.Helper()
+
+	select {
+	case msg, ok := <-c.messages:
+		if !ok {
+			t.Fatalf("Connection closed: %v", c.err)
+		}
+
+		return msg
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for a message")
+	}
+
+	return wsMessage{}
+}
+
+// closeCode waits for the server to close the connection and returns the close code
+func (c *wsClient) closeCode(t *testing.T) int {
+	t.Helper()
+
+	for {
+		select {
+		case _, ok := <-c.messages:
+			if ok {
+				continue
+			}
+
+			var closeErr *websocket.CloseError
+			if errors.As(c.err, &closeErr) {
+				return closeErr.Code
+			}
+
+			t.Fatalf("Expected a close frame, got %v", c.err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for the connection to close")
+		}
+	}
+}
+
+func TestGraphQLSubscriptions(t *testing.T) {
+	ctx := context.Background()
+
+	s := newTestServer(t)
+	s.graphQLSchema = newGraphQLSchema(s.UseCase)
+	s.subscriptionToken = "secret"
+	s.heartbeat = 500 * time.Millisecond
+
+	closing := make(chan struct{})
+	s.closing = closing
+
+	server := httptest.NewServer(logger.Middleware(http.HandlerFunc(s.GetGraphQLHandler)))
+	defer server.Close()
+
+	init := map[string]string{"authorization": "Bearer secret"}
+
+	forbidden := dialGraphQL(t, server.URL)
+	forbidden.send(t, "", msgConnectionInit, map[string]string{"authorization": "Bearer wrong"})
+
+	if code := forbidden.closeCode(t); code != closeForbidden {
+		t.Errorf("Close code with a wrong token = %d, want %d", code, closeForbidden)
+	}
+
+	early := dialGraphQL(t, server.URL)
+	early.subscribe(t, "1", `subscription { orderCreated { id } }`)
+
+	if code := early.closeCode(t); code != closeUnauthorized {
+		t.Errorf("Close code when subscribing before connection_init = %d, want %d", code, closeUnauthorized)
+	}
+
+	client := dialGraphQL(t, server.URL)
+	client.send(t, "", msgConnectionInit, init)
+
+	if ack := client.next(t); ack.Type != msgConnectionAck {
+		t.Fatalf("Expected connection_ack, got %+v", ack)
+	}
+
+	client.subscribe(t, "created", `subscription { orderCreated { id item } }`)
+	client.subscribe(t, "updated", `subscription { orderUpdated { id status } }`)
+	client.subscribe(t, "other", `subscription { orderUpdated(ids: [999]) { id } }`)
+	client.subscribe(t, "deleted", `subscription { orderDeleted { id } }`)
+
+	time.Sleep(100 * time.Millisecond)
+
+	created, err := s.UseCase.CreateOrder(ctx, []byte(`{"item":"Bag","lines":[{"sku":"BAG-1","quantity":1,"unitPrice":{"value":"10.00","currency":"USD"}}]}`))
+	if err != nil {
+		t.Fatalf("Error creating order: %v", err)
+	}
+
+	var result graphQLResponse
+
+	next := func(id string) {
+		t.Helper()
+
+		msg := client.next(t)
+		if msg.ID != id || msg.Type != msgNext {
+			t.Fatalf("Expected a result for %s, got %+v", id, msg)
+		}
+
+		result = graphQLResponse{}
+		if err := json.Unmarshal(msg.Payload, &result); err != nil {
+			t.Fatalf("Error decoding result: %v", err)
+		}
+	}
+
+	if next("created"); !strings.Contains(string(result.Data["orderCreated"]), `"item":"Bag"`) {
+		t.Errorf("Unexpected orderCreated result %s", result.Data["orderCreated"])
+	}
+
+	if _, err = s.UseCase.TransitionOrder(ctx, created.ID, "confirmed", ""); err != nil {
+		t.Fatalf("Error confirming order: %v", err)
+	}
+
+	if next("updated"); !strings.Contains(string(result.Data["orderUpdated"]), `"status":"CONFIRMED"`) {
+		t.Errorf("Unexpected orderUpdated result %s", result.Data["orderUpdated"])
+	}
+
+	client.send(t, "updated", msgComplete, nil)
+	time.Sleep(50 * time.Millisecond)
+
+	if _, err = s.UseCase.UpdateOrder(ctx, created.ID, []byte(`{"item":"Hat","lines":[{"sku":"HAT-1","quantity":1,"unitPrice":{"value":"5.00","currency":"USD"}}]}`)); err != nil {
+		t.Fatalf("Error updating order: %v", err)
+	}
+
+	if err = s.UseCase.DeleteOrder(ctx, created.ID, 0); err != nil {
+		t.Fatalf("Error deleting order: %v", err)
+	}
+
+	if next("deleted"); !strings.Contains(string(result.Data["orderDeleted"]), `"id":`) {
+		t.Errorf("Unexpected orderDeleted result %s", result.Data["orderDeleted"])
+	}
+
+	client.subscribe(t, "query", `{ order(id: `+strconv.Itoa(created.ID)+`) { id } }`)
+
+	if next("query"); len(result.Errors) != 1 || result.Errors[0].Extensions["code"] != "NOT_FOUND" {
+		t.Errorf("Expected NOT_FOUND for the deleted order, got %v", result.Errors)
+	}
+
+	if complete := client.next(t); complete.ID != "query" || complete.Type != msgComplete {
+		t.Errorf("Expected the query to complete, got %+v", complete)
+	}
+
+	client.subscribe(t, "invalid", `subscription { orderPaid { id } }`)
+
+	if invalid := client.next(t); invalid.ID != "invalid" || invalid.Type != msgError {
+		t.Errorf("Expected an error for an unknown field, got %+v", invalid)
+	}
+
+	// A slow heartbeat keeps a loaded test client from being dropped as unresponsive,
+	// so the first ping may still be on its way
+	for deadline := time.Now().Add(2 * time.Second); client.pings.Load() == 0 && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if client.pings.Load() == 0 {
+		t.Error("Expected keep-alive pings")
+	}
+
+	duplicate := dialGraphQL(t, server.URL)
+	duplicate.send(t, "", msgConnectionInit, init)
+	duplicate.next(t)
+	duplicate.subscribe(t, "1", `subscription { orderCreated { id } }`)
+	duplicate.subscribe(t, "1", `subscription { orderDeleted { id } }`)
+
+	if code := duplicate.closeCode(t); code != closeSubscriberExists {
+		t.Errorf("Close code for a duplicate subscription ID = %d, want %d", code, closeSubscriberExists)
+	}
+
+	close(closing)
+
+	if code := client.closeCode(t); code != websocket.CloseGoingAway {
+		t.Errorf("Close code on shutdown = %d, want %d", code, websocket.CloseGoingAway)
+	}
+}
@@ -1,20 +1,29 @@
 package http
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"log/slog"
 	"net/http"
+	"net/url"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/dyammarcano/fullcycle_clean_architecture/internal/adapter/errmap"
 	"github.com/dyammarcano/fullcycle_clean_architecture/internal/domain"
+	"github.com/dyammarcano/fullcycle_clean_architecture/internal/entity"
 	"github.com/dyammarcano/fullcycle_clean_architecture/internal/usecase"
 	"github.com/dyammarcano/fullcycle_clean_architecture/pkg/logger"
 	"github.com/dyammarcano/fullcycle_clean_architecture/pkg/parameters"
 	"github.com/dyammarcano/fullcycle_clean_architecture/pkg/util"
+	"github.com/gorilla/websocket"
 	"github.com/graphql-go/graphql"
-	"github.com/graphql-go/handler"
 	"github.com/inovacc/config"
 )
 
@@ -23,172 +32,526 @@ type OrderServer struct {
 	http.Handler
 
 	UseCase *usecase.OrderUseCase
+	// Webhooks manages the webhooks under /admin/webhooks
+	Webhooks *usecase.WebhookUseCase
+
+	// graphQLSchema is served over HTTP by Handler and over WebSocket by GraphQLWebSocketHandler
+	graphQLSchema *graphql.Schema
+	// subscriptionToken is required in connection_init by GraphQLWebSocketHandler when set
+	subscriptionToken string
+	// adminToken guards the /admin routes, which are disabled while it is empty
+	adminToken string
+	// purgeRetention is the purge retention used when the request sets none
+	purgeRetention time.Duration
+	// heartbeat is how often an idle change stream sends a keep-alive message
+	heartbeat time.Duration
+	// closing is closed when the server shuts down, ending the change streams that
+	// would otherwise keep Shutdown waiting
+	closing <-chan struct{}
 }
 
+// ListOrdersHandler answers one page of orders. The next page, when there is one,
+// is announced in a Link header with rel="next".
 func (s *OrderServer) ListOrdersHandler(w http.ResponseWriter, r *http.Request) {
-	orders, err := s.UseCase.ListOrders()
+	query, err := listOrdersQuery(r.URL.Query())
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
-	util.HelperJSON(w, r, orders)
+	page, err := s.UseCase.ListOrders(r.Context(), query, r.URL.Query().Get("page_token"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeOrderPage(w, r, page)
+}
+
+// writeOrderPage answers the orders of page, announcing the next page in a Link header
+func writeOrderPage(w http.ResponseWriter, r *http.Request, page *domain.OrderPage) {
+	if page.NextPageToken != "" {
+		next := *r.URL
+		params := next.Query()
+		params.Set("page_token", page.NextPageToken)
+		next.RawQuery = params.Encode()
+
+		w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="next"`, next.RequestURI()))
+	}
+
+	util.HelperJSON(w, r, page.Orders)
 }
 
+// GetGraphQLHandler serves the GraphQL requests, and the WebSocket connections
+// that carry subscriptions
 func (s *OrderServer) GetGraphQLHandler(w http.ResponseWriter, r *http.Request) {
+	if websocket.IsWebSocketUpgrade(r) {
+		s.GraphQLWebSocketHandler(w, r)
+		return
+	}
+
 	s.ServeHTTP(w, r)
 }
 
 func (s *OrderServer) CreateOrderHandler(w http.ResponseWriter, r *http.Request) {
-	orderBytes, err := util.ReadBytes(r.Body)
+	order, err := decodeOrder(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	var order domain.Order
-	if err := json.Unmarshal(orderBytes, &order); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, r, err)
 		return
 	}
 
-	created, err := s.UseCase.CreateOrder(order.Bytes())
+	created, err := s.UseCase.CreateOrder(r.Context(), order.Bytes())
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
-	util.HelperJSON(w, r, created)
+	writeOrder(w, r, created)
 }
 
 func (s *OrderServer) GetOrderByIDHandler(w http.ResponseWriter, r *http.Request) {
-	idInt, err := strconv.Atoi(r.PathValue("id"))
+	idInt, err := pathID(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
-	order, err := s.UseCase.GetOrderByID(idInt)
+	order, err := s.UseCase.GetOrderByID(r.Context(), idInt)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
-	util.HelperJSON(w, r, order)
+	writeOrder(w, r, order)
 }
 
+// UpdateOrderHandler replaces an order. With an If-Match header the update only
+// applies to the version it names and answers 412 otherwise; the version in the
+// body is ignored.
 func (s *OrderServer) UpdateOrderHandler(w http.ResponseWriter, r *http.Request) {
-	idInt, err := strconv.Atoi(r.PathValue("id"))
+	idInt, err := pathID(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
-	orderBytes, err := util.ReadBytes(r.Body)
+	version, err := s.ifMatchVersion(r, idInt)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
-	var order domain.Order
-	if err := json.Unmarshal(orderBytes, &order); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	order, err := decodeOrder(r)
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
 
-	_, err = s.UseCase.UpdateOrder(idInt, order.Bytes())
+	order.Version = version
+
+	updated, err := s.UseCase.UpdateOrder(r.Context(), idInt, order.Bytes())
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
+	w.Header().Set("ETag", etag(updated))
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// DeleteOrderHandler soft-deletes an order, honouring If-Match like UpdateOrderHandler
 func (s *OrderServer) DeleteOrderHandler(w http.ResponseWriter, r *http.Request) {
-	idInt, err := strconv.Atoi(r.PathValue("id"))
+	idInt, err := pathID(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	version, err := s.ifMatchVersion(r, idInt)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
-	if err = s.UseCase.DeleteOrder(idInt); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err = s.UseCase.DeleteOrder(r.Context(), idInt, version); err != nil {
+		writeError(w, r, err)
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
-func NewGraphQL(useCase *usecase.OrderUseCase) http.Handler {
-	orderType := graphql.NewObject(graphql.ObjectConfig{
-		Name: "Order",
-		Fields: graphql.Fields{
-			"id": &graphql.Field{
-				Type: graphql.Int,
-			},
-			"item": &graphql.Field{
-				Type: graphql.String,
-			},
-			"amount": &graphql.Field{
-				Type: graphql.Float,
-			},
-		},
-	})
+// TransitionOrderHandler applies a lifecycle action (confirm, pay, ship, deliver,
+// cancel, refund) to an order. The body may carry {"reason": "..."}.
+func (s *OrderServer) TransitionOrderHandler(w http.ResponseWriter, r *http.Request) {
+	idInt, err := pathID(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
 
-	rootQuery := graphql.NewObject(graphql.ObjectConfig{
-		Name: "RootQuery",
-		Fields: graphql.Fields{
-			"listOrders": &graphql.Field{
-				Type: graphql.NewList(orderType),
-				Resolve: func(params graphql.ResolveParams) (any, error) {
-					return useCase.ListOrders()
-				},
-			},
-		},
-	})
+	next, ok := domain.StatusForAction(r.PathValue("action"))
+	if !ok {
+		writeError(w, r, domain.NewValidationError(domain.FieldError{
+			Field:   "action",
+			Rule:    "enum",
+			Message: "must be one of confirm, pay, ship, deliver, cancel, refund",
+		}))
 
-	schema, _ := graphql.NewSchema(graphql.SchemaConfig{
-		Query: rootQuery,
-	})
+		return
+	}
+
+	var body struct {
+		Reason string `json:"reason"`
+	}
+
+	bodyBytes, err := util.ReadBytes(r.Body)
+	if err != nil {
+		writeError(w, r, domain.WrapError(domain.ErrValidation, "request body could not be read", err))
+		return
+	}
+
+	if len(bodyBytes) > 0 {
+		if err = json.Unmarshal(bodyBytes, &body); err != nil {
+			writeError(w, r, domain.NewValidationError(domain.FieldError{
+				Field:   "body",
+				Rule:    "json",
+				Message: "must be a valid JSON object",
+			}))
+
+			return
+		}
+	}
+
+	order, err := s.UseCase.TransitionOrder(r.Context(), idInt, next, body.Reason)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeOrder(w, r, order)
+}
+
+func (s *OrderServer) GetOrderHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	idInt, err := pathID(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	history, err := s.UseCase.ListStatusHistory(r.Context(), idInt)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	util.HelperJSON(w, r, history)
+}
+
+// poolStatser is implemented by repositories backed by a database/sql pool
+type poolStatser interface {
+	Stats() sql.DBStats
+}
+
+// dbStats is the JSON form of sql.DBStats
+type dbStats struct {
+	MaxOpenConnections int   `json:"maxOpenConnections"`
+	OpenConnections    int   `json:"openConnections"`
+	InUse              int   `json:"inUse"`
+	Idle               int   `json:"idle"`
+	WaitCount          int64 `json:"waitCount"`
+	WaitDurationMillis int64 `json:"waitDurationMillis"`
+	MaxIdleClosed      int64 `json:"maxIdleClosed"`
+	MaxIdleTimeClosed  int64 `json:"maxIdleTimeClosed"`
+	MaxLifetimeClosed  int64 `json:"maxLifetimeClosed"`
+}
+
+// DBStatsHandler answers the connection pool statistics of the storage backend,
+// or 404 when the backend has no pool
+func (s *OrderServer) DBStatsHandler(w http.ResponseWriter, r *http.Request) {
+	statser, ok := s.UseCase.OrderRepo.(poolStatser)
+	if !ok {
+		writeError(w, r, domain.WrapError(domain.ErrNotFound, "storage backend has no connection pool", nil))
+		return
+	}
 
-	graphqlHandler := handler.New(&handler.Config{
-		Schema:     &schema,
-		Pretty:     true,
-		Playground: true,
+	stats := statser.Stats()
+
+	util.HelperJSON(w, r, dbStats{
+		MaxOpenConnections: stats.MaxOpenConnections,
+		OpenConnections:    stats.OpenConnections,
+		InUse:              stats.InUse,
+		Idle:               stats.Idle,
+		WaitCount:          stats.WaitCount,
+		WaitDurationMillis: stats.WaitDuration.Milliseconds(),
+		MaxIdleClosed:      stats.MaxIdleClosed,
+		MaxIdleTimeClosed:  stats.MaxIdleTimeClosed,
+		MaxLifetimeClosed:  stats.MaxLifetimeClosed,
 	})
+}
+
+// pathID parses the {id} path value of the request
+func pathID(r *http.Request) (int, error) {
+	// a non numeric id parses as zero and is rejected by the entity rules
+	id, _ := strconv.Atoi(r.PathValue("id"))
+
+	return id, entity.ValidateID(id)
+}
+
+// writeOrder answers order with its version as ETag
+func writeOrder(w http.ResponseWriter, r *http.Request, order *domain.Order) {
+	w.Header().Set("ETag", etag(order))
+	util.HelperJSON(w, r, order)
+}
+
+// etag returns the strong entity tag of the order version
+func etag(order *domain.Order) string {
+	return `"` + strconv.Itoa(order.Version) + `"`
+}
+
+// ifMatchVersion returns the order version named by the If-Match header, or zero
+// when the header is absent or "*" and any version is accepted. If-Match uses the
+// strong comparison, so weak ETags never match; when the header lists several
+// versions the stored one is looked up to pick the version the write applies to.
+func (s *OrderServer) ifMatchVersion(r *http.Request, id int) (int, error) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" || header == "*" {
+		return 0, nil
+	}
+
+	versions, ok := parseIfMatch(header)
+	if !ok {
+		return 0, domain.NewValidationError(domain.FieldError{
+			Field:   "If-Match",
+			Rule:    "etag",
+			Message: `must be "*" or a list of ETags returned for the order`,
+		})
+	}
+
+	switch len(versions) {
+	case 0:
+		return 0, domain.ErrOrderVersionMismatch
+	case 1:
+		return versions[0], nil
+	}
+
+	order, err := s.UseCase.GetOrderByID(r.Context(), id)
+	if err != nil {
+		return 0, err
+	}
+
+	if !slices.Contains(versions, order.Version) {
+		return 0, domain.ErrOrderVersionMismatch
+	}
+
+	return order.Version, nil
+}
+
+// parseIfMatch returns the versions named by the strong ETags of an If-Match list.
+// Weak ETags and ETags that are not a version are skipped, as they never match.
+func parseIfMatch(header string) ([]int, bool) {
+	var versions []int
+
+	for {
+		header = strings.TrimLeft(header, " \t,")
+		if header == "" {
+			return versions, true
+		}
+
+		weak := false
+		if rest, ok := strings.CutPrefix(header, "W/"); ok {
+			weak, header = true, rest
+		}
+
+		rest, ok := strings.CutPrefix(header, `"`)
+		if !ok {
+			return nil, false
+		}
+
+		tag, rest, ok := strings.Cut(rest, `"`)
+		if !ok {
+			return nil, false
+		}
+
+		if version, err := strconv.Atoi(tag); err == nil && version > 0 && !weak {
+			versions = append(versions, version)
+		}
+
+		rest = strings.TrimLeft(rest, " \t")
+		if rest == "" {
+			return versions, true
+		}
+
+		if header, ok = strings.CutPrefix(rest, ","); !ok {
+			return nil, false
+		}
+	}
+}
+
+// decodeOrder reads the request body into a domain order
+func decodeOrder(r *http.Request) (*domain.Order, error) {
+	orderBytes, err := util.ReadBytes(r.Body)
+	if err != nil {
+		return nil, domain.WrapError(domain.ErrValidation, "request body could not be read", err)
+	}
+
+	order := &domain.Order{}
+	if err := json.Unmarshal(orderBytes, order); err != nil {
+		if errors.Is(err, domain.ErrValidation) {
+			return nil, err
+		}
+
+		return nil, domain.NewValidationError(domain.FieldError{
+			Field:   "body",
+			Rule:    "json",
+			Message: "must be a valid JSON order",
+		})
+	}
 
-	return graphqlHandler
+	return order, nil
 }
 
-func NewHttpOrderServer(useCase *usecase.OrderUseCase) *OrderServer {
+// listOrdersQuery reads the filter, sort and page size of GET /order. Amount bounds
+// are decimals in the currency parameter, USD when absent.
+func listOrdersQuery(params url.Values) (domain.ListOrdersQuery, error) {
+	var (
+		query  domain.ListOrdersQuery
+		fields []domain.FieldError
+		err    error
+	)
+
+	if v := params.Get("page_size"); v != "" {
+		if query.PageSize, err = strconv.Atoi(v); err != nil {
+			fields = append(fields, domain.FieldError{Field: "page_size", Rule: "integer", Message: "must be an integer"})
+		}
+	}
+
+	if query.SortBy, query.Descending, err = domain.ParseOrderSort(params.Get("order_by")); err != nil {
+		fields = append(fields, domain.FieldError{Field: "order_by", Rule: "sort", Message: err.Error()})
+	}
+
+	query.Filter.ItemContains = params.Get("item")
+
+	for _, bound := range []struct {
+		param  string
+		amount **domain.Money
+	}{{"min_amount", &query.Filter.MinAmount}, {"max_amount", &query.Filter.MaxAmount}} {
+		v := params.Get(bound.param)
+		if v == "" {
+			continue
+		}
+
+		amount, err := domain.ParseMoney(v, params.Get("currency"))
+		if err != nil {
+			fields = append(fields, domain.FieldError{Field: bound.param, Rule: "decimal", Message: err.Error()})
+			continue
+		}
+
+		*bound.amount = &amount
+	}
+
+	if len(fields) > 0 {
+		return query, domain.NewValidationError(fields...)
+	}
+
+	return query, nil
+}
+
+// writeError answers the request with an RFC 7807 problem matching the error kind
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	requestID := logger.RequestID(r.Context())
+
+	if !errmap.IsKnown(err) {
+		slog.Error("HTTP request failed",
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.String("request_id", requestID),
+			slog.String("error", err.Error()),
+		)
+	}
+
+	problem := &util.Problem{
+		Type:      errmap.ProblemType(err),
+		Title:     errmap.HTTPTitle(err),
+		Status:    errmap.HTTPStatus(err),
+		Detail:    errmap.Message(err),
+		RequestID: requestID,
+	}
+
+	for _, f := range errmap.FieldErrors(err) {
+		problem.Errors = append(problem.Errors, util.ProblemField{Field: f.Field, Rule: f.Rule, Message: f.Message})
+	}
+
+	util.HelperProblem(w, r, problem)
+}
+
+func NewHttpOrderServer(useCase *usecase.OrderUseCase, webhooks *usecase.WebhookUseCase) *OrderServer {
 	cfg, err := config.GetServiceConfig[*parameters.Service]()
 	if err != nil {
 		log.Fatalf("Failed to get service config: %v", err)
 	}
 
+	schema := newGraphQLSchema(useCase)
+
 	orderServer := &OrderServer{
-		UseCase: useCase,
-		Handler: NewGraphQL(useCase),
+		UseCase:           useCase,
+		Webhooks:          webhooks,
+		Handler:           newGraphQLHandler(schema),
+		graphQLSchema:     schema,
+		subscriptionToken: cfg.Http.SubscriptionToken,
+		adminToken:        cfg.Http.AdminToken,
+		purgeRetention:    cfg.Database.GetSoftDeleteRetention(),
+		heartbeat:         cfg.Http.GetHeartbeat(),
 	}
 
-	router := http.NewServeMux()
-	router.HandleFunc("GET /order", orderServer.ListOrdersHandler)
-	router.HandleFunc("POST /order", orderServer.CreateOrderHandler)
-	router.HandleFunc("GET /order/{id}", orderServer.GetOrderByIDHandler)
-	router.HandleFunc("PUT /order/{id}", orderServer.UpdateOrderHandler)
-	router.HandleFunc("DELETE /order/{id}", orderServer.DeleteOrderHandler)
-	router.HandleFunc("/graphql", orderServer.GetGraphQLHandler)
-
 	orderServer.Server = http.Server{
 		Addr:    fmt.Sprintf(":%d", cfg.Http.Port),
-		Handler: logger.Middleware(router),
+		Handler: logger.Middleware(orderServer.routes()),
 	}
 
+	streams, closeStreams := context.WithCancel(context.Background())
+	orderServer.closing = streams.Done()
+	orderServer.RegisterOnShutdown(closeStreams)
+
 	return orderServer
 }
 
+// routes maps the REST, GraphQL and admin routes to the handlers of s
+func (s *OrderServer) routes() *http.ServeMux {
+	router := http.NewServeMux()
+	router.HandleFunc("GET /order", s.ListOrdersHandler)
+	router.HandleFunc("POST /order", s.CreateOrderHandler)
+	router.HandleFunc("GET /order/events", s.OrderEventsHandler)
+	router.HandleFunc("GET /order/{id}", s.GetOrderByIDHandler)
+	router.HandleFunc("PUT /order/{id}", s.UpdateOrderHandler)
+	router.HandleFunc("DELETE /order/{id}", s.DeleteOrderHandler)
+	router.HandleFunc("GET /order/{id}/history", s.GetOrderHistoryHandler)
+	router.HandleFunc("POST /order/{id}/{action}", s.TransitionOrderHandler)
+	router.HandleFunc("/graphql", s.GetGraphQLHandler)
+	router.HandleFunc("GET /admin/db/stats", s.requireAdmin(s.DBStatsHandler))
+	router.HandleFunc("GET /admin/order/deleted", s.requireAdmin(s.ListDeletedOrdersHandler))
+	router.HandleFunc("DELETE /admin/order/deleted", s.requireAdmin(s.PurgeDeletedOrdersHandler))
+	router.HandleFunc("POST /admin/order/{id}/restore", s.requireAdmin(s.RestoreOrderHandler))
+	router.HandleFunc("POST /admin/webhooks", s.requireAdmin(s.CreateWebhookHandler))
+	router.HandleFunc("GET /admin/webhooks", s.requireAdmin(s.ListWebhooksHandler))
+	router.HandleFunc("GET /admin/webhooks/{id}", s.requireAdmin(s.GetWebhookHandler))
+	router.HandleFunc("PUT /admin/webhooks/{id}", s.requireAdmin(s.UpdateWebhookHandler))
+	router.HandleFunc("DELETE /admin/webhooks/{id}", s.requireAdmin(s.DeleteWebhookHandler))
+	router.HandleFunc("GET /admin/webhooks/{id}/deliveries", s.requireAdmin(s.ListDeliveriesHandler))
+	router.HandleFunc("GET /admin/webhooks/{id}/deliveries/{deliveryID}", s.requireAdmin(s.GetDeliveryHandler))
+	router.HandleFunc("POST /admin/webhooks/{id}/deliveries/{deliveryID}/redeliver", s.requireAdmin(s.RedeliverHandler))
+
+	return router
+}
+
+// Start serves HTTP on the configured port until Shutdown is called. It returns
+// nil once the server was shut down.
 func (s *OrderServer) Start() error {
 	slog.Info("HTTP server is running on port", slog.String("port", s.Addr))
-	return s.ListenAndServe()
+
+	if err := s.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
 }
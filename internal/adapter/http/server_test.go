@@ -0,0 +1,302 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dyammarcano/fullcycle_clean_architecture/internal/domain"
+	"github.com/dyammarcano/fullcycle_clean_architecture/internal/repository"
+	"github.com/dyammarcano/fullcycle_clean_architecture/internal/usecase"
+	"github.com/dyammarcano/fullcycle_clean_architecture/pkg/util"
+)
+
+func newTestServer(t *testing.T) *OrderServer {
+	t.Helper()
+
+	repo, err := repository.NewOrderMemoryRepository()
+	if err != nil {
+		t.Fatalf("Error creating repository")
+	}
+
+	return &OrderServer{UseCase: usecase.NewOrderUseCase(repo), Webhooks: usecase.NewWebhookUseCase(repo)}
+}
+
+func decodeProblem(t *testing.T, rec *httptest.ResponseRecorder) util.Problem {
+	t.Helper()
+
+	if ct := rec.Header().Get("Content-Type"); ct != "application/problem+json" {
+		t.Fatalf("Expected problem+json content type, got %q", ct)
+	}
+
+	var problem util.Problem
+	if err := json.Unmarshal(rec.Body.Bytes(), &problem); err != nil {
+		t.Fatalf("Error decoding problem: %v", err)
+	}
+
+	return problem
+}
+
+func TestProblemResponses(t *testing.T) {
+	s := newTestServer(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/order/abc", nil)
+	req.SetPathValue("id", "abc")
+	rec := httptest.NewRecorder()
+	s.GetOrderByIDHandler(rec, req)
+
+	problem := decodeProblem(t, rec)
+	if problem.Status != http.StatusBadRequest || len(problem.Errors) != 1 || problem.Errors[0].Field != "id" {
+		t.Errorf("Unexpected problem for invalid id: %+v", problem)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/order/42", nil)
+	req.SetPathValue("id", "42")
+	rec = httptest.NewRecorder()
+	s.GetOrderByIDHandler(rec, req)
+
+	problem = decodeProblem(t, rec)
+	if problem.Status != http.StatusNotFound || problem.Type != "/problems/not-found" || problem.Instance != "/order/42" {
+		t.Errorf("Unexpected problem for missing order: %+v", problem)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/order", strings.NewReader("{"))
+	rec = httptest.NewRecorder()
+	s.CreateOrderHandler(rec, req)
+
+	problem = decodeProblem(t, rec)
+	if problem.Status != http.StatusBadRequest || len(problem.Errors) != 1 || problem.Errors[0].Field != "body" {
+		t.Errorf("Unexpected problem for malformed body: %+v", problem)
+	}
+}
+
+func TestTransitionOrderHandler(t *testing.T) {
+	s := newTestServer(t)
+
+	body := `{"item":"Bag","lines":[{"sku":"BAG-1","quantity":1,"unitPrice":{"value":"10.00","currency":"USD"}}]}`
+	rec := httptest.NewRecorder()
+	s.CreateOrderHandler(rec, httptest.NewRequest(http.MethodPost, "/order", strings.NewReader(body)))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Error creating order: %s", rec.Body.String())
+	}
+
+	transition := func(action, reason string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/order/1/"+action, strings.NewReader(reason))
+		req.SetPathValue("id", "1")
+		req.SetPathValue("action", action)
+
+		rec := httptest.NewRecorder()
+		s.TransitionOrderHandler(rec, req)
+
+		return rec
+	}
+
+	if rec = transition("cancel", `{"reason":"customer request"}`); rec.Code != http.StatusOK {
+		t.Fatalf("Error cancelling order: %s", rec.Body.String())
+	}
+
+	rec = transition("pay", "")
+	if problem := decodeProblem(t, rec); problem.Status != http.StatusConflict || problem.Type != "/problems/failed-precondition" {
+		t.Errorf("Unexpected problem for invalid transition: %+v", problem)
+	}
+
+	rec = transition("explode", "")
+	if problem := decodeProblem(t, rec); problem.Status != http.StatusBadRequest {
+		t.Errorf("Unexpected problem for unknown action: %+v", problem)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/order/1/history", nil)
+	req.SetPathValue("id", "1")
+	rec = httptest.NewRecorder()
+	s.GetOrderHistoryHandler(rec, req)
+
+	var history []domain.StatusTransition
+	if err := json.Unmarshal(rec.Body.Bytes(), &history); err != nil {
+		t.Fatalf("Error decoding history: %v", err)
+	}
+
+	if len(history) != 1 || history[0].To != domain.StatusCancelled || history[0].Reason != "customer request" {
+		t.Errorf("Unexpected history: %+v", history)
+	}
+}
+
+func TestConditionalRequests(t *testing.T) {
+	s := newTestServer(t)
+
+	body := `{"item":"Bag","lines":[{"sku":"BAG-1","quantity":1,"unitPrice":{"value":"10.00","currency":"USD"}}]}`
+	rec := httptest.NewRecorder()
+	s.CreateOrderHandler(rec, httptest.NewRequest(http.MethodPost, "/order", strings.NewReader(body)))
+
+	if etag := rec.Header().Get("ETag"); etag != `"1"` {
+		t.Fatalf("ETag of a created order = %q, want \"1\"", etag)
+	}
+
+	send := func(handler http.HandlerFunc, method, ifMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/order/1", strings.NewReader(body))
+		req.SetPathValue("id", "1")
+
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+
+		return rec
+	}
+
+	if rec = send(s.UpdateOrderHandler, http.MethodPut, `"1"`); rec.Code != http.StatusNoContent || rec.Header().Get("ETag") != `"2"` {
+		t.Fatalf("Update with a matching ETag = %d, ETag %q", rec.Code, rec.Header().Get("ETag"))
+	}
+
+	rec = send(s.UpdateOrderHandler, http.MethodPut, `"1"`)
+	if problem := decodeProblem(t, rec); problem.Status != http.StatusPreconditionFailed || problem.Type != "/problems/version-mismatch" {
+		t.Errorf("Unexpected problem for a stale ETag: %+v", problem)
+	}
+
+	rec = send(s.UpdateOrderHandler, http.MethodPut, `W/"2"`)
+	if problem := decodeProblem(t, rec); problem.Status != http.StatusPreconditionFailed || problem.Type != "/problems/version-mismatch" {
+		t.Errorf("Unexpected problem for a weak ETag: %+v", problem)
+	}
+
+	rec = send(s.UpdateOrderHandler, http.MethodPut, `"1", W/"2"`)
+	if problem := decodeProblem(t, rec); problem.Status != http.StatusPreconditionFailed || problem.Type != "/problems/version-mismatch" {
+		t.Errorf("Unexpected problem for a list without a current strong ETag: %+v", problem)
+	}
+
+	rec = send(s.UpdateOrderHandler, http.MethodPut, "2")
+	if problem := decodeProblem(t, rec); problem.Status != http.StatusBadRequest || problem.Errors[0].Field != "If-Match" {
+		t.Errorf("Unexpected problem for an unquoted ETag: %+v", problem)
+	}
+
+	if rec = send(s.GetOrderByIDHandler, http.MethodGet, ""); rec.Header().Get("ETag") != `"2"` {
+		t.Errorf("ETag after update = %q, want \"2\"", rec.Header().Get("ETag"))
+	}
+
+	if rec = send(s.UpdateOrderHandler, http.MethodPut, `"1", "2"`); rec.Code != http.StatusNoContent || rec.Header().Get("ETag") != `"3"` {
+		t.Errorf("Update with a list naming the current ETag = %d, ETag %q", rec.Code, rec.Header().Get("ETag"))
+	}
+
+	rec = send(s.DeleteOrderHandler, http.MethodDelete, `"1"`)
+	if problem := decodeProblem(t, rec); problem.Status != http.StatusPreconditionFailed {
+		t.Errorf("Unexpected problem for a stale delete: %+v", problem)
+	}
+
+	if rec = send(s.DeleteOrderHandler, http.MethodDelete, "*"); rec.Code != http.StatusNoContent {
+		t.Errorf("Delete with If-Match * = %d: %s", rec.Code, rec.Body)
+	}
+}
+
+func TestListOrdersHandlerPagination(t *testing.T) {
+	s := newTestServer(t)
+
+	for _, item := range []string{"Bag", "Hat", "Shoes"} {
+		body := `{"item":"` + item + `","lines":[{"sku":"SKU-1","quantity":1,"unitPrice":{"value":"10.00","currency":"USD"}}]}`
+		rec := httptest.NewRecorder()
+		s.CreateOrderHandler(rec, httptest.NewRequest(http.MethodPost, "/order", strings.NewReader(body)))
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Error creating order: %s", rec.Body.String())
+		}
+	}
+
+	list := func(target string) ([]domain.Order, string) {
+		rec := httptest.NewRecorder()
+		s.ListOrdersHandler(rec, httptest.NewRequest(http.MethodGet, target, nil))
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Error listing orders: %s", rec.Body.String())
+		}
+
+		var orders []domain.Order
+		if err := json.Unmarshal(rec.Body.Bytes(), &orders); err != nil {
+			t.Fatalf("Error decoding orders: %v", err)
+		}
+
+		link := rec.Header().Get("Link")
+		if link == "" {
+			return orders, ""
+		}
+
+		return orders, strings.TrimSuffix(strings.TrimPrefix(link, "<"), `>; rel="next"`)
+	}
+
+	orders, next := list("/order?page_size=2&order_by=item+desc")
+	if len(orders) != 2 || orders[0].Item != "Shoes" || orders[1].Item != "Hat" || next == "" {
+		t.Fatalf("Unexpected first page %+v, next %q", orders, next)
+	}
+
+	if orders, next = list(next); len(orders) != 1 || orders[0].Item != "Bag" || next != "" {
+		t.Errorf("Unexpected second page %+v, next %q", orders, next)
+	}
+
+	rec := httptest.NewRecorder()
+	s.ListOrdersHandler(rec, httptest.NewRequest(http.MethodGet, "/order?order_by=item&page_token=bogus", nil))
+
+	if problem := decodeProblem(t, rec); problem.Status != http.StatusBadRequest || problem.Errors[0].Field != "pageToken" {
+		t.Errorf("Unexpected problem for invalid page token: %+v", problem)
+	}
+}
+
+func TestDBStatsHandler(t *testing.T) {
+	rec := httptest.NewRecorder()
+	newTestServer(t).DBStatsHandler(rec, httptest.NewRequest(http.MethodGet, "/admin/db/stats", nil))
+
+	if problem := decodeProblem(t, rec); problem.Status != http.StatusNotFound {
+		t.Errorf("Unexpected problem for the memory backend: %+v", problem)
+	}
+
+	repo, err := repository.OpenOrderSQLiteRepository(filepath.Join(t.TempDir(), "orders.db"), 5*time.Second, true)
+	if err != nil {
+		t.Fatalf("Error opening repository: %v", err)
+	}
+
+	defer func() { _ = repo.Close() }()
+
+	s := &OrderServer{UseCase: usecase.NewOrderUseCase(repo)}
+
+	rec = httptest.NewRecorder()
+	s.DBStatsHandler(rec, httptest.NewRequest(http.MethodGet, "/admin/db/stats", nil))
+
+	var stats dbStats
+	if err = json.Unmarshal(rec.Body.Bytes(), &stats); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("Unexpected response %d %s", rec.Code, rec.Body)
+	}
+
+	if stats.MaxOpenConnections != 1 || stats.OpenConnections != 1 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+
+	// The pool statistics are an admin route
+	s.adminToken = "secret"
+	router := s.routes()
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/db/stats", nil))
+
+	if problem := decodeProblem(t, rec); problem.Status != http.StatusUnauthorized {
+		t.Errorf("Unexpected problem without the admin token: %+v", problem)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/db/stats", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("Unexpected response with the admin token %d %s", rec.Code, rec.Body)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/db/stats", nil))
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected the old debug route to be gone, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,197 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/dyammarcano/fullcycle_clean_architecture/internal/domain"
+	"github.com/dyammarcano/fullcycle_clean_architecture/pkg/util"
+)
+
+// CreateWebhookHandler subscribes a URL to order events and answers the webhook
+// with its secret, which is not shown again
+func (s *OrderServer) CreateWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	webhook, err := decodeWebhook(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	created, err := s.Webhooks.CreateWebhook(r.Context(), webhook)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Location", "/admin/webhooks/"+strconv.Itoa(created.ID))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	util.HelperJSON(w, r, created)
+}
+
+func (s *OrderServer) ListWebhooksHandler(w http.ResponseWriter, r *http.Request) {
+	webhooks, err := s.Webhooks.ListWebhooks(r.Context())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	util.HelperJSON(w, r, webhooks)
+}
+
+func (s *OrderServer) GetWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	webhook, err := s.Webhooks.GetWebhook(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	util.HelperJSON(w, r, webhook)
+}
+
+// UpdateWebhookHandler replaces a webhook; its secret is kept when the body has none
+func (s *OrderServer) UpdateWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	webhook, err := decodeWebhook(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	updated, err := s.Webhooks.UpdateWebhook(r.Context(), id, webhook)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	util.HelperJSON(w, r, updated)
+}
+
+// DeleteWebhookHandler removes a webhook together with its deliveries
+func (s *OrderServer) DeleteWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	if err = s.Webhooks.DeleteWebhook(r.Context(), id); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListDeliveriesHandler answers the latest deliveries of a webhook, newest first.
+// They can be filtered by status (pending, succeeded or dead) and bounded by limit.
+func (s *OrderServer) ListDeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	params := r.URL.Query()
+
+	limit := 0
+	if value := params.Get("limit"); value != "" {
+		if limit, err = strconv.Atoi(value); err != nil {
+			writeError(w, r, domain.NewValidationError(domain.FieldError{Field: "limit", Rule: "integer", Message: "must be an integer"}))
+			return
+		}
+	}
+
+	deliveries, err := s.Webhooks.ListDeliveries(r.Context(), id, domain.DeliveryStatus(params.Get("status")), limit)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	util.HelperJSON(w, r, deliveries)
+}
+
+// GetDeliveryHandler answers a delivery with the history of its attempts
+func (s *OrderServer) GetDeliveryHandler(w http.ResponseWriter, r *http.Request) {
+	id, deliveryID, err := pathDeliveryID(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	delivery, err := s.Webhooks.GetDelivery(r.Context(), id, deliveryID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	util.HelperJSON(w, r, delivery)
+}
+
+// RedeliverHandler queues a delivery to be sent again right away, dead ones included,
+// and answers it
+func (s *OrderServer) RedeliverHandler(w http.ResponseWriter, r *http.Request) {
+	id, deliveryID, err := pathDeliveryID(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	delivery, err := s.Webhooks.RedeliverDelivery(r.Context(), id, deliveryID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	util.HelperJSON(w, r, delivery)
+}
+
+// pathDeliveryID parses the {id} and {deliveryID} path values of the request
+func pathDeliveryID(r *http.Request) (int, int64, error) {
+	id, err := pathID(r)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	deliveryID, err := strconv.ParseInt(r.PathValue("deliveryID"), 10, 64)
+	if err != nil || deliveryID <= 0 {
+		return 0, 0, domain.NewValidationError(domain.FieldError{
+			Field:   "deliveryId",
+			Rule:    "positive_integer",
+			Message: "must be a positive integer",
+		})
+	}
+
+	return id, deliveryID, nil
+}
+
+// decodeWebhook reads the request body into a webhook
+func decodeWebhook(r *http.Request) (*domain.Webhook, error) {
+	webhookBytes, err := util.ReadBytes(r.Body)
+	if err != nil {
+		return nil, domain.WrapError(domain.ErrValidation, "request body could not be read", err)
+	}
+
+	webhook := &domain.Webhook{}
+	if err = json.Unmarshal(webhookBytes, webhook); err != nil {
+		return nil, domain.NewValidationError(domain.FieldError{
+			Field:   "body",
+			Rule:    "json",
+			Message: "must be a valid JSON webhook",
+		})
+	}
+
+	return webhook, nil
+}
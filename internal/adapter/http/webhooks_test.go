@@ -0,0 +1,106 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dyammarcano/fullcycle_clean_architecture/internal/domain"
+)
+
+func TestWebhookHandlers(t *testing.T) {
+	s := newTestServer(t)
+
+	call := func(handler http.HandlerFunc, method, target, body string, pathValues ...string) *httptest.ResponseRecorder {
+		var reader io.Reader
+		if body != "" {
+			reader = strings.NewReader(body)
+		}
+
+		req := httptest.NewRequest(method, target, reader)
+		for i := 0; i+1 < len(pathValues); i += 2 {
+			req.SetPathValue(pathValues[i], pathValues[i+1])
+		}
+
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+
+		return rec
+	}
+
+	rec := call(s.CreateWebhookHandler, http.MethodPost, "/admin/webhooks", `{"url":"ftp://example.com","eventTypes":["order.paid"]}`)
+	if problem := decodeProblem(t, rec); problem.Status != http.StatusBadRequest || len(problem.Errors) != 2 {
+		t.Fatalf("Unexpected problem for an invalid webhook: %+v", problem)
+	}
+
+	rec = call(s.CreateWebhookHandler, http.MethodPost, "/admin/webhooks", `{"url":"https://example.com/hook","eventTypes":["order.created"]}`)
+
+	var created domain.Webhook
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil || rec.Code != http.StatusCreated || created.ID != 1 || created.Secret == "" {
+		t.Fatalf("Unexpected created webhook %d %s", rec.Code, rec.Body)
+	}
+
+	if rec.Header().Get("Location") != "/admin/webhooks/1" || rec.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("Unexpected headers %v", rec.Header())
+	}
+
+	rec = call(s.UpdateWebhookHandler, http.MethodPut, "/admin/webhooks/1", `{"url":"https://example.com/v2"}`, "id", "1")
+	if strings.Contains(rec.Body.String(), "secret") || !strings.Contains(rec.Body.String(), "/v2") {
+		t.Errorf("Unexpected updated webhook %s", rec.Body)
+	}
+
+	if secret := mustWebhook(t, s, 1).Secret; secret != created.Secret {
+		t.Errorf("Expected the update to keep the secret, got %q", secret)
+	}
+
+	if err := s.Webhooks.Publish(context.Background(), domain.Event{ID: 9, Type: domain.EventOrderCreated, OrderID: 1, Data: json.RawMessage(`{}`)}); err != nil {
+		t.Fatalf("Error queueing event: %v", err)
+	}
+
+	rec = call(s.ListDeliveriesHandler, http.MethodGet, "/admin/webhooks/1/deliveries?status=pending", "", "id", "1")
+
+	var deliveries []domain.WebhookDelivery
+	if err := json.Unmarshal(rec.Body.Bytes(), &deliveries); err != nil || len(deliveries) != 1 || deliveries[0].Event.ID != 9 {
+		t.Fatalf("Unexpected deliveries %s", rec.Body)
+	}
+
+	rec = call(s.ListDeliveriesHandler, http.MethodGet, "/admin/webhooks/1/deliveries?limit=many", "", "id", "1")
+	if problem := decodeProblem(t, rec); problem.Status != http.StatusBadRequest || problem.Errors[0].Field != "limit" {
+		t.Errorf("Unexpected problem for an invalid limit: %+v", problem)
+	}
+
+	rec = call(s.RedeliverHandler, http.MethodPost, "/admin/webhooks/1/deliveries/1/redeliver", "", "id", "1", "deliveryID", "1")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"status":"pending"`) {
+		t.Errorf("Unexpected redelivery %d %s", rec.Code, rec.Body)
+	}
+
+	rec = call(s.GetDeliveryHandler, http.MethodGet, "/admin/webhooks/1/deliveries/2", "", "id", "1", "deliveryID", "2")
+	if problem := decodeProblem(t, rec); problem.Status != http.StatusNotFound || problem.Detail != "webhook delivery not found" {
+		t.Errorf("Unexpected problem for a missing delivery: %+v", problem)
+	}
+
+	if rec = call(s.DeleteWebhookHandler, http.MethodDelete, "/admin/webhooks/1", "", "id", "1"); rec.Code != http.StatusNoContent {
+		t.Fatalf("Delete webhook = %d, want 204", rec.Code)
+	}
+
+	rec = call(s.GetWebhookHandler, http.MethodGet, "/admin/webhooks/1", "", "id", "1")
+	if problem := decodeProblem(t, rec); problem.Status != http.StatusNotFound {
+		t.Errorf("Unexpected problem for a deleted webhook: %+v", problem)
+	}
+}
+
+// mustWebhook returns a webhook from the repository, with its secret
+func mustWebhook(t *testing.T, s *OrderServer, id int) *domain.Webhook {
+	t.Helper()
+
+	webhook, err := s.Webhooks.Repo.GetWebhook(context.Background(), id)
+	if err != nil {
+		t.Fatalf("Error getting webhook: %v", err)
+	}
+
+	return webhook
+}
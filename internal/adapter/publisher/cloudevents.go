@@ -0,0 +1,41 @@
+package publisher
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/dyammarcano/fullcycle_clean_architecture/internal/domain"
+	"github.com/dyammarcano/fullcycle_clean_architecture/pkg/events"
+)
+
+// CloudEvents converts order events to CloudEvents and sends them through an
+// events.Publisher, an AMQP or NATS broker in production
+type CloudEvents struct {
+	Publisher events.Publisher
+	// Source is the source attribute of every event
+	Source string
+}
+
+func (p CloudEvents) Publish(ctx context.Context, event domain.Event) error {
+	return p.Publisher.Publish(ctx, ToCloudEvent(p.Source, event))
+}
+
+// Close closes the underlying publisher
+func (p CloudEvents) Close() error {
+	return p.Publisher.Close()
+}
+
+// ToCloudEvent returns event as a CloudEvent: the outbox ID is its id, the order ID
+// its subject and the order event data its JSON data
+func ToCloudEvent(source string, event domain.Event) events.Event {
+	return events.Event{
+		SpecVersion:     events.SpecVersion,
+		ID:              strconv.FormatInt(event.ID, 10),
+		Source:          source,
+		Type:            string(event.Type),
+		Subject:         strconv.Itoa(event.OrderID),
+		Time:            event.OccurredAt,
+		DataContentType: "application/json",
+		Data:            event.Data,
+	}
+}
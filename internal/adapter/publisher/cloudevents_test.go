@@ -0,0 +1,67 @@
+package publisher
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/dyammarcano/fullcycle_clean_architecture/internal/domain"
+	"github.com/dyammarcano/fullcycle_clean_architecture/internal/repository"
+	"github.com/dyammarcano/fullcycle_clean_architecture/internal/usecase"
+	"github.com/dyammarcano/fullcycle_clean_architecture/pkg/events"
+)
+
+func TestCloudEvents(t *testing.T) {
+	ctx := context.Background()
+
+	repo, err := repository.NewOrderMemoryRepository()
+	if err != nil {
+		t.Fatalf("Error creating repository: %v", err)
+	}
+
+	useCase := usecase.NewOrderUseCase(repo)
+
+	created, err := useCase.CreateOrder(ctx, []byte(`{"item":"Bag","lines":[{"sku":"BAG-1","quantity":1,"unitPrice":{"value":"10.00","currency":"USD"}}]}`))
+	if err != nil {
+		t.Fatalf("Error creating order: %v", err)
+	}
+
+	if _, err = useCase.PayOrder(ctx, created.ID, ""); err == nil {
+		t.Fatal("Expected paying a pending order to fail")
+	}
+
+	if _, err = useCase.ConfirmOrder(ctx, created.ID, ""); err != nil {
+		t.Fatalf("Error confirming order: %v", err)
+	}
+
+	dispatcher := events.NewDispatcher()
+
+	var received []events.Event
+	dispatcher.Subscribe("", func(_ context.Context, event events.Event) error {
+		received = append(received, event)
+		return nil
+	})
+
+	relay := usecase.NewOutboxRelay(repo, CloudEvents{Publisher: dispatcher, Source: "/orders"}, usecase.RelayOptions{})
+	if _, err = relay.RelayPending(ctx); err != nil {
+		t.Fatalf("Error relaying events: %v", err)
+	}
+
+	if len(received) != 2 {
+		t.Fatalf("Received %d events, want 2", len(received))
+	}
+
+	first, second := received[0], received[1]
+	if first.Type != string(domain.EventOrderCreated) || second.Type != string(domain.EventOrderStatusChanged) {
+		t.Errorf("Received types %s and %s", first.Type, second.Type)
+	}
+
+	if first.Source != "/orders" || first.Subject != strconv.Itoa(created.ID) || first.ID == second.ID || first.Time.IsZero() {
+		t.Errorf("Unexpected context attributes %+v", first)
+	}
+
+	data, err := domain.Event{Data: second.Data}.OrderData()
+	if err != nil || data.Order.Status != domain.StatusConfirmed || data.Transition == nil {
+		t.Errorf("Unexpected data %s (%v)", second.Data, err)
+	}
+}
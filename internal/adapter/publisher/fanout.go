@@ -0,0 +1,38 @@
+package publisher
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/dyammarcano/fullcycle_clean_architecture/internal/domain"
+)
+
+// Fanout publishes every event to all of its publishers. An event is only relayed
+// once every publisher accepted it, so each of them must tolerate duplicates.
+type Fanout []domain.EventPublisher
+
+func (f Fanout) Publish(ctx context.Context, event domain.Event) error {
+	var errs []error
+
+	for _, p := range f {
+		if err := p.Publish(ctx, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Close closes the publishers that can be closed
+func (f Fanout) Close() error {
+	var errs []error
+
+	for _, p := range f {
+		if c, ok := p.(io.Closer); ok {
+			errs = append(errs, c.Close())
+		}
+	}
+
+	return errors.Join(errs...)
+}
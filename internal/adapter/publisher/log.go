@@ -0,0 +1,24 @@
+// Package publisher holds the domain.EventPublisher implementations the outbox
+// relay delivers order events to
+package publisher
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/dyammarcano/fullcycle_clean_architecture/internal/domain"
+)
+
+// Log writes every event to the structured log. It is used when no broker is configured.
+type Log struct{}
+
+func (Log) Publish(ctx context.Context, event domain.Event) error {
+	slog.InfoContext(ctx, "Order event",
+		slog.Int64("event_id", event.ID),
+		slog.String("type", string(event.Type)),
+		slog.Int("order_id", event.OrderID),
+		slog.Int("order_version", event.OrderVersion),
+	)
+
+	return nil
+}
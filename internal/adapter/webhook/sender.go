@@ -0,0 +1,124 @@
+// Package webhook sends webhook deliveries over HTTP, signed with the secret of
+// their webhook
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dyammarcano/fullcycle_clean_architecture/internal/adapter/publisher"
+	"github.com/dyammarcano/fullcycle_clean_architecture/internal/domain"
+	"github.com/dyammarcano/fullcycle_clean_architecture/pkg/events"
+)
+
+// Headers of every delivery request
+const (
+	// HeaderID holds the delivery ID, the same for every attempt of a delivery
+	HeaderID = "X-Webhook-Id"
+	// HeaderTimestamp holds the Unix time in seconds the request was signed at
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	// HeaderSignature holds "sha256=" and the hex HMAC-SHA256 of the timestamp, a
+	// dot and the body, keyed with the webhook secret
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// DefaultTimeout bounds a delivery request when no timeout is configured
+const DefaultTimeout = 10 * time.Second
+
+// maxResponseBody bounds the response body read before the connection is reused
+const maxResponseBody = 64 << 10
+
+// Sender POSTs every delivery as a structured CloudEvent. Redirects are not
+// followed and only a 2xx status counts as delivered.
+type Sender struct {
+	Client *http.Client
+	// Source is the source attribute of every event
+	Source string
+
+	now func() time.Time
+}
+
+func (s *Sender) Send(ctx context.Context, webhook *domain.Webhook, delivery *domain.WebhookDelivery) (int, error) {
+	body, err := events.Marshal(publisher.ToCloudEvent(s.Source, delivery.Event))
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := s.now().Unix()
+
+	req.Header.Set("Content-Type", events.ContentType)
+	req.Header.Set("User-Agent", "orders-webhooks/1.0")
+	req.Header.Set(HeaderID, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(webhook.Secret, timestamp, body))
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseBody))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// Sign returns the HeaderSignature value of a body sent at timestamp
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether signature is the signature of a body sent at timestamp,
+// no further than tolerance from now; receivers use it to reject forged and
+// replayed requests
+func Verify(secret, signature string, timestamp int64, body []byte, now time.Time, tolerance time.Duration) bool {
+	if d := now.Sub(time.Unix(timestamp, 0)); d > tolerance || d < -tolerance {
+		return false
+	}
+
+	if !strings.HasPrefix(signature, "sha256=") {
+		return false
+	}
+
+	return hmac.Equal([]byte(signature), []byte(Sign(secret, timestamp, body)))
+}
+
+// NewSender returns a Sender whose requests time out after timeout, DefaultTimeout
+// when it is zero
+func NewSender(source string, timeout time.Duration) *Sender {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	client := &http.Client{
+		Timeout: timeout,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	return &Sender{Client: client, Source: source, now: time.Now}
+}
@@ -0,0 +1,91 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/dyammarcano/fullcycle_clean_architecture/internal/domain"
+	"github.com/dyammarcano/fullcycle_clean_architecture/pkg/events"
+)
+
+func TestSender(t *testing.T) {
+	const secret = "0123456789abcdef"
+
+	status := http.StatusNoContent
+
+	var (
+		header http.Header
+		body   []byte
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		body, _ = io.ReadAll(r.Body)
+
+		if status == http.StatusFound {
+			http.Redirect(w, r, "/elsewhere", status)
+			return
+		}
+
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	sender := NewSender("/orders", time.Second)
+
+	now := time.Unix(1700000000, 0)
+	sender.now = func() time.Time { return now }
+
+	webhook := &domain.Webhook{ID: 1, URL: server.URL, Secret: secret}
+	delivery := &domain.WebhookDelivery{ID: 7, WebhookID: 1, Event: domain.Event{
+		ID:         42,
+		Type:       domain.EventOrderCreated,
+		OrderID:    3,
+		OccurredAt: now,
+		Data:       json.RawMessage(`{"id":3}`),
+	}}
+
+	code, err := sender.Send(context.Background(), webhook, delivery)
+	if err != nil || code != http.StatusNoContent {
+		t.Fatalf("Send = %d, %v, want 204", code, err)
+	}
+
+	if header.Get("Content-Type") != events.ContentType || header.Get(HeaderID) != "7" {
+		t.Fatalf("Unexpected headers %v", header)
+	}
+
+	timestamp, _ := strconv.ParseInt(header.Get(HeaderTimestamp), 10, 64)
+	if timestamp != now.Unix() {
+		t.Fatalf("Timestamp = %d, want %d", timestamp, now.Unix())
+	}
+
+	signature := header.Get(HeaderSignature)
+	if !Verify(secret, signature, timestamp, body, now.Add(time.Minute), 5*time.Minute) {
+		t.Fatalf("Signature %q does not verify", signature)
+	}
+
+	if Verify(secret, signature, timestamp, body, now.Add(10*time.Minute), 5*time.Minute) {
+		t.Fatal("Expected a stale timestamp to be rejected")
+	}
+
+	if Verify("another secret!!", signature, timestamp, body, now, 5*time.Minute) {
+		t.Fatal("Expected a signature with another secret to be rejected")
+	}
+
+	event, err := events.Unmarshal(body)
+	if err != nil || event.ID != "42" || event.Type != "order.created" || event.Subject != "3" || event.Source != "/orders" {
+		t.Fatalf("Body = %s, %v, want the order event as a CloudEvent", body, err)
+	}
+
+	for _, status = range []int{http.StatusInternalServerError, http.StatusFound} {
+		if code, err = sender.Send(context.Background(), webhook, delivery); err == nil || code != status {
+			t.Errorf("Send answered %d = %d, %v, want a failure", status, code, err)
+		}
+	}
+}
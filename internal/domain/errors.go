@@ -0,0 +1,110 @@
+package domain
+
+import (
+	"errors"
+	"strings"
+)
+
+// Error kinds shared by every layer. Repositories and use cases return errors
+// that match one of them through errors.Is, so adapters can translate failures
+// without looking at driver specific details.
+var (
+	// ErrNotFound is the kind of errors raised when a resource does not exist
+	ErrNotFound = errors.New("not found")
+
+	// ErrConflict is the kind of errors raised when a write collides with stored state
+	ErrConflict = errors.New("conflict")
+
+	// ErrFailedPrecondition is the kind of errors raised when the resource is not in a state that allows the operation
+	ErrFailedPrecondition = errors.New("failed precondition")
+
+	// ErrValidation is the kind of errors raised when the input is malformed or breaks a rule
+	ErrValidation = errors.New("validation failed")
+
+	// ErrUnavailable is the kind of errors raised when a backing service cannot be reached
+	ErrUnavailable = errors.New("service unavailable")
+)
+
+var (
+	// ErrOrderNotFound is returned when the requested order does not exist
+	ErrOrderNotFound = newKindError(ErrNotFound, "order not found")
+
+	// ErrOrderAlreadyExists is returned when an order with the same ID is already stored
+	ErrOrderAlreadyExists = newKindError(ErrConflict, "order already exists")
+
+	// ErrOrderVersionMismatch is returned when a write expects a version the order no longer has
+	ErrOrderVersionMismatch = newKindError(ErrConflict, "order was modified by another request")
+
+	// ErrInvalidOrder is returned when an order is missing or malformed
+	ErrInvalidOrder = newKindError(ErrValidation, "invalid entity")
+
+	// ErrWebhookNotFound is returned when the requested webhook does not exist
+	ErrWebhookNotFound = newKindError(ErrNotFound, "webhook not found")
+
+	// ErrDeliveryNotFound is returned when the requested webhook delivery does not exist
+	ErrDeliveryNotFound = newKindError(ErrNotFound, "webhook delivery not found")
+
+	// ErrChangesExpired is returned when a change stream is resumed, or read, from a
+	// point the change buffer no longer holds
+	ErrChangesExpired = newKindError(ErrFailedPrecondition, "changes since the resume point are no longer available")
+)
+
+// kindError is an error with its own client safe message that belongs to an error kind
+type kindError struct {
+	kind  error
+	msg   string
+	cause error
+}
+
+func newKindError(kind error, msg string) error {
+	return &kindError{kind: kind, msg: msg}
+}
+
+// WrapError returns an error of the given kind with a client safe message. The cause
+// stays reachable through errors.Is and errors.As but is not part of the message.
+func WrapError(kind error, msg string, cause error) error {
+	return &kindError{kind: kind, msg: msg, cause: cause}
+}
+
+func (e *kindError) Error() string {
+	return e.msg
+}
+
+func (e *kindError) Unwrap() []error {
+	if e.cause == nil {
+		return []error{e.kind}
+	}
+
+	return []error{e.kind, e.cause}
+}
+
+// FieldError describes a rule broken by a single input field
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// ValidationError lists every field level violation found in an input.
+// It belongs to the ErrValidation kind.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+// NewValidationError returns a ValidationError holding the given violations
+func NewValidationError(fields ...FieldError) error {
+	return &ValidationError{Fields: fields}
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		msgs = append(msgs, f.Field+": "+f.Message)
+	}
+
+	return ErrValidation.Error() + ": " + strings.Join(msgs, "; ")
+}
+
+func (e *ValidationError) Unwrap() error {
+	return ErrValidation
+}
@@ -0,0 +1,91 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// EventType names what happened to an order
+type EventType string
+
+const (
+	EventOrderCreated       EventType = "order.created"
+	EventOrderUpdated       EventType = "order.updated"
+	EventOrderDeleted       EventType = "order.deleted"
+	EventOrderRestored      EventType = "order.restored"
+	EventOrderStatusChanged EventType = "order.status_changed"
+)
+
+// EventTypes lists every order event type
+var EventTypes = []EventType{
+	EventOrderCreated, EventOrderUpdated, EventOrderDeleted, EventOrderRestored, EventOrderStatusChanged,
+}
+
+// Event is a change of one order. ID is assigned by the outbox when the event is
+// stored and grows with every event, so consumers can use it to drop duplicates.
+type Event struct {
+	ID           int64           `json:"id"`
+	Type         EventType       `json:"type"`
+	OrderID      int             `json:"orderId"`
+	OrderVersion int             `json:"orderVersion"`
+	OccurredAt   time.Time       `json:"occurredAt"`
+	Data         json.RawMessage `json:"data"`
+}
+
+// OrderEventData is the JSON payload of order events: the order after the change
+// and, for status changes, the transition that was applied
+type OrderEventData struct {
+	Order      *Order            `json:"order"`
+	Transition *StatusTransition `json:"transition,omitempty"`
+}
+
+// NewOrderEvent returns an event of the given type carrying order and transition
+func NewOrderEvent(eventType EventType, order *Order, transition *StatusTransition) (Event, error) {
+	data, err := json.Marshal(OrderEventData{Order: order, Transition: transition})
+	if err != nil {
+		return Event{}, err
+	}
+
+	return Event{
+		Type:         eventType,
+		OrderID:      order.ID,
+		OrderVersion: order.Version,
+		OccurredAt:   time.Now().UTC().Truncate(time.Microsecond),
+		Data:         data,
+	}, nil
+}
+
+// OrderData decodes the payload of an order event
+func (e Event) OrderData() (OrderEventData, error) {
+	var data OrderEventData
+	err := json.Unmarshal(e.Data, &data)
+
+	return data, err
+}
+
+// PendingEvent is an outbox event that has not been delivered yet
+type PendingEvent struct {
+	Event
+	// Attempts counts the failed deliveries so far
+	Attempts int
+}
+
+// EventOutbox stores the events written together with order changes until a relay
+// delivers them. Every method honours the cancellation and deadline of ctx.
+type EventOutbox interface {
+	// AppendEvents stores events, assigning their IDs
+	AppendEvents(ctx context.Context, events ...Event) error
+	// ClaimEvents returns up to limit events due at now, oldest first, and hides them
+	// from other claims until leaseUntil, when they are due again unless delivered
+	ClaimEvents(ctx context.Context, now, leaseUntil time.Time, limit int) ([]PendingEvent, error)
+	// MarkEventDelivered removes a delivered event from the outbox
+	MarkEventDelivered(ctx context.Context, id int64) error
+	// MarkEventFailed counts a failed delivery and makes the event due again at retryAt
+	MarkEventFailed(ctx context.Context, id int64, retryAt time.Time, lastError string) error
+}
+
+// EventPublisher delivers events to consumers outside the order service
+type EventPublisher interface {
+	Publish(ctx context.Context, event Event) error
+}
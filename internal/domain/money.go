@@ -0,0 +1,211 @@
+package domain
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// DefaultCurrency is assumed when an amount is given without a currency
+const DefaultCurrency = "USD"
+
+// currencyExponents holds the number of minor unit digits of the supported ISO 4217 currencies
+var currencyExponents = map[string]int{
+	"ARS": 2, "AUD": 2, "BHD": 3, "BRL": 2, "CAD": 2, "CHF": 2, "CLP": 0, "CNY": 2,
+	"COP": 2, "CZK": 2, "DKK": 2, "EUR": 2, "GBP": 2, "HKD": 2, "INR": 2, "JOD": 3,
+	"JPY": 0, "KRW": 0, "KWD": 3, "MXN": 2, "NOK": 2, "NZD": 2, "OMR": 3, "PEN": 2,
+	"PLN": 2, "PYG": 0, "SEK": 2, "SGD": 2, "TND": 3, "USD": 2, "UYU": 2, "ZAR": 2,
+}
+
+// CurrencyExponent returns the number of minor unit digits of an ISO 4217 currency
+func CurrencyExponent(currency string) (int, bool) {
+	exp, ok := currencyExponents[currency]
+	return exp, ok
+}
+
+// Money is an exact amount of an ISO 4217 currency counted in minor units (e.g. cents)
+type Money struct {
+	Units    int64
+	Currency string
+}
+
+// NewMoney returns an amount of units minor units of currency
+func NewMoney(units int64, currency string) (Money, error) {
+	if _, ok := CurrencyExponent(currency); !ok {
+		return Money{}, fmt.Errorf("unsupported currency %q", currency)
+	}
+
+	return Money{Units: units, Currency: currency}, nil
+}
+
+// ParseMoney parses a decimal amount such as "100.50" in currency. Amounts with more
+// fraction digits than the currency allows are rejected instead of being rounded.
+func ParseMoney(amount, currency string) (Money, error) {
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+
+	exp, ok := CurrencyExponent(currency)
+	if !ok {
+		return Money{}, fmt.Errorf("unsupported currency %q", currency)
+	}
+
+	s := strings.TrimSpace(amount)
+
+	negative := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "-"), "+")
+
+	whole, frac, _ := strings.Cut(s, ".")
+	if whole == "" && frac == "" || !isDigits(whole) || !isDigits(frac) {
+		return Money{}, fmt.Errorf("invalid decimal amount %q", amount)
+	}
+
+	if len(frac) > exp {
+		return Money{}, fmt.Errorf("amount %q has more than %d decimal places for %s", amount, exp, currency)
+	}
+
+	digits := strings.TrimLeft(whole+frac+strings.Repeat("0", exp-len(frac)), "0")
+	if digits == "" {
+		digits = "0"
+	}
+
+	units, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("amount %q is out of range", amount)
+	}
+
+	if negative {
+		units = -units
+	}
+
+	return Money{Units: units, Currency: currency}, nil
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// IsZero reports whether m is the zero value
+func (m Money) IsZero() bool {
+	return m == Money{}
+}
+
+// ErrCurrencyMismatch is returned when amounts of different currencies are combined
+var ErrCurrencyMismatch = newKindError(ErrValidation, "currency mismatch")
+
+// ErrAmountOverflow is returned when an arithmetic result does not fit in minor units
+var ErrAmountOverflow = newKindError(ErrValidation, "amount out of range")
+
+// Add returns m + other; both amounts must share the same currency
+func (m Money) Add(other Money) (Money, error) {
+	if m.Currency != other.Currency {
+		return Money{}, ErrCurrencyMismatch
+	}
+
+	sum := m.Units + other.Units
+	if (other.Units > 0 && sum < m.Units) || (other.Units < 0 && sum > m.Units) {
+		return Money{}, ErrAmountOverflow
+	}
+
+	return Money{Units: sum, Currency: m.Currency}, nil
+}
+
+// Mul returns m multiplied by factor
+func (m Money) Mul(factor int64) (Money, error) {
+	if factor == 0 || m.Units == 0 {
+		return Money{Currency: m.Currency}, nil
+	}
+
+	product := m.Units * factor
+	if product/factor != m.Units || (factor == -1 && m.Units == math.MinInt64) {
+		return Money{}, ErrAmountOverflow
+	}
+
+	return Money{Units: product, Currency: m.Currency}, nil
+}
+
+// Decimal returns the amount as a decimal string such as "100.50"
+func (m Money) Decimal() string {
+	exp, ok := CurrencyExponent(m.Currency)
+	if !ok {
+		exp = 2
+	}
+
+	sign := ""
+	units := m.Units
+
+	if units < 0 {
+		sign = "-"
+		units = -units
+	}
+
+	if exp == 0 {
+		return sign + strconv.FormatUint(uint64(units), 10)
+	}
+
+	pow := uint64(math.Pow10(exp))
+
+	return fmt.Sprintf("%s%d.%0*d", sign, uint64(units)/pow, exp, uint64(units)%pow)
+}
+
+func (m Money) String() string {
+	return m.Decimal() + " " + m.Currency
+}
+
+type moneyJSON struct {
+	Value    string `json:"value"`
+	Currency string `json:"currency"`
+}
+
+// MarshalJSON encodes money as {"value": "100.50", "currency": "USD"}
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(moneyJSON{Value: m.Decimal(), Currency: m.Currency})
+}
+
+// UnmarshalJSON accepts {"value": "100.50", "currency": "USD"} as well as a bare
+// number or string, which is read as an amount of DefaultCurrency
+func (m *Money) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+
+	var value, currency string
+
+	switch data[0] {
+	case '{':
+		var raw struct {
+			Value    json.RawMessage `json:"value"`
+			Currency string          `json:"currency"`
+		}
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return err
+		}
+
+		value, currency = strings.Trim(string(raw.Value), `"`), raw.Currency
+	case '"':
+		if err := json.Unmarshal(data, &value); err != nil {
+			return err
+		}
+	default:
+		value = string(data)
+	}
+
+	parsed, err := ParseMoney(value, currency)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrValidation, err)
+	}
+
+	*m = parsed
+
+	return nil
+}
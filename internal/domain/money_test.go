@@ -0,0 +1,74 @@
+package domain
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		amount   string
+		currency string
+		units    int64
+		decimal  string
+		wantErr  bool
+	}{
+		{"100.50", "USD", 10050, "100.50", false},
+		{"100.5", "", 10050, "100.50", false},
+		{"0.01", "EUR", 1, "0.01", false},
+		{"-3", "BRL", -300, "-3.00", false},
+		{"1500", "JPY", 1500, "1500", false},
+		{"1.234", "KWD", 1234, "1.234", false},
+		{".5", "USD", 50, "0.50", false},
+		{"1.005", "USD", 0, "", true},
+		{"1.5", "JPY", 0, "", true},
+		{"1e2", "USD", 0, "", true},
+		{"", "USD", 0, "", true},
+		{"10", "XXX", 0, "", true},
+		{"99999999999999999999", "USD", 0, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.amount+tt.currency, func(t *testing.T) {
+			m, err := ParseMoney(tt.amount, tt.currency)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error, got %v", m)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if m.Units != tt.units || m.Decimal() != tt.decimal {
+				t.Errorf("Expected %d (%s), got %d (%s)", tt.units, tt.decimal, m.Units, m.Decimal())
+			}
+		})
+	}
+}
+
+func TestMoneyJSON(t *testing.T) {
+	var order Order
+	if err := json.Unmarshal([]byte(`{"item":"Bag","amount":100.50}`), &order); err != nil {
+		t.Fatalf("Error decoding legacy amount: %v", err)
+	}
+
+	if order.Amount != (Money{Units: 10050, Currency: DefaultCurrency}) {
+		t.Errorf("Unexpected legacy amount %v", order.Amount)
+	}
+
+	if data, _ := json.Marshal(order.Amount); string(data) != `{"value":"100.50","currency":"USD"}` {
+		t.Errorf("Unexpected encoding %s", data)
+	}
+
+	if err := json.Unmarshal([]byte(`{"amount":{"value":"7","currency":"EUR"}}`), &order); err != nil {
+		t.Fatalf("Error decoding amount: %v", err)
+	}
+
+	if order.Amount != (Money{Units: 700, Currency: "EUR"}) {
+		t.Errorf("Unexpected amount %v", order.Amount)
+	}
+}
@@ -1,21 +1,102 @@
 package domain
 
 import (
+	"context"
 	"encoding/json"
+	"time"
 )
 
+// OrderRepository is the persistence port for orders. Every method honours the
+// cancellation and deadline of ctx. Repositories maintain the CreatedAt, UpdatedAt
+// and DeletedAt timestamps of the orders they store, and their Version, which
+// starts at 1 and grows by one on every write.
 type OrderRepository interface {
-	ListOrders() ([]*Order, error)
-	CreateOrder(order *Order) (*Order, error)
-	GetOrderByID(id int) (*Order, error)
-	UpdateOrder(id int, order *Order) error
-	DeleteOrder(id int) error
+	// ListOrders returns at most query.PageSize orders matching query.Filter, in
+	// query order, that sort after query.After. HasMore is set when more follow.
+	ListOrders(ctx context.Context, query ListOrdersQuery) (*OrderPage, error)
+	CreateOrder(ctx context.Context, order *Order) (*Order, error)
+	GetOrderByID(ctx context.Context, id int) (*Order, error)
+	// UpdateOrder replaces the content of an order; its status is left untouched.
+	// When order.Version is not zero it must be the stored version, otherwise the
+	// update fails with ErrOrderVersionMismatch.
+	UpdateOrder(ctx context.Context, id int, order *Order) error
+	// DeleteOrder soft-deletes an order and returns it as stored once deleted: every
+	// other method except ListOrders with Filter.Deleted, RestoreOrder and
+	// PurgeDeletedOrders treats it as missing. A non zero version is checked like
+	// the one of UpdateOrder.
+	DeleteOrder(ctx context.Context, id int, version int) (*Order, error)
+	// ChangeOrderStatus stores the new status and appends the transition to the history.
+	// It fails with ErrOrderStatusChanged when the stored status is no longer transition.From.
+	ChangeOrderStatus(ctx context.Context, id int, transition StatusTransition) error
+	// ListStatusHistory returns the transitions of an order, oldest first
+	ListStatusHistory(ctx context.Context, id int) ([]StatusTransition, error)
+	// RestoreOrder brings back a soft-deleted order. It fails with ErrOrderNotFound
+	// when no deleted order has the ID.
+	RestoreOrder(ctx context.Context, id int) error
+	// PurgeDeletedOrders permanently removes the orders soft-deleted before
+	// deletedBefore, with their lines and history, and returns how many were removed
+	PurgeDeletedOrders(ctx context.Context, deletedBefore time.Time) (int, error)
+	// Transact runs fn with a repository and an outbox whose writes are stored
+	// together when fn returns nil and discarded when it returns an error
+	Transact(ctx context.Context, fn func(repo OrderRepository, outbox EventOutbox) error) error
+	// Close releases the connections and resources held by the repository
+	Close() error
 }
 
+// Order is the order aggregate. Amount is always derived from Lines by Recalculate
+// and is never taken from client input.
 type Order struct {
-	ID     int     `json:"id"`
-	Item   string  `json:"item"`
-	Amount float32 `json:"amount"`
+	ID     int         `json:"id"`
+	Item   string      `json:"item"`
+	Status OrderStatus `json:"status"`
+	Lines  []OrderLine `json:"lines"`
+	Amount Money       `json:"amount"`
+	// Version identifies the stored state of the order for optimistic concurrency
+	Version int `json:"version"`
+
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	// DeletedAt is set while the order is soft-deleted
+	DeletedAt *time.Time `json:"deletedAt,omitempty"`
+}
+
+// OrderLine is a product ordered in a given quantity at a unit price
+type OrderLine struct {
+	SKU         string `json:"sku"`
+	Description string `json:"description"`
+	Quantity    int    `json:"quantity"`
+	UnitPrice   Money  `json:"unitPrice"`
+}
+
+// Total returns the unit price multiplied by the quantity
+func (l OrderLine) Total() (Money, error) {
+	return l.UnitPrice.Mul(int64(l.Quantity))
+}
+
+// Recalculate sets Amount to the sum of the line totals. All lines must share the
+// currency of the first line.
+func (o *Order) Recalculate() error {
+	if len(o.Lines) == 0 {
+		o.Amount = Money{}
+		return nil
+	}
+
+	total := Money{Currency: o.Lines[0].UnitPrice.Currency}
+
+	for _, line := range o.Lines {
+		lineTotal, err := line.Total()
+		if err != nil {
+			return err
+		}
+
+		if total, err = total.Add(lineTotal); err != nil {
+			return err
+		}
+	}
+
+	o.Amount = total
+
+	return nil
 }
 
 func (o *Order) Bytes() []byte {
@@ -0,0 +1,225 @@
+package domain
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// OrderSortField is an order attribute listings can be sorted by. Ties are always
+// broken by ID so every sort gives a stable, total order.
+type OrderSortField string
+
+const (
+	SortByID     OrderSortField = "id"
+	SortByItem   OrderSortField = "item"
+	SortByAmount OrderSortField = "amount"
+)
+
+// OrderSortFields lists every supported sort field
+var OrderSortFields = []OrderSortField{SortByID, SortByItem, SortByAmount}
+
+// IsValid reports whether f is a supported sort field
+func (f OrderSortField) IsValid() bool {
+	switch f {
+	case SortByID, SortByItem, SortByAmount:
+		return true
+	}
+
+	return false
+}
+
+// OrderFilter narrows an order listing. Empty fields do not filter.
+type OrderFilter struct {
+	// ItemContains keeps orders whose item contains the text, ignoring the case of
+	// ASCII letters only so every backend matches the same orders
+	ItemContains string
+	// MinAmount and MaxAmount keep orders whose amount is within the inclusive
+	// range; when set, only orders in their currency match
+	MinAmount *Money
+	MaxAmount *Money
+	// Deleted lists the soft-deleted orders instead of the live ones
+	Deleted bool
+}
+
+// Matches reports whether order passes the filter
+func (f OrderFilter) Matches(order *Order) bool {
+	if f.Deleted != (order.DeletedAt != nil) {
+		return false
+	}
+
+	if f.ItemContains != "" && !strings.Contains(asciiLower(order.Item), asciiLower(f.ItemContains)) {
+		return false
+	}
+
+	if f.MinAmount != nil && (order.Amount.Currency != f.MinAmount.Currency || order.Amount.Units < f.MinAmount.Units) {
+		return false
+	}
+
+	if f.MaxAmount != nil && (order.Amount.Currency != f.MaxAmount.Currency || order.Amount.Units > f.MaxAmount.Units) {
+		return false
+	}
+
+	return true
+}
+
+// ListOrdersQuery describes one page of an order listing
+type ListOrdersQuery struct {
+	Filter     OrderFilter
+	SortBy     OrderSortField
+	Descending bool
+	PageSize   int
+	// After is the position of the last order of the previous page, nil for the first page
+	After *OrderCursor
+}
+
+// OrderCursor is the position of an order in a sorted listing
+type OrderCursor struct {
+	ID    int    `json:"id"`
+	Item  string `json:"item,omitempty"`
+	Units int64  `json:"units,omitempty"`
+}
+
+// CursorFor returns the position of order in a listing sorted by field
+func CursorFor(order *Order, field OrderSortField) OrderCursor {
+	cursor := OrderCursor{ID: order.ID}
+
+	switch field {
+	case SortByItem:
+		cursor.Item = order.Item
+	case SortByAmount:
+		cursor.Units = order.Amount.Units
+	}
+
+	return cursor
+}
+
+// Compare orders c and other by field, then by ID. It returns a negative number
+// when c sorts first, zero when both are equal and a positive number otherwise.
+func (c OrderCursor) Compare(other OrderCursor, field OrderSortField) int {
+	switch field {
+	case SortByItem:
+		if n := strings.Compare(c.Item, other.Item); n != 0 {
+			return n
+		}
+	case SortByAmount:
+		if n := cmp.Compare(c.Units, other.Units); n != 0 {
+			return n
+		}
+	}
+
+	return cmp.Compare(c.ID, other.ID)
+}
+
+// OrderPage is one page of an order listing
+type OrderPage struct {
+	Orders []*Order
+	// HasMore reports whether more orders follow this page
+	HasMore bool
+	// NextPageToken resumes the listing after this page, empty on the last page
+	NextPageToken string
+}
+
+// asciiLower lower-cases the ASCII letters of s, leaving the others as they are
+func asciiLower(s string) string {
+	return strings.Map(func(r rune) rune {
+		if 'A' <= r && r <= 'Z' {
+			return r + 'a' - 'A'
+		}
+
+		return r
+	}, s)
+}
+
+// ParseOrderSort parses a sort expression such as "amount" or "amount desc".
+// An empty expression sorts by ID ascending.
+func ParseOrderSort(s string) (OrderSortField, bool, error) {
+	parts := strings.Fields(strings.ToLower(s))
+
+	switch len(parts) {
+	case 0:
+		return SortByID, false, nil
+	case 1, 2:
+	default:
+		return "", false, fmt.Errorf("invalid sort %q", s)
+	}
+
+	field := OrderSortField(parts[0])
+	if !field.IsValid() {
+		return "", false, fmt.Errorf("unknown sort field %q", parts[0])
+	}
+
+	if len(parts) == 1 {
+		return field, false, nil
+	}
+
+	switch parts[1] {
+	case "asc":
+		return field, false, nil
+	case "desc":
+		return field, true, nil
+	}
+
+	return "", false, fmt.Errorf("unknown sort direction %q", parts[1])
+}
+
+// ErrInvalidPageToken is returned when a page token is malformed or was issued
+// for a listing with another filter or sort
+var ErrInvalidPageToken = NewValidationError(FieldError{
+	Field:   "pageToken",
+	Rule:    "page_token",
+	Message: "must be a token returned by the previous page of the same listing",
+})
+
+type pageToken struct {
+	Query  string      `json:"q"`
+	Cursor OrderCursor `json:"c"`
+}
+
+// EncodePageToken returns an opaque token resuming query after cursor
+func EncodePageToken(query ListOrdersQuery, cursor OrderCursor) string {
+	data, err := json.Marshal(pageToken{Query: query.fingerprint(), Cursor: cursor})
+	if err != nil {
+		return ""
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodePageToken returns the cursor held by token. The token must have been
+// issued for a query with the same filter and sort.
+func DecodePageToken(query ListOrdersQuery, token string) (*OrderCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidPageToken
+	}
+
+	var decoded pageToken
+	if err = json.Unmarshal(data, &decoded); err != nil || decoded.Query != query.fingerprint() {
+		return nil, ErrInvalidPageToken
+	}
+
+	return &decoded.Cursor, nil
+}
+
+// fingerprint identifies the filter and sort of the query, so a token cannot be
+// replayed against a different listing
+func (q ListOrdersQuery) fingerprint() string {
+	if q.SortBy == "" {
+		q.SortBy = SortByID
+	}
+
+	key, _ := json.Marshal(struct {
+		Filter     OrderFilter
+		SortBy     OrderSortField
+		Descending bool
+	}{q.Filter, q.SortBy, q.Descending})
+
+	sum := sha256.Sum256(key)
+
+	return hex.EncodeToString(sum[:8])
+}
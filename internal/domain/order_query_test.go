@@ -0,0 +1,62 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseOrderSort(t *testing.T) {
+	tests := []struct {
+		in         string
+		field      OrderSortField
+		descending bool
+		wantErr    bool
+	}{
+		{"", SortByID, false, false},
+		{"item", SortByItem, false, false},
+		{"Amount DESC", SortByAmount, true, false},
+		{"id asc", SortByID, false, false},
+		{"status", "", false, true},
+		{"item sideways", "", false, true},
+		{"item desc id", "", false, true},
+	}
+
+	for _, tt := range tests {
+		field, descending, err := ParseOrderSort(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseOrderSort(%q): unexpected error %v", tt.in, err)
+			continue
+		}
+
+		if field != tt.field || descending != tt.descending {
+			t.Errorf("ParseOrderSort(%q) = %s %v, expected %s %v", tt.in, field, descending, tt.field, tt.descending)
+		}
+	}
+}
+
+func TestPageToken(t *testing.T) {
+	query := ListOrdersQuery{SortBy: SortByItem, Filter: OrderFilter{ItemContains: "bag"}}
+	cursor := OrderCursor{ID: 3, Item: "Bag"}
+
+	token := EncodePageToken(query, cursor)
+
+	got, err := DecodePageToken(query, token)
+	if err != nil || *got != cursor {
+		t.Fatalf("Expected %+v, got %+v (%v)", cursor, got, err)
+	}
+
+	query.PageSize = 20
+	if _, err = DecodePageToken(query, token); err != nil {
+		t.Errorf("Page size must not invalidate the token: %v", err)
+	}
+
+	other := query
+	other.Descending = true
+	if _, err = DecodePageToken(other, token); !errors.Is(err, ErrValidation) {
+		t.Errorf("Expected validation error for another sort, got %v", err)
+	}
+
+	if _, err = DecodePageToken(query, "not a token"); !errors.Is(err, ErrValidation) {
+		t.Errorf("Expected validation error for a malformed token, got %v", err)
+	}
+}
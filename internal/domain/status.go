@@ -0,0 +1,100 @@
+package domain
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// OrderStatus is a step of the order lifecycle
+type OrderStatus string
+
+const (
+	StatusPending   OrderStatus = "pending"
+	StatusConfirmed OrderStatus = "confirmed"
+	StatusPaid      OrderStatus = "paid"
+	StatusShipped   OrderStatus = "shipped"
+	StatusDelivered OrderStatus = "delivered"
+	StatusCancelled OrderStatus = "cancelled"
+	StatusRefunded  OrderStatus = "refunded"
+)
+
+// OrderStatuses lists every status in lifecycle order
+var OrderStatuses = []OrderStatus{
+	StatusPending, StatusConfirmed, StatusPaid, StatusShipped, StatusDelivered, StatusCancelled, StatusRefunded,
+}
+
+// statusTransitions holds the statuses each status may move to. Cancelled and
+// refunded are final; a paid order is refunded instead of cancelled.
+var statusTransitions = map[OrderStatus][]OrderStatus{
+	StatusPending:   {StatusConfirmed, StatusCancelled},
+	StatusConfirmed: {StatusPaid, StatusCancelled},
+	StatusPaid:      {StatusShipped, StatusRefunded},
+	StatusShipped:   {StatusDelivered},
+	StatusDelivered: {StatusRefunded},
+	StatusCancelled: {},
+	StatusRefunded:  {},
+}
+
+// statusActions names the operation that moves an order into each status
+var statusActions = map[string]OrderStatus{
+	"confirm": StatusConfirmed,
+	"pay":     StatusPaid,
+	"ship":    StatusShipped,
+	"deliver": StatusDelivered,
+	"cancel":  StatusCancelled,
+	"refund":  StatusRefunded,
+}
+
+var (
+	// ErrInvalidStatusTransition is returned when the lifecycle does not allow a status change
+	ErrInvalidStatusTransition = newKindError(ErrFailedPrecondition, "invalid status transition")
+
+	// ErrOrderStatusChanged is returned when the stored status changed while a transition was applied
+	ErrOrderStatusChanged = newKindError(ErrConflict, "order status changed concurrently")
+)
+
+// ParseOrderStatus returns the status named s
+func ParseOrderStatus(s string) (OrderStatus, error) {
+	status := OrderStatus(s)
+	if _, ok := statusTransitions[status]; !ok {
+		return "", NewValidationError(FieldError{Field: "status", Rule: "enum", Message: fmt.Sprintf("unknown status %q", s)})
+	}
+
+	return status, nil
+}
+
+// StatusForAction returns the status reached by a lifecycle action such as "cancel"
+func StatusForAction(action string) (OrderStatus, bool) {
+	status, ok := statusActions[action]
+	return status, ok
+}
+
+// CanTransitionTo reports whether the lifecycle allows moving from s to next
+func (s OrderStatus) CanTransitionTo(next OrderStatus) bool {
+	return slices.Contains(statusTransitions[s], next)
+}
+
+// IsFinal reports whether no transition leaves s
+func (s OrderStatus) IsFinal() bool {
+	return len(statusTransitions[s]) == 0
+}
+
+// StatusTransition records a status change of an order
+type StatusTransition struct {
+	From   OrderStatus `json:"from"`
+	To     OrderStatus `json:"to"`
+	Reason string      `json:"reason,omitempty"`
+	At     time.Time   `json:"at"`
+}
+
+// NewStatusTransition checks the lifecycle rules and returns the transition from
+// the current status to next
+func NewStatusTransition(current, next OrderStatus, reason string, at time.Time) (StatusTransition, error) {
+	if !current.CanTransitionTo(next) {
+		return StatusTransition{}, WrapError(ErrFailedPrecondition,
+			fmt.Sprintf("cannot move order from %s to %s", current, next), ErrInvalidStatusTransition)
+	}
+
+	return StatusTransition{From: current, To: next, Reason: reason, At: at}, nil
+}
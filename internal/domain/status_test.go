@@ -0,0 +1,43 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestStatusTransitions(t *testing.T) {
+	allowed := map[[2]OrderStatus]bool{
+		{StatusPending, StatusConfirmed}:   true,
+		{StatusPending, StatusCancelled}:   true,
+		{StatusConfirmed, StatusPaid}:      true,
+		{StatusConfirmed, StatusCancelled}: true,
+		{StatusPaid, StatusShipped}:        true,
+		{StatusPaid, StatusRefunded}:       true,
+		{StatusShipped, StatusDelivered}:   true,
+		{StatusDelivered, StatusRefunded}:  true,
+	}
+
+	for _, from := range OrderStatuses {
+		for _, to := range OrderStatuses {
+			want := allowed[[2]OrderStatus{from, to}]
+
+			if got := from.CanTransitionTo(to); got != want {
+				t.Errorf("%s -> %s: expected %v, got %v", from, to, want, got)
+			}
+
+			_, err := NewStatusTransition(from, to, "", time.Now())
+			if want && err != nil {
+				t.Errorf("%s -> %s: unexpected error %v", from, to, err)
+			}
+
+			if !want && (!errors.Is(err, ErrInvalidStatusTransition) || !errors.Is(err, ErrFailedPrecondition)) {
+				t.Errorf("%s -> %s: expected invalid transition, got %v", from, to, err)
+			}
+		}
+	}
+
+	if !StatusCancelled.IsFinal() || !StatusRefunded.IsFinal() || StatusPaid.IsFinal() {
+		t.Errorf("Unexpected final statuses")
+	}
+}
@@ -0,0 +1,104 @@
+package domain
+
+import (
+	"context"
+	"slices"
+	"time"
+)
+
+// Webhook is a subscription of an HTTP endpoint to order events
+type Webhook struct {
+	ID  int    `json:"id"`
+	URL string `json:"url"`
+	// EventTypes lists the delivered event types; empty subscribes to all of them
+	EventTypes []EventType `json:"eventTypes"`
+	// Secret keys the HMAC signature of every delivery. It is only shown when the
+	// webhook is created.
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Subscribes reports whether the events of eventType are delivered to the webhook
+func (w Webhook) Subscribes(eventType EventType) bool {
+	return len(w.EventTypes) == 0 || slices.Contains(w.EventTypes, eventType)
+}
+
+// DeliveryStatus is the state of a webhook delivery
+type DeliveryStatus string
+
+const (
+	// DeliveryPending deliveries are sent when due, again after every failure
+	DeliveryPending DeliveryStatus = "pending"
+	// DeliverySucceeded deliveries were answered with a 2xx status
+	DeliverySucceeded DeliveryStatus = "succeeded"
+	// DeliveryDead deliveries failed too many times and are only sent again on request
+	DeliveryDead DeliveryStatus = "dead"
+)
+
+// DeliveryStatuses lists every delivery status
+var DeliveryStatuses = []DeliveryStatus{DeliveryPending, DeliverySucceeded, DeliveryDead}
+
+// WebhookDelivery is one event to be sent to one webhook
+type WebhookDelivery struct {
+	ID        int64          `json:"id"`
+	WebhookID int            `json:"webhookId"`
+	Event     Event          `json:"event"`
+	Status    DeliveryStatus `json:"status"`
+	// Attempts counts the requests sent since the delivery was queued or redelivered
+	Attempts      int       `json:"attempts"`
+	NextAttemptAt time.Time `json:"nextAttemptAt"`
+	LastError     string    `json:"lastError,omitempty"`
+	CreatedAt     time.Time `json:"createdAt"`
+	UpdatedAt     time.Time `json:"updatedAt"`
+	// History lists the attempts, oldest first; it is only loaded for a single delivery
+	History []WebhookAttempt `json:"history,omitempty"`
+}
+
+// WebhookAttempt is one request sent for a delivery
+type WebhookAttempt struct {
+	DeliveryID int64     `json:"-"`
+	At         time.Time `json:"at"`
+	// StatusCode is the HTTP status answered, zero when no response was received
+	StatusCode int    `json:"statusCode,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// WebhookRepository stores webhooks and the queue of their deliveries. Every
+// method honours the cancellation and deadline of ctx.
+type WebhookRepository interface {
+	// CreateWebhook stores webhook, setting its ID and timestamps
+	CreateWebhook(ctx context.Context, webhook *Webhook) error
+	// GetWebhook returns the webhook with the ID, or ErrWebhookNotFound
+	GetWebhook(ctx context.Context, id int) (*Webhook, error)
+	// ListWebhooks returns every webhook by ID
+	ListWebhooks(ctx context.Context) ([]Webhook, error)
+	// UpdateWebhook replaces the URL, event types and secret of a webhook
+	UpdateWebhook(ctx context.Context, webhook *Webhook) error
+	// DeleteWebhook removes a webhook together with its deliveries
+	DeleteWebhook(ctx context.Context, id int) error
+
+	// EnqueueWebhookDeliveries queues event for every webhook subscribed to its type,
+	// due at now. An event already queued for a webhook is not queued again.
+	EnqueueWebhookDeliveries(ctx context.Context, event Event, now time.Time) error
+	// ClaimWebhookDeliveries returns up to limit pending deliveries due at now, oldest
+	// first, and hides them from other claims until leaseUntil
+	ClaimWebhookDeliveries(ctx context.Context, now, leaseUntil time.Time, limit int) ([]WebhookDelivery, error)
+	// RecordWebhookAttempt appends attempt to the history of its delivery and moves
+	// the delivery to status, due again at nextAttemptAt when it is pending
+	RecordWebhookAttempt(ctx context.Context, attempt WebhookAttempt, status DeliveryStatus, nextAttemptAt time.Time) error
+	// ListWebhookDeliveries returns up to limit deliveries of a webhook, newest first,
+	// only those in status unless it is empty
+	ListWebhookDeliveries(ctx context.Context, webhookID int, status DeliveryStatus, limit int) ([]WebhookDelivery, error)
+	// GetWebhookDelivery returns a delivery of a webhook with its history, or ErrDeliveryNotFound
+	GetWebhookDelivery(ctx context.Context, webhookID int, id int64) (*WebhookDelivery, error)
+	// RedeliverWebhookDelivery makes a delivery pending, due at now and without attempts
+	// whatever its status; its history is kept
+	RedeliverWebhookDelivery(ctx context.Context, webhookID int, id int64, now time.Time) error
+}
+
+// WebhookSender sends a delivery to the URL of its webhook and returns the HTTP
+// status answered, with an error unless it is a 2xx status
+type WebhookSender interface {
+	Send(ctx context.Context, webhook *Webhook, delivery *WebhookDelivery) (int, error)
+}
@@ -1,9 +1,46 @@
 package entity
 
-import "errors"
+import (
+	"fmt"
+	"math"
+	"slices"
+	"time"
+	"unicode/utf8"
 
-// ErrInvalidEntity is used when an entity is invalid
-var ErrInvalidEntity = errors.New("invalid entity")
+	"github.com/dyammarcano/fullcycle_clean_architecture/internal/domain"
+)
+
+// ErrInvalidEntity is matched by every validation error returned by this package.
+//
+// Deprecated: use errors.As with *domain.ValidationError to get the violated rules.
+var ErrInvalidEntity = domain.ErrValidation
+
+const (
+	// MaxItemLength is the maximum number of characters accepted for an order item or line description
+	MaxItemLength = 255
+
+	// MaxSKULength is the maximum number of characters accepted for a line SKU
+	MaxSKULength = 64
+
+	// MaxQuantity is the largest quantity accepted for a line, the range of the gRPC
+	// quantity field and of the Postgres column
+	MaxQuantity = math.MaxInt32
+
+	// MaxLines is the maximum number of lines accepted in a single order
+	MaxLines = 100
+
+	// MaxReasonLength is the maximum number of characters accepted for a status transition reason
+	MaxReasonLength = 500
+
+	// DefaultPageSize is the number of orders listed per page when no page size is given
+	DefaultPageSize = 50
+
+	// MaxPageSize is the maximum number of orders listed per page
+	MaxPageSize = 100
+
+	// MaxWatchedOrders is the maximum number of order IDs a change stream can be filtered by
+	MaxWatchedOrders = 100
+)
 
 var _ CreateOrderUseCase = (*createOrderUseCase)(nil)
 
@@ -17,9 +54,9 @@ type createOrderUseCase struct {
 
 func (c *createOrderUseCase) Execute(input *OrderInputDTO) (*OrderOutputDTO, error) {
 	order := Order{
-		ID:     input.ID,
-		Item:   input.Item,
-		Amount: input.Amount,
+		ID:    input.ID,
+		Item:  input.Item,
+		Lines: input.Lines,
 	}
 	if err := order.IsValid(); err != nil {
 		return nil, err
@@ -48,28 +85,31 @@ type OrderRepository interface {
 }
 
 type OrderInputDTO struct {
-	ID     int     `json:"id"`
-	Item   string  `json:"item"`
-	Amount float32 `json:"amount"`
+	ID    int                `json:"id"`
+	Item  string             `json:"item"`
+	Lines []domain.OrderLine `json:"lines"`
 }
 
 type OrderOutputDTO struct {
-	ID     int     `json:"id"`
-	Item   string  `json:"item"`
-	Amount float32 `json:"amount"`
+	ID     int                `json:"id"`
+	Item   string             `json:"item"`
+	Lines  []domain.OrderLine `json:"lines"`
+	Amount domain.Money       `json:"amount"`
 }
 
 type Order struct {
 	ID     int
 	Item   string
-	Amount float32
+	Lines  []domain.OrderLine
+	Amount domain.Money
 }
 
-func NewOrder(id int, item string, amount float32) (*Order, error) {
+// NewOrder validates the order and computes its total amount from the lines
+func NewOrder(id int, item string, lines []domain.OrderLine) (*Order, error) {
 	order := &Order{
-		ID:     id,
-		Item:   item,
-		Amount: amount,
+		ID:    id,
+		Item:  item,
+		Lines: lines,
 	}
 
 	if err := order.IsValid(); err != nil {
@@ -79,14 +119,223 @@ func NewOrder(id int, item string, amount float32) (*Order, error) {
 	return order, nil
 }
 
+// ValidateID checks that id can identify a stored order
+func ValidateID(id int) error {
+	if id <= 0 {
+		return domain.NewValidationError(domain.FieldError{
+			Field:   "id",
+			Rule:    "positive_integer",
+			Message: "must be a positive integer",
+		})
+	}
+
+	return nil
+}
+
+// ValidateTransitionReason checks the free text reason given for a status change
+func ValidateTransitionReason(reason string) error {
+	if utf8.RuneCountInString(reason) > MaxReasonLength {
+		return domain.NewValidationError(domain.FieldError{
+			Field:   "reason",
+			Rule:    "max_length",
+			Message: "must be at most 500 characters",
+		})
+	}
+
+	return nil
+}
+
+// ValidateVersion checks the version a write expects; zero means any version
+func ValidateVersion(version int) error {
+	if version < 0 {
+		return domain.NewValidationError(domain.FieldError{
+			Field:   "version",
+			Rule:    "non_negative",
+			Message: "must not be negative",
+		})
+	}
+
+	return nil
+}
+
+// ValidateRetention checks how long soft-deleted orders are kept before a purge
+func ValidateRetention(retention time.Duration) error {
+	if retention < 0 {
+		return domain.NewValidationError(domain.FieldError{
+			Field:   "olderThan",
+			Rule:    "non_negative",
+			Message: "must not be negative",
+		})
+	}
+
+	return nil
+}
+
+// ValidateChangeFilter checks the order IDs and event types a change stream is
+// filtered by
+func ValidateChangeFilter(orderIDs []int, types []domain.EventType) error {
+	var fields []domain.FieldError
+
+	if len(orderIDs) > MaxWatchedOrders {
+		fields = append(fields, domain.FieldError{Field: "orderIds", Rule: "max_items", Message: "must contain at most 100 order IDs"})
+	}
+
+	for i, id := range orderIDs {
+		if id <= 0 {
+			fields = append(fields, domain.FieldError{Field: fmt.Sprintf("orderIds[%d]", i), Rule: "positive_integer", Message: "must be a positive integer"})
+		}
+	}
+
+	for i, eventType := range types {
+		if !slices.Contains(domain.EventTypes, eventType) {
+			fields = append(fields, domain.FieldError{
+				Field:   fmt.Sprintf("types[%d]", i),
+				Rule:    "one_of",
+				Message: "must be an order event type such as order.created",
+			})
+		}
+	}
+
+	if len(fields) > 0 {
+		return domain.NewValidationError(fields...)
+	}
+
+	return nil
+}
+
+// ValidateListOrdersQuery checks the filter, sort and page size of an order listing
+// and fills in the default page size and sort
+func ValidateListOrdersQuery(query *domain.ListOrdersQuery) error {
+	var fields []domain.FieldError
+
+	switch {
+	case query.PageSize == 0:
+		query.PageSize = DefaultPageSize
+	case query.PageSize < 0:
+		fields = append(fields, domain.FieldError{Field: "pageSize", Rule: "positive", Message: "must be greater than zero"})
+	case query.PageSize > MaxPageSize:
+		fields = append(fields, domain.FieldError{Field: "pageSize", Rule: "max", Message: "must be at most 100"})
+	}
+
+	if query.SortBy == "" {
+		query.SortBy = domain.SortByID
+	}
+
+	if !query.SortBy.IsValid() {
+		fields = append(fields, domain.FieldError{Field: "sortBy", Rule: "one_of", Message: "must be one of id, item, amount"})
+	}
+
+	filter := query.Filter
+	if utf8.RuneCountInString(filter.ItemContains) > MaxItemLength {
+		fields = append(fields, domain.FieldError{Field: "filter.item", Rule: "max_length", Message: "must be at most 255 characters"})
+	}
+
+	bounds := []struct {
+		path   string
+		amount *domain.Money
+	}{{"filter.minAmount", filter.MinAmount}, {"filter.maxAmount", filter.MaxAmount}}
+
+	for _, bound := range bounds {
+		if bound.amount == nil {
+			continue
+		}
+
+		if _, ok := domain.CurrencyExponent(bound.amount.Currency); !ok {
+			fields = append(fields, domain.FieldError{Field: bound.path + ".currency", Rule: "iso4217", Message: "must be a supported ISO 4217 currency code"})
+		}
+	}
+
+	if filter.MinAmount != nil && filter.MaxAmount != nil {
+		switch {
+		case filter.MinAmount.Currency != filter.MaxAmount.Currency:
+			fields = append(fields, domain.FieldError{Field: "filter.maxAmount.currency", Rule: "same_currency", Message: "must match the currency of the minimum amount"})
+		case filter.MinAmount.Units > filter.MaxAmount.Units:
+			fields = append(fields, domain.FieldError{Field: "filter.maxAmount", Rule: "range", Message: "must not be less than the minimum amount"})
+		}
+	}
+
+	if len(fields) > 0 {
+		return domain.NewValidationError(fields...)
+	}
+
+	return nil
+}
+
+// IsValid checks every rule of the order, computes Amount from the lines and
+// returns a *domain.ValidationError listing each violated field and rule, or nil
+// when the order is valid
 func (o *Order) IsValid() error {
-	if o.Item == "" {
-		return ErrInvalidEntity
+	var fields []domain.FieldError
+
+	switch {
+	case o.Item == "":
+		fields = append(fields, domain.FieldError{Field: "item", Rule: "required", Message: "must not be empty"})
+	case utf8.RuneCountInString(o.Item) > MaxItemLength:
+		fields = append(fields, domain.FieldError{Field: "item", Rule: "max_length", Message: "must be at most 255 characters"})
+	}
+
+	if len(o.Lines) == 0 {
+		fields = append(fields, domain.FieldError{Field: "lines", Rule: "required", Message: "must contain at least one line"})
 	}
 
-	if o.Amount <= 0 {
-		return ErrInvalidEntity
+	if len(o.Lines) > MaxLines {
+		fields = append(fields, domain.FieldError{Field: "lines", Rule: "max_items", Message: "must contain at most 100 lines"})
 	}
 
+	for i, line := range o.Lines {
+		fields = append(fields, validateLine(fmt.Sprintf("lines[%d]", i), line)...)
+
+		if i > 0 && line.UnitPrice.Currency != o.Lines[0].UnitPrice.Currency {
+			fields = append(fields, domain.FieldError{
+				Field:   fmt.Sprintf("lines[%d].unitPrice.currency", i),
+				Rule:    "same_currency",
+				Message: "must match the currency of the first line",
+			})
+		}
+	}
+
+	if len(fields) > 0 {
+		return domain.NewValidationError(fields...)
+	}
+
+	order := domain.Order{Lines: o.Lines}
+	if err := order.Recalculate(); err != nil {
+		return domain.NewValidationError(domain.FieldError{Field: "amount", Rule: "range", Message: err.Error()})
+	}
+
+	o.Amount = order.Amount
+
 	return nil
 }
+
+func validateLine(path string, line domain.OrderLine) []domain.FieldError {
+	var fields []domain.FieldError
+
+	switch {
+	case line.SKU == "":
+		fields = append(fields, domain.FieldError{Field: path + ".sku", Rule: "required", Message: "must not be empty"})
+	case utf8.RuneCountInString(line.SKU) > MaxSKULength:
+		fields = append(fields, domain.FieldError{Field: path + ".sku", Rule: "max_length", Message: "must be at most 64 characters"})
+	}
+
+	if utf8.RuneCountInString(line.Description) > MaxItemLength {
+		fields = append(fields, domain.FieldError{Field: path + ".description", Rule: "max_length", Message: "must be at most 255 characters"})
+	}
+
+	switch {
+	case line.Quantity <= 0:
+		fields = append(fields, domain.FieldError{Field: path + ".quantity", Rule: "positive", Message: "must be greater than zero"})
+	case line.Quantity > MaxQuantity:
+		fields = append(fields, domain.FieldError{Field: path + ".quantity", Rule: "max", Message: "must be at most 2147483647"})
+	}
+
+	if _, ok := domain.CurrencyExponent(line.UnitPrice.Currency); !ok {
+		fields = append(fields, domain.FieldError{Field: path + ".unitPrice.currency", Rule: "iso4217", Message: "must be a supported ISO 4217 currency code"})
+	}
+
+	if line.UnitPrice.Units <= 0 {
+		fields = append(fields, domain.FieldError{Field: path + ".unitPrice", Rule: "positive", Message: "must be greater than zero"})
+	}
+
+	return fields
+}
@@ -0,0 +1,158 @@
+package entity
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/dyammarcano/fullcycle_clean_architecture/internal/domain"
+)
+
+func usd(units int64) domain.Money {
+	return domain.Money{Units: units, Currency: "USD"}
+}
+
+func line(sku string, quantity int, price domain.Money) domain.OrderLine {
+	return domain.OrderLine{SKU: sku, Quantity: quantity, UnitPrice: price}
+}
+
+func TestNewOrder(t *testing.T) {
+	tests := []struct {
+		name  string
+		item  string
+		lines []domain.OrderLine
+		total int64
+		rules []string
+	}{
+		{"valid", "Bag", []domain.OrderLine{line("BAG", 2, usd(1000)), line("TAG", 1, usd(50))}, 2050, nil},
+		{"empty item", "", []domain.OrderLine{line("BAG", 1, usd(1000))}, 0, []string{"item:required"}},
+		{"long item", strings.Repeat("x", MaxItemLength+1), []domain.OrderLine{line("BAG", 1, usd(1000))}, 0, []string{"item:max_length"}},
+		{"no lines", "Bag", nil, 0, []string{"lines:required"}},
+		{"bad line", "Bag", []domain.OrderLine{line("", 0, usd(-1))}, 0, []string{"lines[0].sku:required", "lines[0].quantity:positive", "lines[0].unitPrice:positive"}},
+		{"large quantity", "Bag", []domain.OrderLine{line("BAG", MaxQuantity+1, usd(1))}, 0, []string{"lines[0].quantity:max"}},
+		{"unknown currency", "Bag", []domain.OrderLine{line("BAG", 1, domain.Money{Units: 1, Currency: "XXX"})}, 0, []string{"lines[0].unitPrice.currency:iso4217"}},
+		{"mixed currencies", "Bag", []domain.OrderLine{line("BAG", 1, usd(1)), line("TAG", 1, domain.Money{Units: 1, Currency: "EUR"})}, 0, []string{"lines[1].unitPrice.currency:same_currency"}},
+		{"overflow", "Bag", []domain.OrderLine{line("BAG", 3, usd(1<<62))}, 0, []string{"amount:range"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order, err := NewOrder(0, tt.item, tt.lines)
+			if tt.rules == nil {
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+
+				if order.Amount != usd(tt.total) {
+					t.Errorf("Expected total %d, got %v", tt.total, order.Amount)
+				}
+
+				return
+			}
+
+			var validationErr *domain.ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("Expected validation error, got %v", err)
+			}
+
+			if !errors.Is(err, ErrInvalidEntity) {
+				t.Errorf("Expected error to match ErrInvalidEntity")
+			}
+
+			got := make([]string, 0, len(validationErr.Fields))
+			for _, f := range validationErr.Fields {
+				got = append(got, f.Field+":"+f.Rule)
+			}
+
+			if strings.Join(got, ",") != strings.Join(tt.rules, ",") {
+				t.Errorf("Expected rules %v, got %v", tt.rules, got)
+			}
+		})
+	}
+}
+
+func TestValidateListOrdersQuery(t *testing.T) {
+	query := domain.ListOrdersQuery{}
+	if err := ValidateListOrdersQuery(&query); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if query.PageSize != DefaultPageSize || query.SortBy != domain.SortByID {
+		t.Errorf("Expected defaults, got %+v", query)
+	}
+
+	minAmount, maxAmount, eur := usd(500), usd(100), domain.Money{Units: 100, Currency: "EUR"}
+
+	tests := []struct {
+		name  string
+		query domain.ListOrdersQuery
+		rules []string
+	}{
+		{"page size", domain.ListOrdersQuery{PageSize: MaxPageSize + 1, SortBy: "status"}, []string{"pageSize:max", "sortBy:one_of"}},
+		{"negative page size", domain.ListOrdersQuery{PageSize: -1}, []string{"pageSize:positive"}},
+		{"amount range", domain.ListOrdersQuery{Filter: domain.OrderFilter{MinAmount: &minAmount, MaxAmount: &maxAmount}}, []string{"filter.maxAmount:range"}},
+		{"currencies", domain.ListOrdersQuery{Filter: domain.OrderFilter{MinAmount: &minAmount, MaxAmount: &eur}}, []string{"filter.maxAmount.currency:same_currency"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateListOrdersQuery(&tt.query)
+
+			var validationErr *domain.ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("Expected validation error, got %v", err)
+			}
+
+			got := make([]string, 0, len(validationErr.Fields))
+			for _, f := range validationErr.Fields {
+				got = append(got, f.Field+":"+f.Rule)
+			}
+
+			if strings.Join(got, ",") != strings.Join(tt.rules, ",") {
+				t.Errorf("Expected rules %v, got %v", tt.rules, got)
+			}
+		})
+	}
+}
+
+func TestValidateWebhook(t *testing.T) {
+	tests := []struct {
+		name    string
+		webhook domain.Webhook
+		rules   []string
+	}{
+		{"valid", domain.Webhook{URL: "https://example.com/hook", EventTypes: []domain.EventType{domain.EventOrderCreated}}, nil},
+		{"empty url", domain.Webhook{}, []string{"url:required"}},
+		{"relative url", domain.Webhook{URL: "/hook"}, []string{"url:http_url"}},
+		{"other scheme", domain.Webhook{URL: "ftp://example.com"}, []string{"url:http_url"}},
+		{"event types", domain.Webhook{URL: "http://example.com", EventTypes: []domain.EventType{"order.created", "order.lost", "order.created"}}, []string{"eventTypes[1]:one_of", "eventTypes[2]:unique"}},
+		{"short secret", domain.Webhook{URL: "http://example.com", Secret: "abc"}, []string{"secret:length"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateWebhook(&tt.webhook)
+			if tt.rules == nil {
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+
+				return
+			}
+
+			var validationErr *domain.ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("Expected validation error, got %v", err)
+			}
+
+			got := make([]string, 0, len(validationErr.Fields))
+			for _, f := range validationErr.Fields {
+				got = append(got, f.Field+":"+f.Rule)
+			}
+
+			if strings.Join(got, ",") != strings.Join(tt.rules, ",") {
+				t.Errorf("Expected rules %v, got %v", tt.rules, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package entity
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"unicode/utf8"
+
+	"github.com/dyammarcano/fullcycle_clean_architecture/internal/domain"
+)
+
+const (
+	// MaxWebhookURLLength is the maximum number of characters accepted for a webhook URL
+	MaxWebhookURLLength = 2048
+
+	// MinWebhookSecretLength and MaxWebhookSecretLength bound the characters of a webhook secret
+	MinWebhookSecretLength = 16
+	MaxWebhookSecretLength = 256
+
+	// MaxDeliveriesPerPage is the maximum number of webhook deliveries listed at once
+	MaxDeliveriesPerPage = 100
+)
+
+// ValidateWebhook checks the URL, event types and secret of a webhook. An empty
+// secret is accepted, as the use case generates one.
+func ValidateWebhook(webhook *domain.Webhook) error {
+	var fields []domain.FieldError
+
+	if u, err := url.Parse(webhook.URL); webhook.URL == "" {
+		fields = append(fields, domain.FieldError{Field: "url", Rule: "required", Message: "must not be empty"})
+	} else if utf8.RuneCountInString(webhook.URL) > MaxWebhookURLLength {
+		fields = append(fields, domain.FieldError{Field: "url", Rule: "max_length", Message: "must be at most 2048 characters"})
+	} else if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		fields = append(fields, domain.FieldError{Field: "url", Rule: "http_url", Message: "must be an absolute http or https URL"})
+	}
+
+	for i, eventType := range webhook.EventTypes {
+		switch {
+		case !slices.Contains(domain.EventTypes, eventType):
+			fields = append(fields, domain.FieldError{
+				Field:   fmt.Sprintf("eventTypes[%d]", i),
+				Rule:    "one_of",
+				Message: "must be an order event type such as order.created",
+			})
+		case slices.Contains(webhook.EventTypes[:i], eventType):
+			fields = append(fields, domain.FieldError{Field: fmt.Sprintf("eventTypes[%d]", i), Rule: "unique", Message: "must not be repeated"})
+		}
+	}
+
+	if n := utf8.RuneCountInString(webhook.Secret); webhook.Secret != "" && (n < MinWebhookSecretLength || n > MaxWebhookSecretLength) {
+		fields = append(fields, domain.FieldError{Field: "secret", Rule: "length", Message: "must have between 16 and 256 characters"})
+	}
+
+	if len(fields) > 0 {
+		return domain.NewValidationError(fields...)
+	}
+
+	return nil
+}
+
+// ValidateDeliveriesQuery checks the status deliveries are filtered by, empty for
+// any, and the number listed, zero for the default
+func ValidateDeliveriesQuery(status domain.DeliveryStatus, limit int) error {
+	var fields []domain.FieldError
+
+	if status != "" && !slices.Contains(domain.DeliveryStatuses, status) {
+		fields = append(fields, domain.FieldError{Field: "status", Rule: "one_of", Message: "must be pending, succeeded or dead"})
+	}
+
+	if limit < 0 {
+		fields = append(fields, domain.FieldError{Field: "limit", Rule: "non_negative", Message: "must not be negative"})
+	} else if limit > MaxDeliveriesPerPage {
+		fields = append(fields, domain.FieldError{Field: "limit", Rule: "max", Message: "must be at most 100"})
+	}
+
+	if len(fields) > 0 {
+		return domain.NewValidationError(fields...)
+	}
+
+	return nil
+}
@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/dyammarcano/fullcycle_clean_architecture/internal/domain"
+	"github.com/dyammarcano/fullcycle_clean_architecture/pkg/parameters"
+	"github.com/inovacc/config"
+)
+
+const (
+	// DriverMemory keeps orders in process memory; nothing survives a restart
+	DriverMemory = "memory"
+
+	// DriverPostgres stores orders in PostgreSQL
+	DriverPostgres = "postgres"
+
+	// DriverSQLite stores orders in a single SQLite file set by db.path
+	DriverSQLite = "sqlite"
+
+	// DefaultDriver is used when neither the flag nor db.driver names a backend
+	DefaultDriver = DriverPostgres
+)
+
+// Store is a storage backend: the order repository with the outbox and the webhooks
+// kept in the same database, so events are stored in the transactions of the orders
+type Store interface {
+	domain.OrderRepository
+	domain.EventOutbox
+	domain.WebhookRepository
+}
+
+// Factory builds a storage backend from the loaded service configuration
+type Factory func() (Store, error)
+
+var (
+	factoriesMu sync.RWMutex
+	factories   = map[string]Factory{}
+)
+
+func init() {
+	Register(DriverMemory, newConfiguredMemoryRepository)
+	Register(DriverPostgres, NewOrderPostgresRepository)
+	Register(DriverSQLite, NewOrderSQLiteRepository)
+}
+
+// Register makes a storage backend available under driver. It panics when the
+// driver is registered twice.
+func Register(driver string, factory Factory) {
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+
+	if _, ok := factories[driver]; ok {
+		panic(fmt.Sprintf("repository: driver %q registered twice", driver))
+	}
+
+	factories[driver] = factory
+}
+
+// Drivers returns the registered driver names, sorted
+func Drivers() []string {
+	factoriesMu.RLock()
+	defer factoriesMu.RUnlock()
+
+	drivers := make([]string, 0, len(factories))
+	for driver := range factories {
+		drivers = append(drivers, driver)
+	}
+
+	slices.Sort(drivers)
+
+	return drivers
+}
+
+// New builds the storage backend registered under driver. An empty driver falls
+// back to db.driver from the configuration, then to DefaultDriver.
+func New(driver string) (Store, error) {
+	driver, err := resolveDriver(driver)
+	if err != nil {
+		return nil, err
+	}
+
+	factoriesMu.RLock()
+	factory := factories[driver]
+	factoriesMu.RUnlock()
+
+	return factory()
+}
+
+// resolveDriver returns the registered driver named by driver, db.driver or
+// DefaultDriver, in that order
+func resolveDriver(driver string) (string, error) {
+	if driver == "" {
+		cfg, err := config.GetServiceConfig[*parameters.Service]()
+		if err != nil {
+			return "", err
+		}
+
+		driver = cfg.Database.Driver
+	}
+
+	if driver == "" {
+		driver = DefaultDriver
+	}
+
+	factoriesMu.RLock()
+	_, ok := factories[strings.ToLower(driver)]
+	factoriesMu.RUnlock()
+
+	if !ok {
+		return "", fmt.Errorf("unknown storage driver %q, expected one of %s", driver, strings.Join(Drivers(), ", "))
+	}
+
+	return strings.ToLower(driver), nil
+}
@@ -0,0 +1,33 @@
+package repository
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	if drivers := Drivers(); !slices.Contains(drivers, DriverMemory) || !slices.Contains(drivers, DriverPostgres) {
+		t.Errorf("Unexpected drivers %v", drivers)
+	}
+
+	repo, err := New("MEMORY")
+	if err != nil {
+		t.Fatalf("Error creating memory repository: %v", err)
+	}
+
+	if _, ok := repo.(*OrderMemoryRepository); !ok {
+		t.Errorf("Expected a memory repository, got %T", repo)
+	}
+
+	if _, err = New("cassandra"); err == nil {
+		t.Errorf("Expected an error for an unknown driver")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Expected a panic when a driver is registered twice")
+		}
+	}()
+
+	Register(DriverMemory, NewOrderMemoryRepository)
+}
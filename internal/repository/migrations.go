@@ -0,0 +1,385 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dyammarcano/fullcycle_clean_architecture/pkg/parameters"
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database"
+	"github.com/golang-migrate/migrate/v4/database/postgres"
+	migratesqlite "github.com/golang-migrate/migrate/v4/database/sqlite"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/inovacc/config"
+)
+
+var (
+	//go:embed migrations/*.sql
+	postgresMigrations embed.FS
+
+	//go:embed sqlite_migrations/*.sql
+	sqliteMigrations embed.FS
+)
+
+// migrationSources maps each SQL driver to its embedded migrations and to the
+// directory, relative to this package, they are embedded from
+var migrationSources = map[string]struct {
+	fs  embed.FS
+	dir string
+}{
+	DriverPostgres: {postgresMigrations, "migrations"},
+	DriverSQLite:   {sqliteMigrations, "sqlite_migrations"},
+}
+
+// MigrationsDir returns the source directory, relative to the module root, of the
+// migrations of driver, or of db.driver when driver is empty
+func MigrationsDir(driver string) (string, error) {
+	driver, err := resolveDriver(driver)
+	if err != nil {
+		return "", err
+	}
+
+	src, ok := migrationSources[driver]
+	if !ok {
+		return "", fmt.Errorf("storage driver %q has no migrations", driver)
+	}
+
+	return filepath.Join("internal", "repository", src.dir), nil
+}
+
+// keepOpen stops migrate from closing a database it does not own
+type keepOpen struct {
+	database.Driver
+}
+
+func (keepOpen) Close() error {
+	return nil
+}
+
+// newMigrate returns a migrate instance applying the embedded migrations of driver
+// to db. Closing it releases what it holds but leaves db open.
+func newMigrate(driver string, db *sql.DB) (*migrate.Migrate, error) {
+	src, ok := migrationSources[driver]
+	if !ok {
+		return nil, fmt.Errorf("storage driver %q has no migrations", driver)
+	}
+
+	sourceDriver, err := iofs.New(src.fs, src.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var dbDriver database.Driver
+
+	switch driver {
+	case DriverPostgres:
+		// A dedicated connection holds the migration lock; closing the driver
+		// returns it to the pool
+		conn, connErr := db.Conn(context.Background())
+		if connErr != nil {
+			return nil, connErr
+		}
+
+		if dbDriver, err = postgres.WithConnection(context.Background(), conn, &postgres.Config{}); err != nil {
+			_ = conn.Close()
+		}
+	case DriverSQLite:
+		var sqliteDriver database.Driver
+		if sqliteDriver, err = migratesqlite.WithInstance(db, &migratesqlite.Config{}); err == nil {
+			dbDriver = keepOpen{sqliteDriver}
+		}
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return migrate.NewWithInstance("iofs", sourceDriver, driver, dbDriver)
+}
+
+// migrateUp applies every pending migration of driver to db
+func migrateUp(driver string, db *sql.DB) error {
+	m, err := newMigrate(driver, db)
+	if err != nil {
+		return err
+	}
+
+	defer func() { _, _ = m.Close() }()
+
+	if err = m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return fmt.Errorf("migrate %s database: %w", driver, err)
+	}
+
+	return nil
+}
+
+// checkSchemaVersion fails when the schema of db is missing, behind the embedded
+// migrations of driver or was left dirty, so the server does not start against a
+// schema its queries do not match. It only reads the version table.
+func checkSchemaVersion(driver string, db *sql.DB, queryTimeout time.Duration) error {
+	migrations, err := embeddedMigrations(driver)
+	if err != nil {
+		return err
+	}
+
+	if len(migrations) == 0 {
+		return nil
+	}
+
+	latest := migrations[len(migrations)-1].Version
+
+	ctx, cancel := context.WithTimeout(context.Background(), queryTimeout)
+	defer cancel()
+
+	var (
+		version uint
+		dirty   bool
+	)
+
+	err = db.QueryRowContext(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return fmt.Errorf("%s database has no schema, run the migrate up command or enable db.autoMigrate", driver)
+	case err != nil:
+		return fmt.Errorf("read %s schema version, run the migrate up command or enable db.autoMigrate: %w", driver, err)
+	case dirty:
+		return fmt.Errorf("%s database schema is dirty at version %d, fix it and run the migrate force command", driver, version)
+	case version < latest:
+		return fmt.Errorf("%s database schema is at version %d, behind %d, run the migrate up command or enable db.autoMigrate", driver, version, latest)
+	}
+
+	return nil
+}
+
+// Migrator runs the embedded migrations against the configured database
+type Migrator struct {
+	driver string
+	db     *sql.DB
+	m      *migrate.Migrate
+}
+
+// NewMigrator connects to the database of driver, or of db.driver when empty,
+// without changing its schema
+func NewMigrator(driver string) (*Migrator, error) {
+	cfg, err := config.GetServiceConfig[*parameters.Service]()
+	if err != nil {
+		return nil, err
+	}
+
+	if driver, err = resolveDriver(driver); err != nil {
+		return nil, err
+	}
+
+	if _, ok := migrationSources[driver]; !ok {
+		return nil, fmt.Errorf("storage driver %q has no migrations", driver)
+	}
+
+	var db *sql.DB
+
+	switch driver {
+	case DriverPostgres:
+		db, err = openPostgres(cfg.Database)
+	case DriverSQLite:
+		db, err = openSQLite(sqlitePath(cfg.Database), cfg.Database.GetQueryTimeout())
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return newMigrator(driver, db)
+}
+
+// newMigrator returns a Migrator owning db
+func newMigrator(driver string, db *sql.DB) (*Migrator, error) {
+	m, err := newMigrate(driver, db)
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	return &Migrator{driver: driver, db: db, m: m}, nil
+}
+
+// Driver returns the storage driver being migrated
+func (m *Migrator) Driver() string {
+	return m.driver
+}
+
+// Up applies every pending migration
+func (m *Migrator) Up() error {
+	return ignoreNoChange(m.m.Up())
+}
+
+// Down reverts the last steps migrations, or all of them when steps is zero
+func (m *Migrator) Down(steps int) error {
+	if steps <= 0 {
+		return ignoreNoChange(m.m.Down())
+	}
+
+	return ignoreNoChange(m.m.Steps(-steps))
+}
+
+// Goto migrates up or down to version
+func (m *Migrator) Goto(version uint) error {
+	return ignoreNoChange(m.m.Migrate(version))
+}
+
+// Force sets the schema version without running any migration and clears the
+// dirty flag. It is the way out of a migration that failed halfway; -1 means no
+// migration was applied.
+func (m *Migrator) Force(version int) error {
+	return m.m.Force(version)
+}
+
+// Migration is one embedded migration
+type Migration struct {
+	Version uint
+	Name    string
+	Applied bool
+}
+
+// MigrationStatus is the schema version of the database and the known migrations
+type MigrationStatus struct {
+	// Version is the last applied migration, zero when none was applied
+	Version uint
+	// Dirty reports that the last migration failed and the schema needs fixing
+	// before Force is used
+	Dirty      bool
+	Migrations []Migration
+}
+
+// Status returns the schema version and which embedded migrations are applied
+func (m *Migrator) Status() (*MigrationStatus, error) {
+	version, dirty, err := m.m.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return nil, err
+	}
+
+	migrations, err := embeddedMigrations(m.driver)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range migrations {
+		migrations[i].Applied = migrations[i].Version < version || (migrations[i].Version == version && !dirty)
+	}
+
+	return &MigrationStatus{Version: version, Dirty: dirty, Migrations: migrations}, nil
+}
+
+// Close releases the database connection
+func (m *Migrator) Close() error {
+	srcErr, dbErr := m.m.Close()
+
+	return errors.Join(srcErr, dbErr, m.db.Close())
+}
+
+func ignoreNoChange(err error) error {
+	if errors.Is(err, migrate.ErrNoChange) {
+		return nil
+	}
+
+	return err
+}
+
+// embeddedMigrations lists the migrations embedded for driver, oldest first
+func embeddedMigrations(driver string) ([]Migration, error) {
+	src := migrationSources[driver]
+
+	sourceDriver, err := iofs.New(src.fs, src.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = sourceDriver.Close() }()
+
+	var migrations []Migration
+
+	version, err := sourceDriver.First()
+	for err == nil {
+		migrations = append(migrations, Migration{Version: version, Name: migrationName(sourceDriver, version)})
+		version, err = sourceDriver.Next(version)
+	}
+
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	return migrations, nil
+}
+
+func migrationName(sourceDriver source.Driver, version uint) string {
+	r, name, err := sourceDriver.ReadUp(version)
+	if err != nil {
+		return ""
+	}
+
+	_ = r.Close()
+
+	return name
+}
+
+var (
+	migrationFile = regexp.MustCompile(`^(\d+)_.*\.(up|down)\.sql$`)
+	migrationSlug = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// CreateMigration writes empty up and down files for a new migration in dir,
+// numbered after the last migration found there, and returns their paths
+func CreateMigration(dir, name string) ([]string, error) {
+	slug := strings.Trim(migrationSlug.ReplaceAllString(strings.ToLower(name), "_"), "_")
+	if slug == "" {
+		return nil, fmt.Errorf("invalid migration name %q", name)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var last uint64
+
+	for _, entry := range entries {
+		match := migrationFile.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+
+		version, parseErr := strconv.ParseUint(match[1], 10, 64)
+		if parseErr == nil && version > last {
+			last = version
+		}
+	}
+
+	paths := make([]string, 0, 2)
+
+	for _, direction := range []string{"up", "down"} {
+		path := filepath.Join(dir, fmt.Sprintf("%06d_%s.%s.sql", last+1, slug, direction))
+
+		f, createErr := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+		if createErr != nil {
+			return paths, createErr
+		}
+
+		if err = f.Close(); err != nil {
+			return paths, err
+		}
+
+		paths = append(paths, path)
+	}
+
+	return paths, nil
+}
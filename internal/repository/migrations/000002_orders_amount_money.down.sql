@@ -0,0 +1,4 @@
+-- Reverting truncates fractional amounts and drops the currency.
+ALTER TABLE orders DROP COLUMN IF EXISTS currency;
+ALTER TABLE orders RENAME COLUMN amount_units TO amount;
+ALTER TABLE orders ALTER COLUMN amount TYPE INTEGER USING (amount / 100)::INTEGER;
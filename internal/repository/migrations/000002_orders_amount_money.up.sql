@@ -0,0 +1,5 @@
+-- Amounts are stored as exact minor units (e.g. cents) together with their ISO 4217 currency.
+-- Existing rows only held whole units, so they are converted as USD amounts.
+ALTER TABLE orders ALTER COLUMN amount TYPE BIGINT USING amount::BIGINT * 100;
+ALTER TABLE orders RENAME COLUMN amount TO amount_units;
+ALTER TABLE orders ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'USD';
@@ -0,0 +1,15 @@
+ALTER TABLE orders
+    ADD COLUMN status VARCHAR(16) NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'confirmed', 'paid', 'shipped', 'delivered', 'cancelled', 'refunded'));
+
+CREATE TABLE IF NOT EXISTS order_status_history
+(
+    id          SERIAL PRIMARY KEY,
+    order_id    INTEGER     NOT NULL REFERENCES orders (id) ON DELETE CASCADE,
+    from_status VARCHAR(16) NOT NULL,
+    to_status   VARCHAR(16) NOT NULL,
+    reason      VARCHAR     NOT NULL DEFAULT '',
+    changed_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS order_status_history_order_id_idx ON order_status_history (order_id, id);
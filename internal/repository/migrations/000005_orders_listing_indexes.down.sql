@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS orders_amount_id_idx;
+
+DROP INDEX IF EXISTS orders_currency_amount_id_idx;
+
+DROP INDEX IF EXISTS orders_item_id_idx;
@@ -0,0 +1,9 @@
+-- Soft-deleted orders would become live again once the column is gone.
+DELETE FROM orders WHERE deleted_at IS NOT NULL;
+
+DROP INDEX IF EXISTS orders_deleted_at_idx;
+
+ALTER TABLE orders
+    DROP COLUMN IF EXISTS deleted_at,
+    DROP COLUMN IF EXISTS updated_at,
+    DROP COLUMN IF EXISTS created_at;
@@ -0,0 +1,7 @@
+ALTER TABLE orders
+    ADD COLUMN created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    ADD COLUMN updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    ADD COLUMN deleted_at TIMESTAMPTZ;
+
+-- Only soft-deleted orders are looked up by deletion time, when listing or purging them.
+CREATE INDEX IF NOT EXISTS orders_deleted_at_idx ON orders (deleted_at) WHERE deleted_at IS NOT NULL;
@@ -0,0 +1,2 @@
+-- Every write increments the version, so clients can detect concurrent changes.
+ALTER TABLE orders ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
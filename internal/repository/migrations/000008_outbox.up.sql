@@ -0,0 +1,15 @@
+-- Events are written in the transaction of the order change and removed once the
+-- relay has delivered them.
+CREATE TABLE IF NOT EXISTS outbox (
+    id              BIGSERIAL PRIMARY KEY,
+    event_type      TEXT        NOT NULL,
+    order_id        INTEGER     NOT NULL,
+    order_version   INTEGER     NOT NULL,
+    occurred_at     TIMESTAMPTZ NOT NULL,
+    data            JSONB       NOT NULL,
+    attempts        INTEGER     NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL,
+    last_error      TEXT        NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS outbox_next_attempt_at_idx ON outbox (next_attempt_at);
@@ -0,0 +1,44 @@
+-- Webhooks receive the order events of the types they subscribe to. Every event is
+-- queued once per webhook in webhook_deliveries, and every request sent for a
+-- delivery is kept in webhook_attempts.
+CREATE TABLE IF NOT EXISTS webhooks
+(
+    id          SERIAL PRIMARY KEY,
+    url         TEXT        NOT NULL,
+    event_types TEXT        NOT NULL DEFAULT '',
+    secret      TEXT        NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL,
+    updated_at  TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries
+(
+    id              BIGSERIAL PRIMARY KEY,
+    webhook_id      INTEGER     NOT NULL REFERENCES webhooks (id) ON DELETE CASCADE,
+    event_id        BIGINT      NOT NULL,
+    event_type      TEXT        NOT NULL,
+    order_id        INTEGER     NOT NULL,
+    order_version   INTEGER     NOT NULL,
+    occurred_at     TIMESTAMPTZ NOT NULL,
+    data            JSONB       NOT NULL,
+    status          TEXT        NOT NULL CHECK (status IN ('pending', 'succeeded', 'dead')),
+    attempts        INTEGER     NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL,
+    last_error      TEXT        NOT NULL DEFAULT '',
+    created_at      TIMESTAMPTZ NOT NULL,
+    updated_at      TIMESTAMPTZ NOT NULL,
+    UNIQUE (webhook_id, event_id)
+);
+
+CREATE INDEX IF NOT EXISTS webhook_deliveries_due_idx ON webhook_deliveries (status, next_attempt_at);
+
+CREATE TABLE IF NOT EXISTS webhook_attempts
+(
+    id          BIGSERIAL PRIMARY KEY,
+    delivery_id BIGINT      NOT NULL REFERENCES webhook_deliveries (id) ON DELETE CASCADE,
+    at          TIMESTAMPTZ NOT NULL,
+    status_code INTEGER     NOT NULL DEFAULT 0,
+    error       TEXT        NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS webhook_attempts_delivery_id_idx ON webhook_attempts (delivery_id, id);
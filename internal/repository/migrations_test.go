@@ -0,0 +1,183 @@
+package repository
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMigrator(t *testing.T) {
+	db, err := openSQLite(filepath.Join(t.TempDir(), "orders.db"), 5*time.Second)
+	if err != nil {
+		t.Fatalf("Error opening database: %v", err)
+	}
+
+	migrator, err := newMigrator(DriverSQLite, db)
+	if err != nil {
+		t.Fatalf("Error creating migrator: %v", err)
+	}
+
+	defer func() { _ = migrator.Close() }()
+
+	embedded, err := embeddedMigrations(DriverSQLite)
+	if err != nil || len(embedded) < 3 {
+		t.Fatalf("Unexpected embedded migrations %+v (%v)", embedded, err)
+	}
+
+	all := make([]uint, 0, len(embedded))
+	for _, m := range embedded {
+		all = append(all, m.Version)
+	}
+
+	applied := func() []uint {
+		t.Helper()
+
+		status, err := migrator.Status()
+		if err != nil {
+			t.Fatalf("Error reading status: %v", err)
+		}
+
+		if status.Dirty {
+			t.Errorf("Schema is dirty at version %d", status.Version)
+		}
+
+		var versions []uint
+		for _, m := range status.Migrations {
+			if m.Applied {
+				versions = append(versions, m.Version)
+			}
+		}
+
+		if len(status.Migrations) != len(all) || status.Migrations[0].Name != "create_orders_table" {
+			t.Errorf("Unexpected migrations %+v", status.Migrations)
+		}
+
+		return versions
+	}
+
+	steps := []struct {
+		name string
+		run  func() error
+		want []uint
+	}{
+		{"up", migrator.Up, all},
+		{"up again", migrator.Up, all},
+		{"down one", func() error { return migrator.Down(1) }, all[:len(all)-1]},
+		{"goto", func() error { return migrator.Goto(2) }, []uint{1, 2}},
+		{"force", func() error { return migrator.Force(3) }, []uint{1, 2, 3}},
+		{"down all", func() error { return migrator.Down(0) }, nil},
+	}
+
+	if got := applied(); got != nil {
+		t.Fatalf("Fresh database has applied migrations %v", got)
+	}
+
+	for _, step := range steps {
+		if step.name == "force" {
+			// Force only records the version, so apply migration 3 by hand first
+			if err = migrator.Goto(3); err != nil {
+				t.Fatalf("Error migrating to 3: %v", err)
+			}
+
+			if _, err = db.Exec(`UPDATE schema_migrations SET dirty = 1`); err != nil {
+				t.Fatalf("Error marking the schema dirty: %v", err)
+			}
+		}
+
+		if err = step.run(); err != nil {
+			t.Fatalf("Error running %s: %v", step.name, err)
+		}
+
+		if got := applied(); !slices.Equal(got, step.want) {
+			t.Errorf("Applied after %s = %v, want %v", step.name, got, step.want)
+		}
+	}
+}
+
+func TestCreateMigration(t *testing.T) {
+	dir := t.TempDir()
+
+	for _, name := range []string{"000004_add_order_status.up.sql", "000004_add_order_status.down.sql", "README.md"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	paths, err := CreateMigration(dir, "Add order notes!")
+	if err != nil {
+		t.Fatalf("Error creating migration: %v", err)
+	}
+
+	want := []string{
+		filepath.Join(dir, "000005_add_order_notes.up.sql"),
+		filepath.Join(dir, "000005_add_order_notes.down.sql"),
+	}
+	if !slices.Equal(paths, want) {
+		t.Errorf("Created %v, want %v", paths, want)
+	}
+
+	if _, err = CreateMigration(dir, "!!"); err == nil {
+		t.Errorf("Expected an error for an empty name")
+	}
+}
+
+func TestMigrationsDir(t *testing.T) {
+	dir, err := MigrationsDir("SQLite")
+	if err != nil || dir != filepath.Join("internal", "repository", "sqlite_migrations") {
+		t.Errorf("MigrationsDir(SQLite) = %q, %v", dir, err)
+	}
+
+	if _, err = MigrationsDir(DriverMemory); err == nil {
+		t.Errorf("Expected an error for a backend without migrations")
+	}
+}
+
+func TestCheckSchemaVersion(t *testing.T) {
+	db, err := openSQLite(filepath.Join(t.TempDir(), "orders.db"), 5*time.Second)
+	if err != nil {
+		t.Fatalf("Error opening database: %v", err)
+	}
+
+	migrator, err := newMigrator(DriverSQLite, db)
+	if err != nil {
+		t.Fatalf("Error creating migrator: %v", err)
+	}
+
+	defer func() { _ = migrator.Close() }()
+
+	check := func(step, want string) {
+		t.Helper()
+
+		err := checkSchemaVersion(DriverSQLite, db, 5*time.Second)
+		if want == "" && err != nil {
+			t.Errorf("Schema %s: unexpected error %v", step, err)
+		}
+
+		if want != "" && (err == nil || !strings.Contains(err.Error(), want)) {
+			t.Errorf("Schema %s: error %v, want one naming %q", step, err, want)
+		}
+	}
+
+	check("missing", "migrate up")
+
+	if err = migrator.Goto(2); err != nil {
+		t.Fatalf("Error migrating to 2: %v", err)
+	}
+
+	check("behind", "migrate up")
+
+	if err = migrator.Up(); err != nil {
+		t.Fatalf("Error migrating up: %v", err)
+	}
+
+	check("up to date", "")
+
+	if _, err = db.Exec(`UPDATE schema_migrations SET dirty = 1`); err != nil {
+		t.Fatalf("Error marking the schema dirty: %v", err)
+	}
+
+	check("dirty", "migrate force")
+}
@@ -1,88 +1,604 @@
 package repository
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
 
 	"github.com/dyammarcano/fullcycle_clean_architecture/internal/domain"
+	"github.com/dyammarcano/fullcycle_clean_architecture/pkg/parameters"
+	"github.com/inovacc/config"
 )
 
+// OrderMemoryRepository keeps orders in process memory. It is safe for concurrent
+// use and never hands out its stored orders, only copies. With a snapshot path it
+// loads the orders on start and writes them back periodically and on Close.
 type OrderMemoryRepository struct {
-	orders map[int]*domain.Order
+	*memoryState
+	// undo is set on the repositories handed to Transact callbacks, which run under
+	// the write lock and log how to revert each of their writes
+	undo *memoryUndo
+
+	snapshotPath string
+	stop         chan struct{}
+	done         chan struct{}
 }
 
-func (r *OrderMemoryRepository) GetOrderByID(id int) (*domain.Order, error) {
-	order, ok := r.orders[id]
+// memoryState is the stored state of the memory repository
+type memoryState struct {
+	mu      sync.RWMutex
+	orders  map[int]*domain.Order
+	history map[int][]domain.StatusTransition
+	// lastID only grows, so an ID is never issued twice even after deletes
+	lastID int
+	// events is the outbox, ordered by ID
+	events      []memoryEvent
+	lastEventID int64
+	// webhooks and the queue of their deliveries, ordered by ID
+	webhooks       map[int]*domain.Webhook
+	lastWebhookID  int
+	deliveries     []domain.WebhookDelivery
+	lastDeliveryID int64
+
+	// dirty is set by every write and cleared once a snapshot is saved
+	dirty bool
+}
+
+// memoryUndo reverts the writes of a transaction, newest first
+type memoryUndo []func()
+
+func (u *memoryUndo) revert() {
+	for i := len(*u) - 1; i >= 0; i-- {
+		(*u)[i]()
+	}
+}
+
+func (r *OrderMemoryRepository) GetOrderByID(_ context.Context, id int) (*domain.Order, error) {
+	r.rlock()
+	defer r.runlock()
+
+	order, ok := r.live(id)
 	if !ok {
-		return nil, errors.New("order not found")
+		return nil, domain.ErrOrderNotFound
+	}
+
+	return cloneOrder(order), nil
+}
+
+// live returns the order with the ID unless it is missing or soft-deleted. The
+// caller holds the lock.
+func (r *OrderMemoryRepository) live(id int) (*domain.Order, bool) {
+	order, ok := r.orders[id]
+	if !ok || order.DeletedAt != nil {
+		return nil, false
 	}
 
-	return order, nil
+	return order, true
 }
 
-func (r *OrderMemoryRepository) ListOrders() ([]*domain.Order, error) {
+func (r *OrderMemoryRepository) ListOrders(_ context.Context, query domain.ListOrdersQuery) (*domain.OrderPage, error) {
+	compare := func(a, b domain.OrderCursor) int {
+		if query.Descending {
+			return b.Compare(a, query.SortBy)
+		}
+
+		return a.Compare(b, query.SortBy)
+	}
+
+	r.rlock()
 	orders := make([]*domain.Order, 0, len(r.orders))
 	for _, order := range r.orders {
+		if !query.Filter.Matches(order) {
+			continue
+		}
+
+		if query.After != nil && compare(domain.CursorFor(order, query.SortBy), *query.After) <= 0 {
+			continue
+		}
+
 		orders = append(orders, order)
 	}
+	r.runlock()
+
+	slices.SortFunc(orders, func(a, b *domain.Order) int {
+		return compare(domain.CursorFor(a, query.SortBy), domain.CursorFor(b, query.SortBy))
+	})
 
-	return orders, nil
+	page := pageOf(orders, query.PageSize)
+	for i, order := range page.Orders {
+		page.Orders[i] = cloneOrder(order)
+	}
+
+	return page, nil
 }
 
-func (r *OrderMemoryRepository) CreateOrder(order *domain.Order) (*domain.Order, error) {
+func (r *OrderMemoryRepository) CreateOrder(_ context.Context, order *domain.Order) (*domain.Order, error) {
 	if order == nil {
-		return nil, errors.New("invalid entity")
+		return nil, domain.ErrInvalidOrder
 	}
 
-	if order.ID != 0 {
-		if _, ok := r.orders[order.ID]; ok {
-			return nil, errors.New("order already exists")
-		}
-	}
+	r.lock()
+	defer r.unlock()
 
-	order.ID = len(r.orders) + 1
-	r.orders[order.ID] = order
+	r.lastID++
+	createdAt := now()
+	order.ID = r.lastID
+	order.Version = 1
+	order.CreatedAt, order.UpdatedAt, order.DeletedAt = createdAt, createdAt, nil
+	r.saveOrder(order.ID)
+	r.orders[order.ID] = cloneOrder(order)
+	r.dirty = true
 
-	return order, nil
+	return cloneOrder(order), nil
 }
 
-func (r *OrderMemoryRepository) UpdateOrder(id int, order *domain.Order) error {
+func (r *OrderMemoryRepository) UpdateOrder(_ context.Context, id int, order *domain.Order) error {
 	if order == nil {
-		return errors.New("invalid entity")
+		return domain.ErrInvalidOrder
+	}
+
+	r.lock()
+	defer r.unlock()
+
+	stored, ok := r.live(id)
+	if !ok {
+		return domain.ErrOrderNotFound
 	}
 
-	if _, ok := r.orders[id]; !ok {
-		return errors.New("order not found")
+	if order.Version != 0 && order.Version != stored.Version {
+		return domain.ErrOrderVersionMismatch
 	}
 
 	order.ID = id
-	r.orders[id] = order
+	order.Status = stored.Status
+	order.Version = stored.Version + 1
+	order.CreatedAt, order.UpdatedAt, order.DeletedAt = stored.CreatedAt, now(), nil
+	r.saveOrder(id)
+	r.orders[id] = cloneOrder(order)
+	r.dirty = true
 
 	return nil
 }
 
-func (r *OrderMemoryRepository) DeleteOrder(id int) error {
-	if _, ok := r.orders[id]; !ok {
-		return errors.New("order not found")
+func (r *OrderMemoryRepository) DeleteOrder(_ context.Context, id int, version int) (*domain.Order, error) {
+	r.lock()
+	defer r.unlock()
+
+	order, ok := r.live(id)
+	if !ok {
+		return nil, domain.ErrOrderNotFound
+	}
+
+	if version != 0 && version != order.Version {
+		return nil, domain.ErrOrderVersionMismatch
 	}
 
-	delete(r.orders, id)
+	r.saveOrder(id)
+
+	deletedAt := now()
+	order.DeletedAt, order.UpdatedAt = &deletedAt, deletedAt
+	order.Version++
+	r.dirty = true
+
+	return cloneOrder(order), nil
+}
+
+func (r *OrderMemoryRepository) RestoreOrder(_ context.Context, id int) error {
+	r.lock()
+	defer r.unlock()
+
+	order, ok := r.orders[id]
+	if !ok || order.DeletedAt == nil {
+		return domain.ErrOrderNotFound
+	}
+
+	r.saveOrder(id)
+	order.DeletedAt, order.UpdatedAt = nil, now()
+	order.Version++
+	r.dirty = true
 
 	return nil
 }
 
+func (r *OrderMemoryRepository) PurgeDeletedOrders(_ context.Context, deletedBefore time.Time) (int, error) {
+	r.lock()
+	defer r.unlock()
+
+	purged := 0
+
+	for id, order := range r.orders {
+		if order.DeletedAt != nil && order.DeletedAt.Before(deletedBefore) {
+			r.saveOrder(id)
+			r.saveHistory(id)
+			delete(r.orders, id)
+			delete(r.history, id)
+			purged++
+		}
+	}
+
+	if purged > 0 {
+		r.dirty = true
+	}
+
+	return purged, nil
+}
+
+func (r *OrderMemoryRepository) ChangeOrderStatus(_ context.Context, id int, transition domain.StatusTransition) error {
+	r.lock()
+	defer r.unlock()
+
+	order, ok := r.live(id)
+	if !ok {
+		return domain.ErrOrderNotFound
+	}
+
+	if order.Status != transition.From {
+		return domain.ErrOrderStatusChanged
+	}
+
+	r.saveOrder(id)
+	r.saveHistory(id)
+	order.Status, order.UpdatedAt = transition.To, now()
+	order.Version++
+	r.history[id] = append(r.history[id], transition)
+	r.dirty = true
+
+	return nil
+}
+
+func (r *OrderMemoryRepository) ListStatusHistory(_ context.Context, id int) ([]domain.StatusTransition, error) {
+	r.rlock()
+	defer r.runlock()
+
+	if _, ok := r.live(id); !ok {
+		return nil, domain.ErrOrderNotFound
+	}
+
+	return slices.Clone(r.history[id]), nil
+}
+
+// Transact runs fn holding the write lock, on a repository that logs how to revert
+// its writes. The log is replayed when fn fails, so only what fn touched is copied.
+// Other callers wait until fn returns, so fn must only use the repository it is
+// given. Nested calls join the running transaction.
+func (r *OrderMemoryRepository) Transact(_ context.Context, fn func(repo domain.OrderRepository, outbox domain.EventOutbox) error) (err error) {
+	if r.undo != nil {
+		return fn(r, r)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tx := &OrderMemoryRepository{memoryState: r.memoryState, undo: &memoryUndo{}}
+	lastID, lastEventID, lastWebhookID, lastDeliveryID, dirty := r.lastID, r.lastEventID, r.lastWebhookID, r.lastDeliveryID, r.dirty
+
+	committed := false
+	defer func() {
+		if !committed {
+			tx.undo.revert()
+			r.lastID, r.lastEventID, r.lastWebhookID, r.lastDeliveryID, r.dirty = lastID, lastEventID, lastWebhookID, lastDeliveryID, dirty
+		}
+	}()
+
+	if err = fn(tx, tx); err != nil {
+		return err
+	}
+
+	committed = true
+
+	return nil
+}
+
+// lock takes the write lock, which the repositories handed to Transact callbacks
+// already hold
+func (r *OrderMemoryRepository) lock() {
+	if r.undo == nil {
+		r.mu.Lock()
+	}
+}
+
+func (r *OrderMemoryRepository) unlock() {
+	if r.undo == nil {
+		r.mu.Unlock()
+	}
+}
+
+// rlock takes the read lock, unless the write lock is held by Transact
+func (r *OrderMemoryRepository) rlock() {
+	if r.undo == nil {
+		r.mu.RLock()
+	}
+}
+
+func (r *OrderMemoryRepository) runlock() {
+	if r.undo == nil {
+		r.mu.RUnlock()
+	}
+}
+
+// saveOrder logs how to restore the stored order with the ID, missing or not.
+// The caller holds the lock.
+func (r *OrderMemoryRepository) saveOrder(id int) {
+	if r.undo == nil {
+		return
+	}
+
+	order, ok := r.orders[id]
+	if ok {
+		order = cloneOrder(order)
+	}
+
+	*r.undo = append(*r.undo, func() {
+		if ok {
+			r.orders[id] = order
+		} else {
+			delete(r.orders, id)
+		}
+	})
+}
+
+// saveHistory logs how to restore the status history of the order with the ID.
+// Transitions are only ever appended, so the slice itself is kept. The caller
+// holds the lock.
+func (r *OrderMemoryRepository) saveHistory(id int) {
+	if r.undo == nil {
+		return
+	}
+
+	history, ok := r.history[id]
+
+	*r.undo = append(*r.undo, func() {
+		if ok {
+			r.history[id] = history
+		} else {
+			delete(r.history, id)
+		}
+	})
+}
+
+// Close stops the periodic snapshots and, when a snapshot path is set, saves the
+// orders a last time
 func (r *OrderMemoryRepository) Close() error {
+	if r.stop != nil {
+		close(r.stop)
+		<-r.done
+		r.stop = nil
+	}
+
+	var err error
+	if r.snapshotPath != "" {
+		err = r.Snapshot()
+	}
+
+	r.mu.Lock()
 	r.orders = nil
+	r.history = nil
+	r.events = nil
+	r.webhooks = nil
+	r.deliveries = nil
+	r.mu.Unlock()
+
+	return err
+}
+
+// memorySnapshot is the on-disk format of the memory repository
+type memorySnapshot struct {
+	LastID      int                               `json:"lastId"`
+	Orders      []*domain.Order                   `json:"orders"`
+	History     map[int][]domain.StatusTransition `json:"history,omitempty"`
+	LastEventID int64                             `json:"lastEventId,omitempty"`
+	Events      []memoryEvent                     `json:"events,omitempty"`
+
+	LastWebhookID  int                      `json:"lastWebhookId,omitempty"`
+	Webhooks       []*domain.Webhook        `json:"webhooks,omitempty"`
+	LastDeliveryID int64                    `json:"lastDeliveryId,omitempty"`
+	Deliveries     []domain.WebhookDelivery `json:"deliveries,omitempty"`
+}
+
+// Snapshot writes the orders to the snapshot path. The file is replaced atomically
+// so a crash while saving keeps the previous snapshot.
+func (r *OrderMemoryRepository) Snapshot() error {
+	r.mu.Lock()
+	if !r.dirty {
+		r.mu.Unlock()
+		return nil
+	}
+
+	snapshot := memorySnapshot{
+		LastID:      r.lastID,
+		Orders:      make([]*domain.Order, 0, len(r.orders)),
+		History:     r.history,
+		LastEventID: r.lastEventID,
+		Events:      r.events,
+
+		LastWebhookID:  r.lastWebhookID,
+		LastDeliveryID: r.lastDeliveryID,
+		Deliveries:     r.deliveries,
+	}
+
+	for _, order := range r.orders {
+		snapshot.Orders = append(snapshot.Orders, order)
+	}
+
+	for _, webhook := range r.webhooks {
+		snapshot.Webhooks = append(snapshot.Webhooks, webhook)
+	}
+
+	slices.SortFunc(snapshot.Orders, func(a, b *domain.Order) int { return a.ID - b.ID })
+	slices.SortFunc(snapshot.Webhooks, func(a, b *domain.Webhook) int { return a.ID - b.ID })
+
+	data, err := json.Marshal(snapshot)
+	r.dirty = false
+	r.mu.Unlock()
+
+	if err == nil {
+		err = writeFileAtomic(r.snapshotPath, data)
+	}
+
+	if err != nil {
+		r.mu.Lock()
+		r.dirty = true
+		r.mu.Unlock()
+
+		return fmt.Errorf("saving memory snapshot: %w", err)
+	}
+
+	return nil
+}
+
+// load restores the orders saved in the snapshot path, if the file exists
+func (r *OrderMemoryRepository) load() error {
+	data, err := os.ReadFile(r.snapshotPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("loading memory snapshot: %w", err)
+	}
+
+	var snapshot memorySnapshot
+	if err = json.Unmarshal(data, &snapshot); err != nil {
+		return fmt.Errorf("loading memory snapshot %s: %w", r.snapshotPath, err)
+	}
+
+	for _, order := range snapshot.Orders {
+		// snapshots written before versions existed start their orders at 1
+		order.Version = max(order.Version, 1)
+		r.orders[order.ID] = order
+		r.lastID = max(r.lastID, order.ID)
+	}
+
+	for id, history := range snapshot.History {
+		r.history[id] = history
+	}
+
+	r.lastID = max(r.lastID, snapshot.LastID)
+	r.events = snapshot.Events
+	r.lastEventID = snapshot.LastEventID
+
+	for _, webhook := range snapshot.Webhooks {
+		r.webhooks[webhook.ID] = webhook
+	}
+
+	r.lastWebhookID = snapshot.LastWebhookID
+	r.deliveries = snapshot.Deliveries
+	r.lastDeliveryID = snapshot.LastDeliveryID
 
 	return nil
 }
 
-func NewOrderMemoryRepository() (domain.OrderRepository, error) {
-	return &OrderMemoryRepository{
-		orders: make(map[int]*domain.Order),
-	}, nil
+// snapshotLoop saves a snapshot on every tick until Close is called
+func (r *OrderMemoryRepository) snapshotLoop(interval time.Duration) {
+	defer close(r.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := r.Snapshot(); err != nil {
+				slog.Error("Memory snapshot failed", slog.String("error", err.Error()))
+			}
+		case <-r.stop:
+			return
+		}
+	}
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames it over path
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err = tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+
+	if err = tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// cloneOrder returns a deep copy of order, so callers never share stored state
+func cloneOrder(order *domain.Order) *domain.Order {
+	clone := *order
+	clone.Lines = slices.Clone(order.Lines)
+
+	if order.DeletedAt != nil {
+		deletedAt := *order.DeletedAt
+		clone.DeletedAt = &deletedAt
+	}
+
+	return &clone
+}
+
+func NewOrderMemoryRepository() (Store, error) {
+	return newOrderMemoryRepository(), nil
+}
+
+func newOrderMemoryRepository() *OrderMemoryRepository {
+	return &OrderMemoryRepository{memoryState: &memoryState{
+		orders:   make(map[int]*domain.Order),
+		history:  make(map[int][]domain.StatusTransition),
+		webhooks: make(map[int]*domain.Webhook),
+	}}
+}
+
+// OpenOrderMemoryRepository returns a memory repository restored from the snapshot
+// at path. With a positive interval the orders are saved there periodically; they
+// are always saved on Close.
+func OpenOrderMemoryRepository(path string, interval time.Duration) (*OrderMemoryRepository, error) {
+	r := newOrderMemoryRepository()
+	r.snapshotPath = path
+
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+
+	if interval > 0 {
+		r.stop = make(chan struct{})
+		r.done = make(chan struct{})
+
+		go r.snapshotLoop(interval)
+	}
+
+	return r, nil
+}
+
+// newConfiguredMemoryRepository builds the memory backend from db.snapshotPath and
+// db.snapshotInterval; without a snapshot path, or without a loaded configuration,
+// nothing is persisted
+func newConfiguredMemoryRepository() (Store, error) {
+	cfg, err := config.GetServiceConfig[*parameters.Service]()
+	if err != nil || cfg.Database.SnapshotPath == "" {
+		return NewOrderMemoryRepository()
+	}
+
+	return OpenOrderMemoryRepository(cfg.Database.SnapshotPath, cfg.Database.SnapshotInterval)
 }
 
 // NewMemoryRepository kept for backward compatibility in tests
-func NewMemoryRepository() (domain.OrderRepository, error) {
+func NewMemoryRepository() (Store, error) {
 	return NewOrderMemoryRepository()
 }
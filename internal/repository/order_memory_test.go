@@ -0,0 +1,173 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/dyammarcano/fullcycle_clean_architecture/internal/domain"
+)
+
+func TestMemoryRepositorySnapshot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "orders.json")
+
+	repository, err := OpenOrderMemoryRepository(path, time.Hour)
+	if err != nil {
+		t.Fatalf("Error opening repository: %v", err)
+	}
+
+	ctx := context.Background()
+
+	order := &domain.Order{Item: "Bag", Status: domain.StatusPending}
+	if _, err = repository.CreateOrder(ctx, order); err != nil {
+		t.Fatalf("Error creating order: %v", err)
+	}
+
+	deleted := &domain.Order{Item: "Hat", Status: domain.StatusPending}
+	if _, err = repository.CreateOrder(ctx, deleted); err != nil {
+		t.Fatalf("Error creating order: %v", err)
+	}
+
+	if _, err = repository.DeleteOrder(ctx, deleted.ID, 0); err != nil {
+		t.Fatalf("Error deleting order: %v", err)
+	}
+
+	transition := domain.StatusTransition{From: domain.StatusPending, To: domain.StatusConfirmed, At: time.Now().UTC()}
+	if err = repository.ChangeOrderStatus(ctx, order.ID, transition); err != nil {
+		t.Fatalf("Error changing status: %v", err)
+	}
+
+	if err = repository.Close(); err != nil {
+		t.Fatalf("Error closing repository: %v", err)
+	}
+
+	if repository, err = OpenOrderMemoryRepository(path, 0); err != nil {
+		t.Fatalf("Error reopening repository: %v", err)
+	}
+
+	got, err := repository.GetOrderByID(ctx, order.ID)
+	if err != nil || got.Status != domain.StatusConfirmed {
+		t.Errorf("Restored order = %+v (%v)", got, err)
+	}
+
+	if history, _ := repository.ListStatusHistory(ctx, order.ID); len(history) != 1 || !history[0].At.Equal(transition.At) {
+		t.Errorf("Restored history = %+v", history)
+	}
+
+	next, err := repository.CreateOrder(ctx, &domain.Order{Item: "Cap"})
+	if err != nil || next.ID != 3 {
+		t.Errorf("ID after restore = %+v (%v), want 3", next, err)
+	}
+}
+
+func TestMemoryRepositoryTransactRollback(t *testing.T) {
+	ctx := context.Background()
+	repository := newOrderMemoryRepository()
+
+	order, _ := repository.CreateOrder(ctx, &domain.Order{Item: "Bag", Status: domain.StatusPending})
+	webhook := &domain.Webhook{URL: "http://example.com", EventTypes: []domain.EventType{domain.EventOrderCreated}}
+	_ = repository.CreateWebhook(ctx, webhook)
+
+	event := domain.Event{Type: domain.EventOrderCreated, OrderID: order.ID, OccurredAt: now()}
+	_ = repository.AppendEvents(ctx, event)
+	_ = repository.EnqueueWebhookDeliveries(ctx, event, now())
+
+	state := func() string {
+		data, err := json.Marshal(memorySnapshot{
+			LastID: repository.lastID, Orders: slices.Collect(maps.Values(repository.orders)), History: repository.history,
+			LastEventID: repository.lastEventID, Events: repository.events,
+			LastWebhookID: repository.lastWebhookID, Webhooks: slices.Collect(maps.Values(repository.webhooks)),
+			LastDeliveryID: repository.lastDeliveryID, Deliveries: repository.deliveries,
+		})
+		if err != nil {
+			t.Fatalf("Error encoding state: %v", err)
+		}
+
+		return string(data)
+	}
+
+	before := state()
+	failure := errors.New("rolled back")
+
+	writes := func(tx domain.OrderRepository, outbox domain.EventOutbox) error {
+		// the undo log of the memory repository covers its webhooks too
+		webhooks := tx.(domain.WebhookRepository)
+
+		transition := domain.StatusTransition{From: domain.StatusPending, To: domain.StatusConfirmed, At: now()}
+		if err := tx.ChangeOrderStatus(ctx, order.ID, transition); err != nil {
+			return err
+		}
+
+		if err := webhooks.UpdateWebhook(ctx, &domain.Webhook{ID: webhook.ID, URL: "http://example.org"}); err != nil {
+			return err
+		}
+
+		claimed, err := webhooks.ClaimWebhookDeliveries(ctx, now(), now().Add(time.Minute), 10)
+		if err != nil || len(claimed) != 1 {
+			return fmt.Errorf("claimed %d deliveries: %w", len(claimed), err)
+		}
+
+		attempt := domain.WebhookAttempt{DeliveryID: claimed[0].ID, At: now(), StatusCode: 500}
+		if err = webhooks.RecordWebhookAttempt(ctx, attempt, domain.DeliveryDead, now()); err != nil {
+			return err
+		}
+
+		if err = outbox.MarkEventDelivered(ctx, 1); err != nil {
+			return err
+		}
+
+		return tx.Transact(ctx, func(nested domain.OrderRepository, nestedOutbox domain.EventOutbox) error {
+			created, err := nested.CreateOrder(ctx, &domain.Order{Item: "Hat"})
+			if err != nil {
+				return err
+			}
+
+			return nestedOutbox.AppendEvents(ctx, domain.Event{Type: domain.EventOrderCreated, OrderID: created.ID, OccurredAt: now()})
+		})
+	}
+
+	err := repository.Transact(ctx, func(tx domain.OrderRepository, outbox domain.EventOutbox) error {
+		if err := writes(tx, outbox); err != nil {
+			return err
+		}
+
+		return failure
+	})
+	if !errors.Is(err, failure) {
+		t.Fatalf("Transact = %v, want the error of fn", err)
+	}
+
+	if after := state(); after != before {
+		t.Errorf("State after a rollback = %s, want %s", after, before)
+	}
+
+	func() {
+		defer func() { _ = recover() }()
+
+		_ = repository.Transact(ctx, func(tx domain.OrderRepository, outbox domain.EventOutbox) error {
+			if err := writes(tx, outbox); err != nil {
+				return err
+			}
+
+			panic(failure)
+		})
+	}()
+
+	if after := state(); after != before {
+		t.Errorf("State after a panic = %s, want %s", after, before)
+	}
+
+	if err = repository.Transact(ctx, writes); err != nil {
+		t.Fatalf("Error committing transaction: %v", err)
+	}
+
+	if after := state(); after == before {
+		t.Error("State unchanged after a commit")
+	}
+}
@@ -3,189 +3,129 @@ package repository
 import (
 	"context"
 	"database/sql"
-	"errors"
-	"fmt"
 	"log"
-	"log/slog"
-	"time"
+	"strconv"
+	"strings"
 
 	"github.com/dyammarcano/fullcycle_clean_architecture/internal/domain"
 	"github.com/dyammarcano/fullcycle_clean_architecture/pkg/parameters"
-	"github.com/golang-migrate/migrate/v4"
-	"github.com/golang-migrate/migrate/v4/database/postgres"
-	_ "github.com/golang-migrate/migrate/v4/source/file"
 	"github.com/inovacc/config"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 type OrderPostgresRepository struct {
-	db *sql.DB
+	sqlRepository
 }
 
-func (r *OrderPostgresRepository) ListOrders() ([]*domain.Order, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+var postgresDialect = sqlDialect{
+	bindPrefix:   "$",
+	itemColumn:   `item COLLATE "C"`,
+	itemContains: `item COLLATE "C" ILIKE %s`,
+	skipLocked:   " FOR UPDATE SKIP LOCKED",
+	inIDs: func(column string, ids []int64) (string, []any) {
+		return column + " = ANY($1)", []any{pq.Array(ids)}
+	},
+	translateError: translatePostgresError,
+}
 
-	rows, err := r.db.QueryContext(ctx, "SELECT * FROM orders")
+func NewOrderPostgresRepository() (Store, error) {
+	cfg, err := config.GetServiceConfig[*parameters.Service]()
 	if err != nil {
-		return nil, err
-	}
-	defer func(rows *sql.Rows) {
-		if err := rows.Close(); err != nil {
-			slog.Error(">>> Error closing rows: ", slog.String("error", err.Error()))
-		}
-	}(rows)
-
-	orders := make([]*domain.Order, 0)
-	for rows.Next() {
-		var order domain.Order
-		var amountTmp float64
-
-		if err = rows.Scan(&order.ID, &order.Item, &amountTmp); err != nil {
-			return orders, err
-		}
-		order.Amount = float32(amountTmp)
-
-		orders = append(orders, &order)
+		log.Fatalf("Failed to get service config: %v", err)
 	}
 
-	return orders, nil
+	return OpenOrderPostgresRepository(cfg.Database)
 }
 
-func (r *OrderPostgresRepository) CreateOrder(order *domain.Order) (*domain.Order, error) {
-	if order == nil {
-		return nil, fmt.Errorf("invalid entity")
-	}
-
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	stmt, err := r.db.PrepareContext(ctx, `INSERT INTO orders(item, amount) VALUES($1, $2) RETURNING id`)
+// OpenOrderPostgresRepository connects to the database described by cfg. With
+// cfg.AutoMigrate the pending migrations are applied, otherwise a schema behind
+// the embedded migrations is an error.
+func OpenOrderPostgresRepository(cfg parameters.Database) (*OrderPostgresRepository, error) {
+	db, err := openPostgres(cfg)
 	if err != nil {
 		return nil, err
 	}
-	defer func(stmt *sql.Stmt) {
-		if err := stmt.Close(); err != nil {
-			slog.Error(">>> Error closing statement: ", slog.String("error", err.Error()))
-		}
-	}(stmt)
-
-	rows, err := stmt.Query(order.Item, order.Amount)
-	if err != nil {
-		return nil, err
-	}
-	defer func(rows *sql.Rows) {
-		if err := rows.Close(); err != nil {
-			slog.Error(">>> Error closing rows: ", slog.String("error", err.Error()))
-		}
-	}(rows)
 
-	for rows.Next() {
-		if err = rows.Scan(&order.ID); err != nil {
-			return nil, err
-		}
+	if !cfg.AutoMigrate {
+		err = checkSchemaVersion(DriverPostgres, db, cfg.GetQueryTimeout())
+	} else {
+		err = migrateUp(DriverPostgres, db)
 	}
 
-	return order, nil
-}
-
-func (r *OrderPostgresRepository) GetOrderByID(id int) (*domain.Order, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	row := r.db.QueryRowContext(ctx, "SELECT id, item, amount FROM orders WHERE id = $1", id)
-
-	order := &domain.Order{}
-	var amountTmp float64
-	if err := row.Scan(&order.ID, &order.Item, &amountTmp); err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, fmt.Errorf("order not found")
-		}
-
+	if err != nil {
+		_ = db.Close()
 		return nil, err
 	}
 
-	return order, nil
+	return &OrderPostgresRepository{sqlRepository{db: db, dialect: postgresDialect, queryTimeout: cfg.GetQueryTimeout()}}, nil
 }
 
-func (r *OrderPostgresRepository) UpdateOrder(id int, order *domain.Order) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	stmt, err := r.db.PrepareContext(ctx, `UPDATE orders SET item = $1, amount = $2 WHERE id = $3`)
-	if err != nil {
-		return err
+// postgresDataSourceName returns cfg.DSN or builds a key=value connection string
+// from the connection settings
+func postgresDataSourceName(cfg parameters.Database) string {
+	if cfg.DSN != "" {
+		return cfg.DSN
 	}
-	defer func(stmt *sql.Stmt) {
-		if err := stmt.Close(); err != nil {
-			slog.Error(">>> Error closing statement: ", slog.String("error", err.Error()))
-		}
-	}(stmt)
 
-	if _, err = stmt.Exec(order.Item, order.Amount, id); err != nil {
-		return err
+	var port, statementTimeout string
+	if cfg.Port > 0 {
+		port = strconv.Itoa(cfg.Port)
 	}
 
-	return nil
-}
-
-func (r *OrderPostgresRepository) DeleteOrder(id int) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	stmt, err := r.db.PrepareContext(ctx, `DELETE FROM orders WHERE id = $1`)
-	if err != nil {
-		return err
+	if cfg.StatementTimeout > 0 {
+		statementTimeout = strconv.FormatInt(cfg.StatementTimeout.Milliseconds(), 10)
 	}
-	defer func(stmt *sql.Stmt) {
-		if err := stmt.Close(); err != nil {
-			slog.Error(">>> Error closing statement: ", slog.String("error", err.Error()))
-		}
-	}(stmt)
 
-	if _, err = stmt.Exec(id); err != nil {
-		return err
+	params := []struct{ key, value string }{
+		{"host", cfg.Host},
+		{"port", port},
+		{"user", cfg.User},
+		{"password", cfg.Password},
+		{"dbname", cfg.GetDBName()},
+		{"sslmode", cfg.GetSSLMode()},
+		{"sslrootcert", cfg.SSLRootCert},
+		{"sslcert", cfg.SSLCert},
+		{"sslkey", cfg.SSLKey},
+		{"application_name", cfg.ApplicationName},
+		// Unknown keys are sent by lib/pq as run-time parameters
+		{"statement_timeout", statementTimeout},
 	}
 
-	return nil
-}
-
-func NewOrderPostgresRepository() (domain.OrderRepository, error) {
-	cfg, err := config.GetServiceConfig[*parameters.Service]()
-	if err != nil {
-		log.Fatalf("Failed to get service config: %v", err)
+	parts := make([]string, 0, len(params))
+	for _, p := range params {
+		if p.value != "" {
+			parts = append(parts, p.key+"='"+dsnEscaper.Replace(p.value)+"'")
+		}
 	}
 
-	dataSourceName := fmt.Sprintf("user=%s dbname=%s password=%s host=%s port=%d sslmode=%s",
-		cfg.Database.User, cfg.Database.Name, cfg.Database.Password, cfg.Database.Host, cfg.Database.Port, "disable")
+	return strings.Join(parts, " ")
+}
 
-	// Open the database connection
+// dsnEscaper quotes values of key=value connection strings
+var dsnEscaper = strings.NewReplacer(`\`, `\\`, `'`, `\'`)
 
-	db, err := sql.Open("postgres", dataSourceName)
+// openPostgres opens a pool sized by cfg and checks the database is reachable
+func openPostgres(cfg parameters.Database) (*sql.DB, error) {
+	db, err := sql.Open("postgres", postgresDataSourceName(cfg))
 	if err != nil {
 		return nil, err
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
 
-	if err = db.PingContext(ctx); err != nil {
-		return nil, err
+	if cfg.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(cfg.MaxIdleConns)
 	}
 
-	driver, err := postgres.WithInstance(db, &postgres.Config{})
-	if err != nil {
-		return nil, err
-	}
-
-	m, err := migrate.NewWithDatabaseInstance("file://internal/repository/migrations", "postgres", driver)
-	if err != nil {
-		return nil, err
-	}
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.GetQueryTimeout())
+	defer cancel()
 
-	if err = m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
-		return nil, err
+	if err = db.PingContext(ctx); err != nil {
+		_ = db.Close()
+		return nil, domain.WrapError(domain.ErrUnavailable, "database unavailable", err)
 	}
 
-	return &OrderPostgresRepository{db: db}, nil
+	return db, nil
 }
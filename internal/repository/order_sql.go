@@ -0,0 +1,657 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dyammarcano/fullcycle_clean_architecture/internal/domain"
+)
+
+// sqlDialect holds what differs between the SQL databases. The queries of the
+// package are written with $n bind parameters and rebound to the dialect.
+type sqlDialect struct {
+	// bindPrefix precedes the number of a bind parameter
+	bindPrefix string
+	// itemColumn sorts items byte-wise, as the memory repository does
+	itemColumn string
+	// itemContains matches a LIKE pattern against the item ignoring the case of
+	// ASCII letters only
+	itemContains string
+	// skipLocked ends the SELECT of a claim, letting concurrent claims take disjoint rows
+	skipLocked string
+	// inIDs returns the condition and arguments matching column against ids
+	inIDs func(column string, ids []int64) (string, []any)
+	// translateError maps driver errors onto the domain error kinds
+	translateError func(ctx context.Context, err error) error
+}
+
+// bind returns the n-th bind parameter, starting at 1
+func (d sqlDialect) bind(n int) string {
+	return d.bindPrefix + strconv.Itoa(n)
+}
+
+// bindParam matches the $n bind parameters of a query
+var bindParam = regexp.MustCompile(`\$(\d+)`)
+
+// rebind rewrites the $n bind parameters of query for the dialect
+func (d sqlDialect) rebind(query string) string {
+	if d.bindPrefix == "$" {
+		return query
+	}
+
+	return bindParam.ReplaceAllString(query, d.bindPrefix+"${1}")
+}
+
+// buildListOrdersQuery returns the keyset paginated SELECT for query. One row more
+// than the page size is fetched to learn whether another page follows.
+func buildListOrdersQuery(dialect sqlDialect, query domain.ListOrdersQuery) (string, []any) {
+	var (
+		where []string
+		args  []any
+	)
+
+	arg := func(v any) string {
+		args = append(args, v)
+		return dialect.bind(len(args))
+	}
+
+	if query.Filter.Deleted {
+		where = append(where, "deleted_at IS NOT NULL")
+	} else {
+		where = append(where, "deleted_at IS NULL")
+	}
+
+	if query.Filter.ItemContains != "" {
+		where = append(where, fmt.Sprintf(dialect.itemContains, arg("%"+likeEscaper.Replace(query.Filter.ItemContains)+"%")))
+	}
+
+	if minAmount := query.Filter.MinAmount; minAmount != nil {
+		where = append(where, "currency = "+arg(minAmount.Currency), "amount_units >= "+arg(minAmount.Units))
+	}
+
+	if maxAmount := query.Filter.MaxAmount; maxAmount != nil {
+		where = append(where, "currency = "+arg(maxAmount.Currency), "amount_units <= "+arg(maxAmount.Units))
+	}
+
+	column := "id"
+	switch query.SortBy {
+	case domain.SortByItem:
+		column = dialect.itemColumn
+	case domain.SortByAmount:
+		column = "amount_units"
+	}
+
+	direction, op := "ASC", ">"
+	if query.Descending {
+		direction, op = "DESC", "<"
+	}
+
+	if after := query.After; after != nil {
+		switch query.SortBy {
+		case domain.SortByItem:
+			where = append(where, fmt.Sprintf("(%s, id) %s (%s, %s)", column, op, arg(after.Item), arg(after.ID)))
+		case domain.SortByAmount:
+			where = append(where, fmt.Sprintf("(%s, id) %s (%s, %s)", column, op, arg(after.Units), arg(after.ID)))
+		default:
+			where = append(where, fmt.Sprintf("id %s %s", op, arg(after.ID)))
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString("SELECT " + orderColumns + " FROM orders WHERE " + strings.Join(where, " AND "))
+
+	if column == "id" {
+		sb.WriteString(" ORDER BY id " + direction)
+	} else {
+		sb.WriteString(fmt.Sprintf(" ORDER BY %s %s, id %s", column, direction, direction))
+	}
+
+	if query.PageSize > 0 {
+		sb.WriteString(" LIMIT " + arg(query.PageSize+1))
+	}
+
+	return sb.String(), args
+}
+
+// orderColumns are the orders columns read by scanOrder, in its order
+const orderColumns = "id, item, status, amount_units, currency, version, created_at, updated_at, deleted_at"
+
+// rowScanner is implemented by *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// sqlConn is implemented by both *sql.DB and *sql.Tx
+type sqlConn interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+}
+
+// dialectConn rebinds the queries it runs to its dialect
+type dialectConn struct {
+	conn    sqlConn
+	dialect sqlDialect
+}
+
+func (c dialectConn) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return c.conn.ExecContext(ctx, c.dialect.rebind(query), args...)
+}
+
+func (c dialectConn) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return c.conn.QueryContext(ctx, c.dialect.rebind(query), args...)
+}
+
+func (c dialectConn) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return c.conn.QueryRowContext(ctx, c.dialect.rebind(query), args...)
+}
+
+func (c dialectConn) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return c.conn.PrepareContext(ctx, c.dialect.rebind(query))
+}
+
+// scanOrder reads the orderColumns of one row
+func scanOrder(row rowScanner) (*domain.Order, error) {
+	var (
+		order     domain.Order
+		deletedAt sql.NullTime
+	)
+
+	if err := row.Scan(&order.ID, &order.Item, &order.Status, &order.Amount.Units, &order.Amount.Currency,
+		&order.Version, &order.CreatedAt, &order.UpdatedAt, &deletedAt); err != nil {
+		return nil, err
+	}
+
+	order.CreatedAt = order.CreatedAt.UTC()
+	order.UpdatedAt = order.UpdatedAt.UTC()
+
+	if deletedAt.Valid {
+		at := deletedAt.Time.UTC()
+		order.DeletedAt = &at
+	}
+
+	return &order, nil
+}
+
+// now returns the current time at the microsecond precision every backend keeps
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
+
+// likeEscaper escapes the LIKE wildcards so filters match them literally
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// pageOf trims the extra row fetched by buildListOrdersQuery
+func pageOf(orders []*domain.Order, pageSize int) *domain.OrderPage {
+	page := &domain.OrderPage{Orders: orders}
+	if pageSize > 0 && len(orders) > pageSize {
+		page.Orders = orders[:pageSize]
+		page.HasMore = true
+	}
+
+	return page
+}
+
+// sqlRepository implements the repository on a database/sql pool, leaving what
+// differs between the databases to its dialect
+type sqlRepository struct {
+	db *sql.DB
+	// tx is set on the repositories handed to Transact callbacks
+	tx           *sql.Tx
+	dialect      sqlDialect
+	queryTimeout time.Duration
+}
+
+func (r *sqlRepository) ListOrders(ctx context.Context, query domain.ListOrdersQuery) (*domain.OrderPage, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	stmt, args := buildListOrdersQuery(r.dialect, query)
+
+	rows, err := r.conn().QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return nil, r.translateError(ctx, err)
+	}
+	defer closeRows(rows)
+
+	orders := make([]*domain.Order, 0)
+	for rows.Next() {
+		order, err := scanOrder(rows)
+		if err != nil {
+			return nil, r.translateError(ctx, err)
+		}
+
+		orders = append(orders, order)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, r.translateError(ctx, err)
+	}
+
+	page := pageOf(orders, query.PageSize)
+	if err = r.loadLines(ctx, r.conn(), page.Orders); err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
+
+func (r *sqlRepository) CreateOrder(ctx context.Context, order *domain.Order) (*domain.Order, error) {
+	if order == nil {
+		return nil, domain.ErrInvalidOrder
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	createdAt := now()
+
+	err := r.withTx(ctx, func(tx sqlConn) error {
+		row := tx.QueryRowContext(ctx,
+			`INSERT INTO orders(item, status, amount_units, currency, created_at, updated_at)
+			VALUES($1, $2, $3, $4, $5, $5) RETURNING id, version`,
+			order.Item, order.Status, order.Amount.Units, order.Amount.Currency, createdAt)
+		if err := row.Scan(&order.ID, &order.Version); err != nil {
+			return r.translateError(ctx, err)
+		}
+
+		return r.insertLines(ctx, tx, order.ID, order.Lines)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	order.CreatedAt, order.UpdatedAt, order.DeletedAt = createdAt, createdAt, nil
+
+	return order, nil
+}
+
+func (r *sqlRepository) GetOrderByID(ctx context.Context, id int) (*domain.Order, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	row := r.conn().QueryRowContext(ctx, "SELECT "+orderColumns+" FROM orders WHERE id = $1 AND deleted_at IS NULL", id)
+
+	order, err := scanOrder(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrOrderNotFound
+		}
+
+		return nil, r.translateError(ctx, err)
+	}
+
+	if err = r.loadLines(ctx, r.conn(), []*domain.Order{order}); err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+
+func (r *sqlRepository) UpdateOrder(ctx context.Context, id int, order *domain.Order) error {
+	if order == nil {
+		return domain.ErrInvalidOrder
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	return r.withTx(ctx, func(tx sqlConn) error {
+		result, err := tx.ExecContext(ctx,
+			`UPDATE orders SET item = $1, amount_units = $2, currency = $3, updated_at = $4, version = version + 1
+			WHERE id = $5 AND deleted_at IS NULL AND ($6 = 0 OR version = $6)`,
+			order.Item, order.Amount.Units, order.Amount.Currency, now(), id, order.Version)
+		if err != nil {
+			return r.translateError(ctx, err)
+		}
+
+		if err = r.checkVersioned(ctx, tx, result, id); err != nil {
+			return err
+		}
+
+		if _, err = tx.ExecContext(ctx, `DELETE FROM order_lines WHERE order_id = $1`, id); err != nil {
+			return r.translateError(ctx, err)
+		}
+
+		return r.insertLines(ctx, tx, id, order.Lines)
+	})
+}
+
+func (r *sqlRepository) DeleteOrder(ctx context.Context, id int, version int) (*domain.Order, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	var deleted *domain.Order
+
+	err := r.withTx(ctx, func(tx sqlConn) error {
+		row := tx.QueryRowContext(ctx,
+			`UPDATE orders SET deleted_at = $1, updated_at = $1, version = version + 1
+			WHERE id = $2 AND deleted_at IS NULL AND ($3 = 0 OR version = $3) RETURNING `+orderColumns, now(), id, version)
+
+		order, err := scanOrder(row)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return r.versionConflict(ctx, tx, id)
+			}
+
+			return r.translateError(ctx, err)
+		}
+
+		deleted = order
+
+		return r.loadLines(ctx, tx, []*domain.Order{order})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return deleted, nil
+}
+
+func (r *sqlRepository) RestoreOrder(ctx context.Context, id int) error {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	result, err := r.conn().ExecContext(ctx,
+		`UPDATE orders SET deleted_at = NULL, updated_at = $1, version = version + 1 WHERE id = $2 AND deleted_at IS NOT NULL`, now(), id)
+	if err != nil {
+		return r.translateError(ctx, err)
+	}
+
+	return r.checkAffected(ctx, result)
+}
+
+func (r *sqlRepository) PurgeDeletedOrders(ctx context.Context, deletedBefore time.Time) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	// Lines and history go with the order through ON DELETE CASCADE
+	result, err := r.conn().ExecContext(ctx, `DELETE FROM orders WHERE deleted_at < $1`, deletedBefore.UTC())
+	if err != nil {
+		return 0, r.translateError(ctx, err)
+	}
+
+	purged, err := result.RowsAffected()
+	if err != nil {
+		return 0, r.translateError(ctx, err)
+	}
+
+	return int(purged), nil
+}
+
+func (r *sqlRepository) ChangeOrderStatus(ctx context.Context, id int, transition domain.StatusTransition) error {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	return r.withTx(ctx, func(tx sqlConn) error {
+		result, err := tx.ExecContext(ctx,
+			`UPDATE orders SET status = $1, updated_at = $2, version = version + 1 WHERE id = $3 AND status = $4 AND deleted_at IS NULL`,
+			transition.To, now(), id, transition.From)
+		if err != nil {
+			return r.translateError(ctx, err)
+		}
+
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return r.translateError(ctx, err)
+		}
+
+		if affected == 0 {
+			exists, err := r.orderExists(ctx, tx, id)
+			if err != nil {
+				return err
+			}
+
+			if !exists {
+				return domain.ErrOrderNotFound
+			}
+
+			return domain.ErrOrderStatusChanged
+		}
+
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO order_status_history(order_id, from_status, to_status, reason, changed_at) VALUES($1, $2, $3, $4, $5)`,
+			id, transition.From, transition.To, transition.Reason, transition.At.UTC())
+
+		return r.translateError(ctx, err)
+	})
+}
+
+func (r *sqlRepository) ListStatusHistory(ctx context.Context, id int) ([]domain.StatusTransition, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	exists, err := r.orderExists(ctx, r.conn(), id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !exists {
+		return nil, domain.ErrOrderNotFound
+	}
+
+	rows, err := r.conn().QueryContext(ctx,
+		`SELECT from_status, to_status, reason, changed_at FROM order_status_history WHERE order_id = $1 ORDER BY id`, id)
+	if err != nil {
+		return nil, r.translateError(ctx, err)
+	}
+	defer closeRows(rows)
+
+	history := make([]domain.StatusTransition, 0)
+	for rows.Next() {
+		var transition domain.StatusTransition
+
+		if err = rows.Scan(&transition.From, &transition.To, &transition.Reason, &transition.At); err != nil {
+			return nil, r.translateError(ctx, err)
+		}
+
+		history = append(history, transition)
+	}
+
+	return history, r.translateError(ctx, rows.Err())
+}
+
+// Transact runs fn inside a database transaction. Nested calls join the running one.
+func (r *sqlRepository) Transact(ctx context.Context, fn func(repo domain.OrderRepository, outbox domain.EventOutbox) error) error {
+	if r.tx != nil {
+		return fn(r, r)
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return r.translateError(ctx, err)
+	}
+
+	txRepo := &sqlRepository{db: r.db, tx: tx, dialect: r.dialect, queryTimeout: r.queryTimeout}
+
+	return r.commit(ctx, tx, fn(txRepo, txRepo))
+}
+
+// Close closes the database, waiting for running queries to finish
+func (r *sqlRepository) Close() error {
+	if r.tx != nil {
+		return nil
+	}
+
+	return r.db.Close()
+}
+
+// Stats returns the connection pool statistics
+func (r *sqlRepository) Stats() sql.DBStats {
+	return r.db.Stats()
+}
+
+// conn returns the transaction of the repository, if any, or the pool
+func (r *sqlRepository) conn() sqlConn {
+	if r.tx != nil {
+		return dialectConn{conn: r.tx, dialect: r.dialect}
+	}
+
+	return dialectConn{conn: r.db, dialect: r.dialect}
+}
+
+// withTx runs fn inside a transaction, committing on success and rolling back
+// otherwise. Inside Transact fn joins the running transaction.
+func (r *sqlRepository) withTx(ctx context.Context, fn func(tx sqlConn) error) error {
+	if r.tx != nil {
+		return fn(r.conn())
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return r.translateError(ctx, err)
+	}
+
+	return r.commit(ctx, tx, fn(dialectConn{conn: tx, dialect: r.dialect}))
+}
+
+// commit commits tx when err is nil and rolls it back otherwise
+func (r *sqlRepository) commit(ctx context.Context, tx *sql.Tx, err error) error {
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			slog.Error(">>> Error rolling back transaction: ", slog.String("error", rbErr.Error()))
+		}
+
+		return err
+	}
+
+	return r.translateError(ctx, tx.Commit())
+}
+
+// translateError maps driver errors onto the domain error kinds
+func (r *sqlRepository) translateError(ctx context.Context, err error) error {
+	return r.dialect.translateError(ctx, err)
+}
+
+// loadLines fills the lines of every order with a single query
+func (r *sqlRepository) loadLines(ctx context.Context, conn sqlConn, orders []*domain.Order) error {
+	if len(orders) == 0 {
+		return nil
+	}
+
+	byID := make(map[int]*domain.Order, len(orders))
+	ids := make([]int64, 0, len(orders))
+
+	for _, order := range orders {
+		order.Lines = make([]domain.OrderLine, 0)
+		byID[order.ID] = order
+		ids = append(ids, int64(order.ID))
+	}
+
+	in, args := r.dialect.inIDs("order_id", ids)
+
+	rows, err := conn.QueryContext(ctx,
+		`SELECT order_id, sku, description, quantity, unit_price_units, currency
+		FROM order_lines WHERE `+in+` ORDER BY order_id, line_no`, args...)
+	if err != nil {
+		return r.translateError(ctx, err)
+	}
+	defer closeRows(rows)
+
+	for rows.Next() {
+		var orderID int
+		var line domain.OrderLine
+
+		if err = rows.Scan(&orderID, &line.SKU, &line.Description, &line.Quantity,
+			&line.UnitPrice.Units, &line.UnitPrice.Currency); err != nil {
+			return r.translateError(ctx, err)
+		}
+
+		if order, ok := byID[orderID]; ok {
+			order.Lines = append(order.Lines, line)
+		}
+	}
+
+	return r.translateError(ctx, rows.Err())
+}
+
+// insertLines stores the lines of an order, numbering them in order
+func (r *sqlRepository) insertLines(ctx context.Context, tx sqlConn, orderID int, lines []domain.OrderLine) error {
+	stmt, err := tx.PrepareContext(ctx,
+		`INSERT INTO order_lines(order_id, line_no, sku, description, quantity, unit_price_units, currency)
+		VALUES($1, $2, $3, $4, $5, $6, $7)`)
+	if err != nil {
+		return r.translateError(ctx, err)
+	}
+	defer func(stmt *sql.Stmt) {
+		if err := stmt.Close(); err != nil {
+			slog.Error(">>> Error closing statement: ", slog.String("error", err.Error()))
+		}
+	}(stmt)
+
+	for i, line := range lines {
+		if _, err = stmt.ExecContext(ctx, orderID, i+1, line.SKU, line.Description, line.Quantity,
+			line.UnitPrice.Units, line.UnitPrice.Currency); err != nil {
+			return r.translateError(ctx, err)
+		}
+	}
+
+	return nil
+}
+
+// orderExists reports whether the order exists and is not soft-deleted
+func (r *sqlRepository) orderExists(ctx context.Context, conn sqlConn, id int) (bool, error) {
+	var exists bool
+	if err := conn.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM orders WHERE id = $1 AND deleted_at IS NULL)`, id).Scan(&exists); err != nil {
+		return false, r.translateError(ctx, err)
+	}
+
+	return exists, nil
+}
+
+// checkAffected reports a missing order when a write matched no rows
+func (r *sqlRepository) checkAffected(ctx context.Context, result sql.Result) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return r.translateError(ctx, err)
+	}
+
+	if affected == 0 {
+		return domain.ErrOrderNotFound
+	}
+
+	return nil
+}
+
+// checkVersioned reports why a versioned write matched no rows: the order is
+// missing or soft-deleted, or its version changed
+func (r *sqlRepository) checkVersioned(ctx context.Context, conn sqlConn, result sql.Result, id int) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return r.translateError(ctx, err)
+	}
+
+	if affected > 0 {
+		return nil
+	}
+
+	return r.versionConflict(ctx, conn, id)
+}
+
+// versionConflict returns why a versioned write of the order matched no rows
+func (r *sqlRepository) versionConflict(ctx context.Context, conn sqlConn, id int) error {
+	exists, err := r.orderExists(ctx, conn, id)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		return domain.ErrOrderNotFound
+	}
+
+	return domain.ErrOrderVersionMismatch
+}
+
+func closeRows(rows *sql.Rows) {
+	if err := rows.Close(); err != nil {
+		slog.Error(">>> Error closing rows: ", slog.String("error", err.Error()))
+	}
+}
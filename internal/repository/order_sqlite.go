@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dyammarcano/fullcycle_clean_architecture/internal/domain"
+	"github.com/dyammarcano/fullcycle_clean_architecture/pkg/parameters"
+	"github.com/inovacc/config"
+	_ "modernc.org/sqlite"
+)
+
+// DefaultSQLitePath is the database file used when db.path is not set
+const DefaultSQLitePath = "orders.db"
+
+// OrderSQLiteRepository stores orders in a single SQLite file through a pure Go
+// driver. Writes are serialized on one connection, which is what SQLite allows anyway.
+type OrderSQLiteRepository struct {
+	sqlRepository
+}
+
+var sqliteDialect = sqlDialect{
+	bindPrefix:   "?",
+	itemColumn:   "item",
+	itemContains: `item LIKE %s ESCAPE '\'`,
+	// The single connection serializes claims, so no row locking is needed
+	skipLocked: "",
+	inIDs: func(column string, ids []int64) (string, []any) {
+		binds := make([]string, len(ids))
+		args := make([]any, len(ids))
+
+		for i, id := range ids {
+			binds[i], args[i] = "$"+strconv.Itoa(i+1), id
+		}
+
+		return column + " IN (" + strings.Join(binds, ", ") + ")", args
+	},
+	translateError: translateSQLiteError,
+}
+
+func NewOrderSQLiteRepository() (Store, error) {
+	cfg, err := config.GetServiceConfig[*parameters.Service]()
+	if err != nil {
+		log.Fatalf("Failed to get service config: %v", err)
+	}
+
+	return OpenOrderSQLiteRepository(sqlitePath(cfg.Database), cfg.Database.GetQueryTimeout(), cfg.Database.AutoMigrate)
+}
+
+// OpenOrderSQLiteRepository opens, creating it when missing, the database file at
+// path. With autoMigrate the pending migrations are applied, otherwise a schema
+// behind the embedded migrations is an error.
+func OpenOrderSQLiteRepository(path string, queryTimeout time.Duration, autoMigrate bool) (*OrderSQLiteRepository, error) {
+	db, err := openSQLite(path, queryTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	if !autoMigrate {
+		err = checkSchemaVersion(DriverSQLite, db, queryTimeout)
+	} else {
+		err = migrateUp(DriverSQLite, db)
+	}
+
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	return &OrderSQLiteRepository{sqlRepository{db: db, dialect: sqliteDialect, queryTimeout: queryTimeout}}, nil
+}
+
+// sqlitePath returns db.path or DefaultSQLitePath
+func sqlitePath(cfg parameters.Database) string {
+	if cfg.Path == "" {
+		return DefaultSQLitePath
+	}
+
+	return cfg.Path
+}
+
+// openSQLite opens the database file at path on a single connection
+func openSQLite(path string, queryTimeout time.Duration) (*sql.DB, error) {
+	dsn := "file:" + path + "?" + url.Values{
+		"_pragma": {"foreign_keys(1)", "busy_timeout(5000)", "journal_mode(WAL)"},
+	}.Encode()
+
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	db.SetMaxOpenConns(1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), queryTimeout)
+	defer cancel()
+
+	if err = db.PingContext(ctx); err != nil {
+		_ = db.Close()
+		return nil, domain.WrapError(domain.ErrUnavailable, "database unavailable", err)
+	}
+
+	return db, nil
+}
@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dyammarcano/fullcycle_clean_architecture/internal/domain"
+)
+
+func TestOrderSQLiteRepository(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "orders.db")
+
+	repo, err := OpenOrderSQLiteRepository(path, 5*time.Second, true)
+	if err != nil {
+		t.Fatalf("Error opening repository: %v", err)
+	}
+
+	ctx := context.Background()
+
+	order := &domain.Order{
+		Item:   "Bag",
+		Status: domain.StatusPending,
+		Lines: []domain.OrderLine{
+			{SKU: "BAG-1", Quantity: 2, UnitPrice: domain.Money{Units: 150, Currency: "EUR"}},
+			{SKU: "STRAP-1", Description: "Strap", Quantity: 1, UnitPrice: domain.Money{Units: 50, Currency: "EUR"}},
+		},
+		Amount: domain.Money{Units: 350, Currency: "EUR"},
+	}
+
+	if _, err = repo.CreateOrder(ctx, order); err != nil {
+		t.Fatalf("Error creating order: %v", err)
+	}
+
+	at := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err = repo.ChangeOrderStatus(ctx, order.ID, domain.StatusTransition{From: domain.StatusPending, To: domain.StatusConfirmed, Reason: "ok", At: at}); err != nil {
+		t.Fatalf("Error changing status: %v", err)
+	}
+
+	if err = repo.ChangeOrderStatus(ctx, order.ID, domain.StatusTransition{From: domain.StatusPending, To: domain.StatusCancelled, At: at}); !errors.Is(err, domain.ErrOrderStatusChanged) {
+		t.Errorf("Expected a status conflict, got %v", err)
+	}
+
+	if err = repo.Close(); err != nil {
+		t.Fatalf("Error closing repository: %v", err)
+	}
+
+	// Reopening the file must keep the data and skip the applied migrations
+	if repo, err = OpenOrderSQLiteRepository(path, 5*time.Second, true); err != nil {
+		t.Fatalf("Error reopening repository: %v", err)
+	}
+	defer func() { _ = repo.Close() }()
+
+	got, err := repo.GetOrderByID(ctx, order.ID)
+	if err != nil {
+		t.Fatalf("Error getting order: %v", err)
+	}
+
+	if got.Status != domain.StatusConfirmed || len(got.Lines) != 2 || got.Lines[1].Description != "Strap" || got.Amount != order.Amount {
+		t.Errorf("Unexpected order %+v", got)
+	}
+
+	history, err := repo.ListStatusHistory(ctx, order.ID)
+	if err != nil || len(history) != 1 || !history[0].At.Equal(at) {
+		t.Errorf("Unexpected history %+v (%v)", history, err)
+	}
+
+	second := &domain.Order{Item: "Hat", Status: domain.StatusPending, Amount: domain.Money{Units: 100, Currency: "EUR"}}
+	if _, err = repo.CreateOrder(ctx, second); err != nil {
+		t.Fatalf("Error creating order: %v", err)
+	}
+
+	page, err := repo.ListOrders(ctx, domain.ListOrdersQuery{SortBy: domain.SortByAmount, PageSize: 1})
+	if err != nil || len(page.Orders) != 1 || page.Orders[0].ID != second.ID || !page.HasMore {
+		t.Fatalf("Unexpected first page %+v (%v)", page, err)
+	}
+
+	after := domain.CursorFor(page.Orders[0], domain.SortByAmount)
+	page, err = repo.ListOrders(ctx, domain.ListOrdersQuery{SortBy: domain.SortByAmount, PageSize: 1, After: &after})
+	if err != nil || len(page.Orders) != 1 || page.Orders[0].ID != order.ID || page.HasMore {
+		t.Errorf("Unexpected second page %+v (%v)", page, err)
+	}
+
+	page, err = repo.ListOrders(ctx, domain.ListOrdersQuery{Filter: domain.OrderFilter{ItemContains: "HA"}, PageSize: 10})
+	if err != nil || len(page.Orders) != 1 || page.Orders[0].Item != "Hat" {
+		t.Errorf("Unexpected filtered page %+v (%v)", page, err)
+	}
+
+	second.Item = "Cap"
+	if err = repo.UpdateOrder(ctx, second.ID, second); err != nil {
+		t.Errorf("Error updating order: %v", err)
+	}
+
+	if _, err = repo.DeleteOrder(ctx, order.ID, 0); err != nil {
+		t.Errorf("Error deleting order: %v", err)
+	}
+
+	if _, err = repo.GetOrderByID(ctx, order.ID); !errors.Is(err, domain.ErrOrderNotFound) {
+		t.Errorf("Expected not found after delete, got %v", err)
+	}
+
+	if _, err = repo.DeleteOrder(ctx, order.ID, 0); !errors.Is(err, domain.ErrOrderNotFound) {
+		t.Errorf("Expected not found deleting twice, got %v", err)
+	}
+}
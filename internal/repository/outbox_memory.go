@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/dyammarcano/fullcycle_clean_architecture/internal/domain"
+)
+
+// memoryEvent is an outbox entry of the memory repository
+type memoryEvent struct {
+	domain.PendingEvent
+	NextAttemptAt time.Time `json:"nextAttemptAt"`
+	LastError     string    `json:"lastError,omitempty"`
+}
+
+func (r *OrderMemoryRepository) AppendEvents(_ context.Context, events ...domain.Event) error {
+	r.lock()
+	defer r.unlock()
+
+	r.saveEventCount()
+
+	for i := range events {
+		r.lastEventID++
+		events[i].ID = r.lastEventID
+		r.events = append(r.events, memoryEvent{
+			PendingEvent:  domain.PendingEvent{Event: events[i]},
+			NextAttemptAt: events[i].OccurredAt,
+		})
+	}
+
+	if len(events) > 0 {
+		r.dirty = true
+	}
+
+	return nil
+}
+
+func (r *OrderMemoryRepository) ClaimEvents(_ context.Context, now, leaseUntil time.Time, limit int) ([]domain.PendingEvent, error) {
+	r.lock()
+	defer r.unlock()
+
+	claimed := make([]domain.PendingEvent, 0)
+
+	for i := range r.events {
+		if len(claimed) == limit {
+			break
+		}
+
+		if event := &r.events[i]; !event.NextAttemptAt.After(now) {
+			r.saveEvent(i)
+			event.NextAttemptAt = leaseUntil
+			claimed = append(claimed, event.PendingEvent)
+		}
+	}
+
+	return claimed, nil
+}
+
+func (r *OrderMemoryRepository) MarkEventDelivered(_ context.Context, id int64) error {
+	r.lock()
+	defer r.unlock()
+
+	r.saveEvents()
+	r.events = slices.DeleteFunc(r.events, func(event memoryEvent) bool { return event.ID == id })
+	r.dirty = true
+
+	return nil
+}
+
+func (r *OrderMemoryRepository) MarkEventFailed(_ context.Context, id int64, retryAt time.Time, lastError string) error {
+	r.lock()
+	defer r.unlock()
+
+	for i := range r.events {
+		if event := &r.events[i]; event.ID == id {
+			r.saveEvent(i)
+			event.Attempts++
+			event.NextAttemptAt, event.LastError = retryAt, lastError
+			r.dirty = true
+		}
+	}
+
+	return nil
+}
+
+// saveEventCount logs how to drop the events appended to the outbox. The caller
+// holds the lock.
+func (r *OrderMemoryRepository) saveEventCount() {
+	if r.undo == nil {
+		return
+	}
+
+	n := len(r.events)
+	*r.undo = append(*r.undo, func() { r.events = r.events[:n] })
+}
+
+// saveEvent logs how to restore the i-th event of the outbox. The caller holds
+// the lock.
+func (r *OrderMemoryRepository) saveEvent(i int) {
+	if r.undo == nil {
+		return
+	}
+
+	event := r.events[i]
+	*r.undo = append(*r.undo, func() { r.events[i] = event })
+}
+
+// saveEvents logs how to restore the whole outbox. The caller holds the lock.
+func (r *OrderMemoryRepository) saveEvents() {
+	if r.undo == nil {
+		return
+	}
+
+	events := slices.Clone(r.events)
+	*r.undo = append(*r.undo, func() { r.events = events })
+}
@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"slices"
+	"time"
+
+	"github.com/dyammarcano/fullcycle_clean_architecture/internal/domain"
+)
+
+// outboxColumns are the outbox columns read by scanPendingEvent, in its order
+const outboxColumns = "id, event_type, order_id, order_version, occurred_at, data, attempts"
+
+// scanPendingEvent reads the outboxColumns of one row
+func scanPendingEvent(row rowScanner) (domain.PendingEvent, error) {
+	var (
+		event domain.PendingEvent
+		data  string
+	)
+
+	if err := row.Scan(&event.ID, &event.Type, &event.OrderID, &event.OrderVersion, &event.OccurredAt,
+		&data, &event.Attempts); err != nil {
+		return event, err
+	}
+
+	event.OccurredAt = event.OccurredAt.UTC()
+	event.Data = json.RawMessage(data)
+
+	return event, nil
+}
+
+func (r *sqlRepository) AppendEvents(ctx context.Context, events ...domain.Event) error {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	return r.withTx(ctx, func(tx sqlConn) error {
+		for i, event := range events {
+			row := tx.QueryRowContext(ctx,
+				`INSERT INTO outbox(event_type, order_id, order_version, occurred_at, data, next_attempt_at)
+				VALUES($1, $2, $3, $4, $5, $4) RETURNING id`,
+				event.Type, event.OrderID, event.OrderVersion, event.OccurredAt.UTC(), string(event.Data))
+			if err := row.Scan(&events[i].ID); err != nil {
+				return r.translateError(ctx, err)
+			}
+		}
+
+		return nil
+	})
+}
+
+func (r *sqlRepository) ClaimEvents(ctx context.Context, now, leaseUntil time.Time, limit int) ([]domain.PendingEvent, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	// Where the dialect supports it, SKIP LOCKED lets several relays claim disjoint
+	// batches concurrently
+	rows, err := r.conn().QueryContext(ctx,
+		`UPDATE outbox SET next_attempt_at = $1
+		WHERE id IN (SELECT id FROM outbox WHERE next_attempt_at <= $2 ORDER BY id LIMIT $3`+r.dialect.skipLocked+`)
+		RETURNING `+outboxColumns, leaseUntil.UTC(), now.UTC(), limit)
+	if err != nil {
+		return nil, r.translateError(ctx, err)
+	}
+	defer closeRows(rows)
+
+	events := make([]domain.PendingEvent, 0)
+	for rows.Next() {
+		event, err := scanPendingEvent(rows)
+		if err != nil {
+			return nil, r.translateError(ctx, err)
+		}
+
+		events = append(events, event)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, r.translateError(ctx, err)
+	}
+
+	slices.SortFunc(events, func(a, b domain.PendingEvent) int { return cmp.Compare(a.ID, b.ID) })
+
+	return events, nil
+}
+
+func (r *sqlRepository) MarkEventDelivered(ctx context.Context, id int64) error {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	_, err := r.conn().ExecContext(ctx, `DELETE FROM outbox WHERE id = $1`, id)
+
+	return r.translateError(ctx, err)
+}
+
+func (r *sqlRepository) MarkEventFailed(ctx context.Context, id int64, retryAt time.Time, lastError string) error {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	_, err := r.conn().ExecContext(ctx,
+		`UPDATE outbox SET attempts = attempts + 1, next_attempt_at = $1, last_error = $2 WHERE id = $3`,
+		retryAt.UTC(), lastError, id)
+
+	return r.translateError(ctx, err)
+}
@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"log/slog"
+	"net"
+
+	"github.com/dyammarcano/fullcycle_clean_architecture/internal/domain"
+	"github.com/lib/pq"
+)
+
+// translatePostgresError maps driver errors onto the domain error kinds. The raw
+// error is logged here because the translated message hides driver details.
+// When ctx is done the context error is returned, so cancelled requests and
+// expired deadlines are not reported as database failures.
+func translatePostgresError(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+
+	var kind error
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code.Class() {
+		case "23":
+			if pqErr.Code == "23505" {
+				return domain.WrapError(domain.ErrConflict, "order conflicts with stored data", err)
+			}
+
+			return domain.WrapError(domain.ErrValidation, "order rejected by storage constraints", err)
+		case "22":
+			return domain.WrapError(domain.ErrValidation, "order contains out of range data", err)
+		case "08", "53", "57":
+			kind = domain.ErrUnavailable
+		}
+	}
+
+	var netErr net.Error
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) ||
+		errors.As(err, &netErr) {
+		kind = domain.ErrUnavailable
+	}
+
+	if kind == nil {
+		return err
+	}
+
+	slog.Error(">>> Database unavailable: ", slog.String("error", err.Error()))
+
+	return domain.WrapError(kind, "database unavailable", err)
+}
@@ -1,9 +1,13 @@
 package repository
 
 import (
+	"context"
+	"slices"
 	"testing"
+	"time"
 
 	"github.com/dyammarcano/fullcycle_clean_architecture/internal/domain"
+	"github.com/dyammarcano/fullcycle_clean_architecture/pkg/parameters"
 )
 
 func TestRepository(t *testing.T) {
@@ -12,35 +16,187 @@ func TestRepository(t *testing.T) {
 		t.Fatalf("Error creating repository")
 	}
 
+	ctx := context.Background()
+
 	var order = &domain.Order{
 		Item:   "Bag",
-		Amount: 2,
+		Lines:  []domain.OrderLine{{SKU: "BAG-1", Quantity: 1, UnitPrice: domain.Money{Units: 200, Currency: "USD"}}},
+		Amount: domain.Money{Units: 200, Currency: "USD"},
 	}
 
-	if _, err := repository.CreateOrder(order); err != nil {
+	if _, err := repository.CreateOrder(ctx, order); err != nil {
 		t.Errorf("Error creating order")
 	}
 
-	orders, err := repository.ListOrders()
+	page, err := repository.ListOrders(ctx, domain.ListOrdersQuery{PageSize: 10})
 	if err != nil {
-		t.Errorf("Error listing orders")
+		t.Fatalf("Error listing orders")
 	}
 
-	if len(orders) == 0 {
+	if len(page.Orders) == 0 {
 		t.Errorf("Error listing orders")
 	}
 
-	if _, err = repository.GetOrderByID(order.ID); err != nil {
+	if _, err = repository.GetOrderByID(ctx, order.ID); err != nil {
 		t.Errorf("Error getting order")
 	}
 
-	order.Amount = 5
+	order.Amount = domain.Money{Units: 500, Currency: "USD"}
 
-	if err = repository.UpdateOrder(order.ID, order); err != nil {
+	if err = repository.UpdateOrder(ctx, order.ID, order); err != nil {
 		t.Errorf("Error updating order")
 	}
 
-	if err = repository.DeleteOrder(order.ID); err != nil {
+	if _, err = repository.DeleteOrder(ctx, order.ID, 0); err != nil {
 		t.Errorf("Error deleting order")
 	}
 }
+
+func TestMemoryRepositoryListOrders(t *testing.T) {
+	repository, err := NewMemoryRepository()
+	if err != nil {
+		t.Fatalf("Error creating repository")
+	}
+
+	ctx := context.Background()
+
+	for _, o := range []struct {
+		item  string
+		units int64
+	}{{"Shoes", 300}, {"bag", 100}, {"Hat", 200}, {"Bag strap", 50}} {
+		order := &domain.Order{
+			Item:   o.item,
+			Lines:  []domain.OrderLine{{SKU: "SKU", Quantity: 1, UnitPrice: domain.Money{Units: o.units, Currency: "USD"}}},
+			Amount: domain.Money{Units: o.units, Currency: "USD"},
+		}
+
+		if _, err = repository.CreateOrder(ctx, order); err != nil {
+			t.Fatalf("Error creating order: %v", err)
+		}
+	}
+
+	items := func(page *domain.OrderPage) []string {
+		var got []string
+		for _, order := range page.Orders {
+			got = append(got, order.Item)
+		}
+
+		return got
+	}
+
+	query := domain.ListOrdersQuery{SortBy: domain.SortByAmount, Descending: true, PageSize: 3}
+
+	page, err := repository.ListOrders(ctx, query)
+	if err != nil {
+		t.Fatalf("Error listing orders: %v", err)
+	}
+
+	if got := items(page); !slices.Equal(got, []string{"Shoes", "Hat", "bag"}) || !page.HasMore {
+		t.Errorf("first page = %v, has more %v", got, page.HasMore)
+	}
+
+	after := domain.CursorFor(page.Orders[len(page.Orders)-1], query.SortBy)
+	query.After = &after
+
+	if page, err = repository.ListOrders(ctx, query); err != nil {
+		t.Fatalf("Error listing orders: %v", err)
+	}
+
+	if got := items(page); !slices.Equal(got, []string{"Bag strap"}) || page.HasMore {
+		t.Errorf("second page = %v, has more %v", got, page.HasMore)
+	}
+
+	minAmount := domain.Money{Units: 60, Currency: "USD"}
+	filtered := domain.ListOrdersQuery{
+		Filter:   domain.OrderFilter{ItemContains: "BAG", MinAmount: &minAmount},
+		SortBy:   domain.SortByItem,
+		PageSize: 10,
+	}
+
+	if page, err = repository.ListOrders(ctx, filtered); err != nil {
+		t.Fatalf("Error listing orders: %v", err)
+	}
+
+	if got := items(page); !slices.Equal(got, []string{"bag"}) {
+		t.Errorf("filtered page = %v", got)
+	}
+}
+
+func TestBuildListOrdersQuery(t *testing.T) {
+	maxAmount := domain.Money{Units: 5000, Currency: "EUR"}
+	query := domain.ListOrdersQuery{
+		Filter:     domain.OrderFilter{ItemContains: "50%_off", MaxAmount: &maxAmount},
+		SortBy:     domain.SortByItem,
+		Descending: true,
+		PageSize:   20,
+		After:      &domain.OrderCursor{ID: 7, Item: "Hat"},
+	}
+
+	stmt, args := buildListOrdersQuery(postgresDialect, query)
+
+	want := `SELECT id, item, status, amount_units, currency, version, created_at, updated_at, deleted_at FROM orders ` +
+		`WHERE deleted_at IS NULL AND item COLLATE "C" ILIKE $1 AND currency = $2 AND amount_units <= $3 AND (item COLLATE "C", id) < ($4, $5) ` +
+		`ORDER BY item COLLATE "C" DESC, id DESC LIMIT $6`
+	if stmt != want {
+		t.Errorf("statement = %s, want %s", stmt, want)
+	}
+
+	wantArgs := []any{`%50\%\_off%`, "EUR", int64(5000), "Hat", 7, 21}
+	if !slices.Equal(args, wantArgs) {
+		t.Errorf("args = %v, want %v", args, wantArgs)
+	}
+}
+
+func TestSQLDialectRebind(t *testing.T) {
+	query := `UPDATE orders SET deleted_at = $1, updated_at = $1 WHERE id = $2 AND ($13 = 0 OR version = $13)`
+
+	if got := postgresDialect.rebind(query); got != query {
+		t.Errorf("postgres rebind = %s, want the query unchanged", got)
+	}
+
+	want := `UPDATE orders SET deleted_at = ?1, updated_at = ?1 WHERE id = ?2 AND (?13 = 0 OR version = ?13)`
+	if got := sqliteDialect.rebind(query); got != want {
+		t.Errorf("sqlite rebind = %s, want %s", got, want)
+	}
+
+	in, args := sqliteDialect.inIDs("order_id", []int64{4, 9})
+	if in != "order_id IN ($1, $2)" || !slices.Equal(args, []any{int64(4), int64(9)}) {
+		t.Errorf("sqlite inIDs = %s %v", in, args)
+	}
+}
+
+func TestPostgresDataSourceName(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  parameters.Database
+		want string
+	}{
+		{
+			name: "legacy name and default sslmode",
+			cfg:  parameters.Database{Host: "localhost", Port: 5432, User: "postgres", Password: "secret", Name: "orders"},
+			want: `host='localhost' port='5432' user='postgres' password='secret' dbname='orders' sslmode='disable'`,
+		},
+		{
+			name: "tls, application name and statement timeout",
+			cfg: parameters.Database{
+				Host: "db", User: "app", Password: `it's\me`, Name: "ignored", DBName: "orders",
+				SSLMode: "verify-full", SSLRootCert: "/certs/ca.pem", SSLCert: "/certs/client.pem", SSLKey: "/certs/client.key",
+				ApplicationName: "orders api", StatementTimeout: 2500 * time.Millisecond,
+			},
+			want: `host='db' user='app' password='it\'s\\me' dbname='orders' sslmode='verify-full' ` +
+				`sslrootcert='/certs/ca.pem' sslcert='/certs/client.pem' sslkey='/certs/client.key' ` +
+				`application_name='orders api' statement_timeout='2500'`,
+		},
+		{
+			name: "dsn override",
+			cfg:  parameters.Database{Host: "db", DSN: "postgres://app@db/orders?sslmode=require"},
+			want: "postgres://app@db/orders?sslmode=require",
+		},
+	}
+
+	for _, tt := range tests {
+		if got := postgresDataSourceName(tt.cfg); got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,1047 @@
+// Package repositorytest holds the conformance suite every storage backend must
+// pass: its domain.OrderRepository, with the outbox and webhooks sharing its database
+package repositorytest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dyammarcano/fullcycle_clean_architecture/internal/domain"
+)
+
+// Repository is a storage backend under test
+type Repository interface {
+	domain.OrderRepository
+	domain.EventOutbox
+	domain.WebhookRepository
+}
+
+// NewRepository returns an empty repository for one test. It is closed by the suite.
+type NewRepository func(t *testing.T) Repository
+
+// Run runs the conformance suite against the repositories built by newRepository
+func Run(t *testing.T, newRepository NewRepository) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, repo Repository)
+	}{
+		{"CreateAndGet", testCreateAndGet},
+		{"CreateIgnoresID", testCreateIgnoresID},
+		{"ReturnsCopies", testReturnsCopies},
+		{"Update", testUpdate},
+		{"Delete", testDelete},
+		{"NotFound", testNotFound},
+		{"UniqueIDsAfterDelete", testUniqueIDsAfterDelete},
+		{"StatusTransitions", testStatusTransitions},
+		{"ListPagination", testListPagination},
+		{"ListFilter", testListFilter},
+		{"Timestamps", testTimestamps},
+		{"SoftDelete", testSoftDelete},
+		{"PurgeDeletedOrders", testPurgeDeletedOrders},
+		{"Versions", testVersions},
+		{"Transact", testTransact},
+		{"Outbox", testOutbox},
+		{"Webhooks", testWebhooks},
+		{"WebhookDeliveries", testWebhookDeliveries},
+		{"ConcurrentCreates", testConcurrentCreates},
+		{"ConcurrentTransitions", testConcurrentTransitions},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newRepository(t)
+			t.Cleanup(func() {
+				if err := repo.Close(); err != nil {
+					t.Errorf("Error closing repository: %v", err)
+				}
+			})
+
+			tt.run(t, repo)
+		})
+	}
+}
+
+// newOrder returns a pending order with one line per unit price, in USD
+func newOrder(item string, unitPrices ...int64) *domain.Order {
+	order := &domain.Order{Item: item, Status: domain.StatusPending}
+
+	for i, units := range unitPrices {
+		order.Lines = append(order.Lines, domain.OrderLine{
+			SKU:         fmt.Sprintf("%s-%d", item, i+1),
+			Description: item,
+			Quantity:    1,
+			UnitPrice:   domain.Money{Units: units, Currency: "USD"},
+		})
+	}
+
+	if err := order.Recalculate(); err != nil {
+		panic(err)
+	}
+
+	return order
+}
+
+func mustCreate(t *testing.T, repo domain.OrderRepository, order *domain.Order) *domain.Order {
+	t.Helper()
+
+	created, err := repo.CreateOrder(context.Background(), order)
+	if err != nil {
+		t.Fatalf("Error creating order %q: %v", order.Item, err)
+	}
+
+	if created.ID <= 0 || created.ID != order.ID {
+		t.Fatalf("Created order ID = %d, input ID = %d", created.ID, order.ID)
+	}
+
+	return created
+}
+
+func mustGet(t *testing.T, repo domain.OrderRepository, id int) *domain.Order {
+	t.Helper()
+
+	order, err := repo.GetOrderByID(context.Background(), id)
+	if err != nil {
+		t.Fatalf("Error getting order %d: %v", id, err)
+	}
+
+	return order
+}
+
+// assertOrder compares the stored fields of two orders
+func assertOrder(t *testing.T, got, want *domain.Order) {
+	t.Helper()
+
+	if got.ID != want.ID || got.Item != want.Item || got.Status != want.Status || got.Amount != want.Amount {
+		t.Errorf("Order = %+v, want %+v", got, want)
+	}
+
+	if len(got.Lines) != len(want.Lines) || (len(got.Lines) > 0 && !slices.Equal(got.Lines, want.Lines)) {
+		t.Errorf("Lines = %+v, want %+v", got.Lines, want.Lines)
+	}
+}
+
+func testCreateAndGet(t *testing.T, repo Repository) {
+	order := mustCreate(t, repo, newOrder("Bag", 150, 50))
+
+	assertOrder(t, mustGet(t, repo, order.ID), order)
+}
+
+func testCreateIgnoresID(t *testing.T, repo Repository) {
+	existing := mustCreate(t, repo, newOrder("Bag", 100))
+
+	for _, id := range []int{existing.ID, existing.ID + 1000} {
+		input := newOrder("Hat", 200)
+		input.ID = id
+
+		if created := mustCreate(t, repo, input); created.ID == existing.ID || created.ID == id {
+			t.Errorf("Create with ID %d stored the order as %d", id, created.ID)
+		}
+	}
+
+	assertOrder(t, mustGet(t, repo, existing.ID), existing)
+}
+
+func testReturnsCopies(t *testing.T, repo Repository) {
+	input := newOrder("Bag", 150)
+	created := mustCreate(t, repo, input)
+	want := newOrder("Bag", 150)
+	want.ID = created.ID
+
+	input.Item = "changed"
+	created.Item = "changed"
+	created.Lines[0].Quantity = 9
+
+	got := mustGet(t, repo, want.ID)
+	got.Lines[0].SKU = "changed"
+
+	page, err := repo.ListOrders(context.Background(), domain.ListOrdersQuery{})
+	if err != nil {
+		t.Fatalf("Error listing orders: %v", err)
+	}
+
+	page.Orders[0].Item = "changed"
+
+	assertOrder(t, mustGet(t, repo, want.ID), want)
+}
+
+func testUpdate(t *testing.T, repo Repository) {
+	ctx := context.Background()
+
+	order := mustCreate(t, repo, newOrder("Bag", 150, 50))
+
+	if err := repo.ChangeOrderStatus(ctx, order.ID, transition(domain.StatusPending, domain.StatusConfirmed)); err != nil {
+		t.Fatalf("Error changing status: %v", err)
+	}
+
+	update := newOrder("Hat", 300)
+	update.Status = domain.StatusPending
+
+	if err := repo.UpdateOrder(ctx, order.ID, update); err != nil {
+		t.Fatalf("Error updating order: %v", err)
+	}
+
+	want := newOrder("Hat", 300)
+	want.ID = order.ID
+	want.Status = domain.StatusConfirmed
+
+	assertOrder(t, mustGet(t, repo, order.ID), want)
+}
+
+func testDelete(t *testing.T, repo Repository) {
+	ctx := context.Background()
+
+	order := mustCreate(t, repo, newOrder("Bag", 150))
+	kept := mustCreate(t, repo, newOrder("Hat", 100))
+
+	deleted, err := repo.DeleteOrder(ctx, order.ID, 0)
+	if err != nil {
+		t.Fatalf("Error deleting order: %v", err)
+	}
+
+	if deleted.DeletedAt == nil || !deleted.UpdatedAt.Equal(*deleted.DeletedAt) || deleted.Version != order.Version+1 {
+		t.Errorf("Deleted order = %+v, want it stamped as deleted at version %d", deleted, order.Version+1)
+	}
+
+	want := *order
+	want.Version, want.UpdatedAt, want.DeletedAt = deleted.Version, deleted.UpdatedAt, deleted.DeletedAt
+	assertOrder(t, deleted, &want)
+
+	if _, err := repo.GetOrderByID(ctx, order.ID); !errors.Is(err, domain.ErrOrderNotFound) {
+		t.Errorf("Get after delete = %v, want not found", err)
+	}
+
+	if _, err := repo.ListStatusHistory(ctx, order.ID); !errors.Is(err, domain.ErrOrderNotFound) {
+		t.Errorf("History after delete = %v, want not found", err)
+	}
+
+	assertOrder(t, mustGet(t, repo, kept.ID), kept)
+}
+
+func testNotFound(t *testing.T, repo Repository) {
+	ctx := context.Background()
+
+	const missing = 4242
+
+	checks := map[string]error{
+		"GetOrderByID":      func() error { _, err := repo.GetOrderByID(ctx, missing); return err }(),
+		"UpdateOrder":       repo.UpdateOrder(ctx, missing, newOrder("Bag", 100)),
+		"DeleteOrder":       func() error { _, err := repo.DeleteOrder(ctx, missing, 0); return err }(),
+		"ChangeOrderStatus": repo.ChangeOrderStatus(ctx, missing, transition(domain.StatusPending, domain.StatusConfirmed)),
+		"ListStatusHistory": func() error { _, err := repo.ListStatusHistory(ctx, missing); return err }(),
+	}
+
+	for method, err := range checks {
+		if !errors.Is(err, domain.ErrOrderNotFound) {
+			t.Errorf("%s = %v, want %v", method, err, domain.ErrOrderNotFound)
+		}
+	}
+}
+
+func testUniqueIDsAfterDelete(t *testing.T, repo Repository) {
+	ctx := context.Background()
+
+	first := mustCreate(t, repo, newOrder("Bag", 100))
+	last := mustCreate(t, repo, newOrder("Hat", 100))
+
+	if _, err := repo.DeleteOrder(ctx, last.ID, 0); err != nil {
+		t.Fatalf("Error deleting order: %v", err)
+	}
+
+	next := mustCreate(t, repo, newOrder("Cap", 100))
+	if next.ID == first.ID || next.ID == last.ID {
+		t.Errorf("ID %d was issued again after a delete", next.ID)
+	}
+
+	assertOrder(t, mustGet(t, repo, first.ID), first)
+}
+
+func transition(from, to domain.OrderStatus) domain.StatusTransition {
+	return domain.StatusTransition{From: from, To: to, Reason: string(to), At: time.Now().UTC().Truncate(time.Microsecond)}
+}
+
+func testStatusTransitions(t *testing.T, repo Repository) {
+	ctx := context.Background()
+
+	order := mustCreate(t, repo, newOrder("Bag", 100))
+
+	want := []domain.StatusTransition{
+		transition(domain.StatusPending, domain.StatusConfirmed),
+		transition(domain.StatusConfirmed, domain.StatusPaid),
+	}
+
+	for _, tr := range want {
+		if err := repo.ChangeOrderStatus(ctx, order.ID, tr); err != nil {
+			t.Fatalf("Error changing status to %s: %v", tr.To, err)
+		}
+	}
+
+	stale := transition(domain.StatusPending, domain.StatusCancelled)
+	if err := repo.ChangeOrderStatus(ctx, order.ID, stale); !errors.Is(err, domain.ErrOrderStatusChanged) {
+		t.Errorf("Stale transition = %v, want %v", err, domain.ErrOrderStatusChanged)
+	}
+
+	if got := mustGet(t, repo, order.ID); got.Status != domain.StatusPaid {
+		t.Errorf("Status = %s, want %s", got.Status, domain.StatusPaid)
+	}
+
+	history, err := repo.ListStatusHistory(ctx, order.ID)
+	if err != nil {
+		t.Fatalf("Error listing history: %v", err)
+	}
+
+	if len(history) != len(want) {
+		t.Fatalf("History = %+v, want %+v", history, want)
+	}
+
+	for i := range want {
+		if history[i].From != want[i].From || history[i].To != want[i].To || history[i].Reason != want[i].Reason || !history[i].At.Equal(want[i].At) {
+			t.Errorf("History[%d] = %+v, want %+v", i, history[i], want[i])
+		}
+	}
+
+	if history, err = repo.ListStatusHistory(ctx, mustCreate(t, repo, newOrder("Hat", 100)).ID); err != nil || len(history) != 0 {
+		t.Errorf("History of a new order = %+v (%v), want empty", history, err)
+	}
+}
+
+// listAll walks every page of query and returns the items in order
+func listAll(t *testing.T, repo domain.OrderRepository, query domain.ListOrdersQuery) []string {
+	t.Helper()
+
+	var items []string
+
+	for range 100 {
+		page, err := repo.ListOrders(context.Background(), query)
+		if err != nil {
+			t.Fatalf("Error listing orders: %v", err)
+		}
+
+		if query.PageSize > 0 && len(page.Orders) > query.PageSize {
+			t.Fatalf("Page has %d orders, page size is %d", len(page.Orders), query.PageSize)
+		}
+
+		for _, order := range page.Orders {
+			items = append(items, order.Item)
+		}
+
+		if !page.HasMore {
+			return items
+		}
+
+		last := domain.CursorFor(page.Orders[len(page.Orders)-1], query.SortBy)
+		query.After = &last
+	}
+
+	t.Fatalf("Listing did not end")
+
+	return nil
+}
+
+func testListPagination(t *testing.T, repo Repository) {
+	// "bag" sorts after "Cap" byte-wise; Hat and Belt tie on amount
+	for _, order := range []*domain.Order{
+		newOrder("Hat", 300),
+		newOrder("bag", 100),
+		newOrder("Cap", 500),
+		newOrder("Belt", 300),
+		newOrder("Scarf", 200),
+	} {
+		mustCreate(t, repo, order)
+	}
+
+	tests := []struct {
+		sortBy     domain.OrderSortField
+		descending bool
+		want       []string
+	}{
+		{domain.SortByID, false, []string{"Hat", "bag", "Cap", "Belt", "Scarf"}},
+		{domain.SortByID, true, []string{"Scarf", "Belt", "Cap", "bag", "Hat"}},
+		{domain.SortByItem, false, []string{"Belt", "Cap", "Hat", "Scarf", "bag"}},
+		{domain.SortByItem, true, []string{"bag", "Scarf", "Hat", "Cap", "Belt"}},
+		{domain.SortByAmount, false, []string{"bag", "Scarf", "Hat", "Belt", "Cap"}},
+		{domain.SortByAmount, true, []string{"Cap", "Belt", "Hat", "Scarf", "bag"}},
+	}
+
+	for _, tt := range tests {
+		for _, pageSize := range []int{0, 1, 2, 5} {
+			query := domain.ListOrdersQuery{SortBy: tt.sortBy, Descending: tt.descending, PageSize: pageSize}
+			if got := listAll(t, repo, query); !slices.Equal(got, tt.want) {
+				t.Errorf("Sort %s desc=%v page size %d = %v, want %v", tt.sortBy, tt.descending, pageSize, got, tt.want)
+			}
+		}
+	}
+
+	page, err := repo.ListOrders(context.Background(), domain.ListOrdersQuery{PageSize: 5})
+	if err != nil || page.HasMore {
+		t.Errorf("A full last page reports more orders (%v)", err)
+	}
+}
+
+func testListFilter(t *testing.T, repo Repository) {
+	for _, order := range []*domain.Order{
+		newOrder("Handbag", 300),
+		newOrder("bag", 100),
+		newOrder("50%_off", 500),
+		newOrder("500off", 500),
+		newOrder("Écharpe", 50),
+	} {
+		mustCreate(t, repo, order)
+	}
+
+	euro := newOrder("Bag", 200)
+	euro.Lines[0].UnitPrice.Currency = "EUR"
+	euro.Amount.Currency = "EUR"
+	mustCreate(t, repo, euro)
+
+	minAmount := domain.Money{Units: 200, Currency: "USD"}
+	maxAmount := domain.Money{Units: 300, Currency: "USD"}
+
+	tests := []struct {
+		name   string
+		filter domain.OrderFilter
+		want   []string
+	}{
+		{"item ignoring case", domain.OrderFilter{ItemContains: "BAG"}, []string{"Handbag", "bag", "Bag"}},
+		{"ASCII letters ignoring case", domain.OrderFilter{ItemContains: "ÉCHARPE"}, []string{"Écharpe"}},
+		{"non-ASCII letters keeping case", domain.OrderFilter{ItemContains: "écharpe"}, nil},
+		{"wildcards are literal", domain.OrderFilter{ItemContains: "%_"}, []string{"50%_off"}},
+		{"min amount", domain.OrderFilter{MinAmount: &minAmount}, []string{"Handbag", "50%_off", "500off"}},
+		{"amount range", domain.OrderFilter{MinAmount: &minAmount, MaxAmount: &maxAmount}, []string{"Handbag"}},
+		{"item and amount", domain.OrderFilter{ItemContains: "bag", MaxAmount: &maxAmount}, []string{"Handbag", "bag"}},
+	}
+
+	for _, tt := range tests {
+		if got := listAll(t, repo, domain.ListOrdersQuery{Filter: tt.filter, PageSize: 2}); !slices.Equal(got, tt.want) {
+			t.Errorf("Filter %s = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func testTimestamps(t *testing.T, repo Repository) {
+	ctx := context.Background()
+	start := time.Now().Add(-time.Second)
+
+	created := mustCreate(t, repo, newOrder("Bag", 100))
+	if created.CreatedAt.Before(start) || !created.UpdatedAt.Equal(created.CreatedAt) || created.DeletedAt != nil {
+		t.Fatalf("Created order timestamps = %v, %v, %v", created.CreatedAt, created.UpdatedAt, created.DeletedAt)
+	}
+
+	got := mustGet(t, repo, created.ID)
+	if !got.CreatedAt.Equal(created.CreatedAt) || !got.UpdatedAt.Equal(created.UpdatedAt) || got.DeletedAt != nil {
+		t.Errorf("Stored timestamps = %v, %v, %v, want %v", got.CreatedAt, got.UpdatedAt, got.DeletedAt, created.CreatedAt)
+	}
+
+	time.Sleep(2 * time.Millisecond)
+
+	if err := repo.UpdateOrder(ctx, created.ID, newOrder("Hat", 200)); err != nil {
+		t.Fatalf("Error updating order: %v", err)
+	}
+
+	updated := mustGet(t, repo, created.ID)
+	if !updated.CreatedAt.Equal(created.CreatedAt) || !updated.UpdatedAt.After(created.UpdatedAt) {
+		t.Errorf("Timestamps after update = %v, %v, created at %v", updated.CreatedAt, updated.UpdatedAt, created.CreatedAt)
+	}
+
+	time.Sleep(2 * time.Millisecond)
+
+	if err := repo.ChangeOrderStatus(ctx, created.ID, transition(domain.StatusPending, domain.StatusConfirmed)); err != nil {
+		t.Fatalf("Error changing status: %v", err)
+	}
+
+	confirmed := mustGet(t, repo, created.ID)
+	if !confirmed.UpdatedAt.After(updated.UpdatedAt) {
+		t.Errorf("UpdatedAt after a transition = %v, was %v", confirmed.UpdatedAt, updated.UpdatedAt)
+	}
+
+	page, err := repo.ListOrders(ctx, domain.ListOrdersQuery{})
+	if err != nil || len(page.Orders) != 1 {
+		t.Fatalf("Unexpected page %+v (%v)", page, err)
+	}
+
+	if listed := page.Orders[0]; !listed.CreatedAt.Equal(confirmed.CreatedAt) || !listed.UpdatedAt.Equal(confirmed.UpdatedAt) {
+		t.Errorf("Listed timestamps = %v, %v, want %v, %v", listed.CreatedAt, listed.UpdatedAt, confirmed.CreatedAt, confirmed.UpdatedAt)
+	}
+}
+
+func testSoftDelete(t *testing.T, repo Repository) {
+	ctx := context.Background()
+
+	deleted := mustCreate(t, repo, newOrder("Bag", 150, 50))
+	kept := mustCreate(t, repo, newOrder("Hat", 100))
+
+	if err := repo.ChangeOrderStatus(ctx, deleted.ID, transition(domain.StatusPending, domain.StatusConfirmed)); err != nil {
+		t.Fatalf("Error changing status: %v", err)
+	}
+
+	if _, err := repo.DeleteOrder(ctx, deleted.ID, 0); err != nil {
+		t.Fatalf("Error deleting order: %v", err)
+	}
+
+	missing := map[string]error{
+		"DeleteOrder":       func() error { _, err := repo.DeleteOrder(ctx, deleted.ID, 0); return err }(),
+		"UpdateOrder":       repo.UpdateOrder(ctx, deleted.ID, newOrder("Cap", 100)),
+		"ChangeOrderStatus": repo.ChangeOrderStatus(ctx, deleted.ID, transition(domain.StatusConfirmed, domain.StatusPaid)),
+		"RestoreOrder live": repo.RestoreOrder(ctx, kept.ID),
+		"RestoreOrder none": repo.RestoreOrder(ctx, 4242),
+	}
+
+	for method, err := range missing {
+		if !errors.Is(err, domain.ErrOrderNotFound) {
+			t.Errorf("%s = %v, want %v", method, err, domain.ErrOrderNotFound)
+		}
+	}
+
+	if got := listAll(t, repo, domain.ListOrdersQuery{PageSize: 1}); !slices.Equal(got, []string{"Hat"}) {
+		t.Errorf("Live orders = %v, want [Hat]", got)
+	}
+
+	page, err := repo.ListOrders(ctx, domain.ListOrdersQuery{Filter: domain.OrderFilter{Deleted: true}})
+	if err != nil || len(page.Orders) != 1 {
+		t.Fatalf("Unexpected deleted page %+v (%v)", page, err)
+	}
+
+	if got := page.Orders[0]; got.ID != deleted.ID || got.DeletedAt == nil || got.DeletedAt.Before(deleted.CreatedAt) || len(got.Lines) != 2 {
+		t.Errorf("Deleted order = %+v", got)
+	}
+
+	if err = repo.RestoreOrder(ctx, deleted.ID); err != nil {
+		t.Fatalf("Error restoring order: %v", err)
+	}
+
+	restored := mustGet(t, repo, deleted.ID)
+	if restored.DeletedAt != nil || restored.Status != domain.StatusConfirmed || len(restored.Lines) != 2 {
+		t.Errorf("Restored order = %+v", restored)
+	}
+
+	if history, err := repo.ListStatusHistory(ctx, deleted.ID); err != nil || len(history) != 1 {
+		t.Errorf("History after restore = %+v (%v)", history, err)
+	}
+
+	if got := listAll(t, repo, domain.ListOrdersQuery{Filter: domain.OrderFilter{Deleted: true}}); len(got) != 0 {
+		t.Errorf("Deleted orders after restore = %v", got)
+	}
+}
+
+func testPurgeDeletedOrders(t *testing.T, repo Repository) {
+	ctx := context.Background()
+
+	first := mustCreate(t, repo, newOrder("Bag", 100))
+	second := mustCreate(t, repo, newOrder("Hat", 100))
+	kept := mustCreate(t, repo, newOrder("Cap", 100))
+
+	for _, order := range []*domain.Order{first, second} {
+		if err := repo.ChangeOrderStatus(ctx, order.ID, transition(domain.StatusPending, domain.StatusConfirmed)); err != nil {
+			t.Fatalf("Error changing status: %v", err)
+		}
+
+		if _, err := repo.DeleteOrder(ctx, order.ID, 0); err != nil {
+			t.Fatalf("Error deleting order: %v", err)
+		}
+	}
+
+	if purged, err := repo.PurgeDeletedOrders(ctx, first.CreatedAt.Add(-time.Hour)); err != nil || purged != 0 {
+		t.Errorf("Purge before the deletes = %d (%v), want 0", purged, err)
+	}
+
+	if purged, err := repo.PurgeDeletedOrders(ctx, time.Now().Add(time.Second)); err != nil || purged != 2 {
+		t.Errorf("Purge after the deletes = %d (%v), want 2", purged, err)
+	}
+
+	if err := repo.RestoreOrder(ctx, first.ID); !errors.Is(err, domain.ErrOrderNotFound) {
+		t.Errorf("Restore after purge = %v, want %v", err, domain.ErrOrderNotFound)
+	}
+
+	if got := listAll(t, repo, domain.ListOrdersQuery{Filter: domain.OrderFilter{Deleted: true}}); len(got) != 0 {
+		t.Errorf("Deleted orders after purge = %v", got)
+	}
+
+	assertOrder(t, mustGet(t, repo, kept.ID), kept)
+
+	next := mustCreate(t, repo, newOrder("Belt", 100))
+	if next.ID == first.ID || next.ID == second.ID {
+		t.Errorf("ID %d was issued again after a purge", next.ID)
+	}
+}
+
+func testVersions(t *testing.T, repo Repository) {
+	ctx := context.Background()
+
+	created := mustCreate(t, repo, newOrder("Bag", 100))
+	if created.Version != 1 {
+		t.Fatalf("Created version = %d, want 1", created.Version)
+	}
+
+	update := newOrder("Hat", 200)
+	update.Version = 1
+
+	if err := repo.UpdateOrder(ctx, created.ID, update); err != nil {
+		t.Fatalf("Error updating version 1: %v", err)
+	}
+
+	stale := newOrder("Cap", 300)
+	stale.Version = 1
+
+	if err := repo.UpdateOrder(ctx, created.ID, stale); !errors.Is(err, domain.ErrOrderVersionMismatch) {
+		t.Fatalf("Update of a stale version = %v, want version mismatch", err)
+	}
+
+	want := newOrder("Hat", 200)
+	want.ID = created.ID
+	got := mustGet(t, repo, created.ID)
+	assertOrder(t, got, want)
+
+	if got.Version != 2 {
+		t.Errorf("Version after update = %d, want 2", got.Version)
+	}
+
+	if err := repo.UpdateOrder(ctx, created.ID, newOrder("Cap", 300)); err != nil {
+		t.Fatalf("Error updating without a version: %v", err)
+	}
+
+	if err := repo.ChangeOrderStatus(ctx, created.ID, transition(domain.StatusPending, domain.StatusConfirmed)); err != nil {
+		t.Fatalf("Error changing status: %v", err)
+	}
+
+	if got = mustGet(t, repo, created.ID); got.Version != 4 {
+		t.Errorf("Version after update and transition = %d, want 4", got.Version)
+	}
+
+	if _, err := repo.DeleteOrder(ctx, created.ID, 3); !errors.Is(err, domain.ErrOrderVersionMismatch) {
+		t.Errorf("Delete of a stale version = %v, want version mismatch", err)
+	}
+
+	if _, err := repo.DeleteOrder(ctx, created.ID, 4); err != nil {
+		t.Fatalf("Error deleting version 4: %v", err)
+	}
+
+	if _, err := repo.DeleteOrder(ctx, created.ID, 5); !errors.Is(err, domain.ErrOrderNotFound) {
+		t.Errorf("Delete of a deleted order = %v, want not found", err)
+	}
+
+	if err := repo.RestoreOrder(ctx, created.ID); err != nil {
+		t.Fatalf("Error restoring order: %v", err)
+	}
+
+	if got = mustGet(t, repo, created.ID); got.Version != 6 {
+		t.Errorf("Version after delete and restore = %d, want 6", got.Version)
+	}
+}
+
+func testTransact(t *testing.T, repo Repository) {
+	ctx := context.Background()
+	failure := errors.New("rolled back")
+
+	event := func(order *domain.Order) domain.Event {
+		event, err := domain.NewOrderEvent(domain.EventOrderCreated, order, nil)
+		if err != nil {
+			t.Fatalf("Error creating event: %v", err)
+		}
+
+		return event
+	}
+
+	var committed *domain.Order
+
+	err := repo.Transact(ctx, func(tx domain.OrderRepository, outbox domain.EventOutbox) error {
+		created, err := tx.CreateOrder(ctx, newOrder("Bag", 100))
+		if err != nil {
+			return err
+		}
+
+		committed = created
+
+		return outbox.AppendEvents(ctx, event(created))
+	})
+	if err != nil {
+		t.Fatalf("Error committing transaction: %v", err)
+	}
+
+	err = repo.Transact(ctx, func(tx domain.OrderRepository, outbox domain.EventOutbox) error {
+		created, err := tx.CreateOrder(ctx, newOrder("Hat", 200))
+		if err != nil {
+			return err
+		}
+
+		if _, err = tx.DeleteOrder(ctx, committed.ID, 0); err != nil {
+			return err
+		}
+
+		if err = outbox.AppendEvents(ctx, event(created)); err != nil {
+			return err
+		}
+
+		return failure
+	})
+	if !errors.Is(err, failure) {
+		t.Fatalf("Transact = %v, want the error of fn", err)
+	}
+
+	assertOrder(t, mustGet(t, repo, committed.ID), committed)
+
+	if items := listAll(t, repo, domain.ListOrdersQuery{}); !slices.Equal(items, []string{"Bag"}) {
+		t.Errorf("Orders after a rollback = %v, want [Bag]", items)
+	}
+
+	now := time.Now()
+
+	events, err := repo.ClaimEvents(ctx, now, now.Add(time.Minute), 10)
+	if err != nil {
+		t.Fatalf("Error claiming events: %v", err)
+	}
+
+	if len(events) != 1 || events[0].OrderID != committed.ID {
+		t.Errorf("Events after a rollback = %+v, want the committed one", events)
+	}
+}
+
+func testOutbox(t *testing.T, repo Repository) {
+	ctx := context.Background()
+	order := mustCreate(t, repo, newOrder("Bag", 100))
+
+	var events []domain.Event
+	for _, eventType := range []domain.EventType{domain.EventOrderCreated, domain.EventOrderUpdated, domain.EventOrderDeleted} {
+		event, err := domain.NewOrderEvent(eventType, order, nil)
+		if err != nil {
+			t.Fatalf("Error creating event: %v", err)
+		}
+
+		events = append(events, event)
+	}
+
+	if err := repo.AppendEvents(ctx, events...); err != nil {
+		t.Fatalf("Error appending events: %v", err)
+	}
+
+	if events[0].ID == 0 || events[1].ID <= events[0].ID || events[2].ID <= events[1].ID {
+		t.Fatalf("Event IDs = %d, %d, %d, want increasing IDs", events[0].ID, events[1].ID, events[2].ID)
+	}
+
+	now := time.Now()
+	lease := now.Add(time.Minute)
+
+	claimed, err := repo.ClaimEvents(ctx, now, lease, 2)
+	if err != nil {
+		t.Fatalf("Error claiming events: %v", err)
+	}
+
+	if len(claimed) != 2 || claimed[0].ID != events[0].ID || claimed[1].ID != events[1].ID {
+		t.Fatalf("Claimed %+v, want the two oldest events", claimed)
+	}
+
+	got := claimed[0]
+	if got.Type != domain.EventOrderCreated || got.OrderID != order.ID || got.OrderVersion != order.Version ||
+		!got.OccurredAt.Equal(events[0].OccurredAt) || got.Attempts != 0 {
+		t.Errorf("Claimed event = %+v, want %+v", got, events[0])
+	}
+
+	if data, err := got.OrderData(); err != nil || data.Order == nil || data.Order.Item != "Bag" {
+		t.Errorf("Claimed event data = %+v (%v)", data, err)
+	}
+
+	if claimed, err = repo.ClaimEvents(ctx, now, lease, 10); err != nil || len(claimed) != 1 || claimed[0].ID != events[2].ID {
+		t.Fatalf("Second claim = %+v (%v), want only the unleased event", claimed, err)
+	}
+
+	if err = repo.MarkEventDelivered(ctx, events[0].ID); err != nil {
+		t.Fatalf("Error marking event delivered: %v", err)
+	}
+
+	if err = repo.MarkEventFailed(ctx, events[1].ID, lease.Add(time.Minute), "broker down"); err != nil {
+		t.Fatalf("Error marking event failed: %v", err)
+	}
+
+	if claimed, err = repo.ClaimEvents(ctx, lease, lease.Add(time.Minute), 10); err != nil || len(claimed) != 1 || claimed[0].ID != events[2].ID {
+		t.Fatalf("Claim after the lease = %+v (%v), want the event whose lease expired", claimed, err)
+	}
+
+	retry := lease.Add(2 * time.Minute)
+	if claimed, err = repo.ClaimEvents(ctx, retry, retry.Add(time.Minute), 10); err != nil || len(claimed) != 2 {
+		t.Fatalf("Claim after the retry time = %+v (%v), want the failed and the leased event", claimed, err)
+	}
+
+	if claimed[0].ID != events[1].ID || claimed[0].Attempts != 1 {
+		t.Errorf("Retried event = %+v, want event %d after one attempt", claimed[0], events[1].ID)
+	}
+}
+
+func testWebhooks(t *testing.T, repo Repository) {
+	ctx := context.Background()
+
+	webhook := &domain.Webhook{URL: "https://example.com/hook", EventTypes: []domain.EventType{domain.EventOrderCreated}, Secret: "s3cret"}
+	if err := repo.CreateWebhook(ctx, webhook); err != nil {
+		t.Fatalf("Error creating webhook: %v", err)
+	}
+
+	if webhook.ID <= 0 || webhook.CreatedAt.IsZero() || !webhook.UpdatedAt.Equal(webhook.CreatedAt) {
+		t.Fatalf("Created webhook = %+v, want an ID and timestamps", webhook)
+	}
+
+	got, err := repo.GetWebhook(ctx, webhook.ID)
+	if err != nil {
+		t.Fatalf("Error getting webhook: %v", err)
+	}
+
+	if got.URL != webhook.URL || !slices.Equal(got.EventTypes, webhook.EventTypes) || got.Secret != "s3cret" || !got.CreatedAt.Equal(webhook.CreatedAt) {
+		t.Errorf("Webhook = %+v, want %+v", got, webhook)
+	}
+
+	all := &domain.Webhook{URL: "https://example.com/all", Secret: "other"}
+	if err = repo.CreateWebhook(ctx, all); err != nil {
+		t.Fatalf("Error creating webhook: %v", err)
+	}
+
+	all.EventTypes = []domain.EventType{domain.EventOrderDeleted, domain.EventOrderRestored}
+	if err = repo.UpdateWebhook(ctx, all); err != nil {
+		t.Fatalf("Error updating webhook: %v", err)
+	}
+
+	if all.CreatedAt.IsZero() || all.UpdatedAt.Before(all.CreatedAt) {
+		t.Errorf("Updated webhook timestamps = %v, %v", all.CreatedAt, all.UpdatedAt)
+	}
+
+	webhooks, err := repo.ListWebhooks(ctx)
+	if err != nil {
+		t.Fatalf("Error listing webhooks: %v", err)
+	}
+
+	if len(webhooks) != 2 || webhooks[0].ID != webhook.ID || !slices.Equal(webhooks[1].EventTypes, all.EventTypes) {
+		t.Errorf("Webhooks = %+v", webhooks)
+	}
+
+	if err = repo.DeleteWebhook(ctx, webhook.ID); err != nil {
+		t.Fatalf("Error deleting webhook: %v", err)
+	}
+
+	if _, err = repo.GetWebhook(ctx, webhook.ID); !errors.Is(err, domain.ErrWebhookNotFound) {
+		t.Errorf("GetWebhook after delete = %v, want ErrWebhookNotFound", err)
+	}
+
+	if err = repo.DeleteWebhook(ctx, webhook.ID); !errors.Is(err, domain.ErrWebhookNotFound) {
+		t.Errorf("DeleteWebhook twice = %v, want ErrWebhookNotFound", err)
+	}
+
+	if err = repo.UpdateWebhook(ctx, webhook); !errors.Is(err, domain.ErrWebhookNotFound) {
+		t.Errorf("UpdateWebhook of a deleted webhook = %v, want ErrWebhookNotFound", err)
+	}
+}
+
+func testWebhookDeliveries(t *testing.T, repo Repository) {
+	ctx := context.Background()
+	order := mustCreate(t, repo, newOrder("Bag", 100))
+
+	created := &domain.Webhook{URL: "https://example.com/created", EventTypes: []domain.EventType{domain.EventOrderCreated}, Secret: "a"}
+	all := &domain.Webhook{URL: "https://example.com/all", Secret: "b"}
+
+	for _, webhook := range []*domain.Webhook{created, all} {
+		if err := repo.CreateWebhook(ctx, webhook); err != nil {
+			t.Fatalf("Error creating webhook: %v", err)
+		}
+	}
+
+	var events []domain.Event
+	for _, eventType := range []domain.EventType{domain.EventOrderCreated, domain.EventOrderUpdated} {
+		event, err := domain.NewOrderEvent(eventType, order, nil)
+		if err != nil {
+			t.Fatalf("Error creating event: %v", err)
+		}
+
+		events = append(events, event)
+	}
+
+	if err := repo.AppendEvents(ctx, events...); err != nil {
+		t.Fatalf("Error appending events: %v", err)
+	}
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+
+	for _, event := range append(events, events[0]) {
+		if err := repo.EnqueueWebhookDeliveries(ctx, event, now); err != nil {
+			t.Fatalf("Error enqueuing deliveries: %v", err)
+		}
+	}
+
+	lease := now.Add(time.Minute)
+
+	claimed, err := repo.ClaimWebhookDeliveries(ctx, now, lease, 10)
+	if err != nil {
+		t.Fatalf("Error claiming deliveries: %v", err)
+	}
+
+	if len(claimed) != 3 {
+		t.Fatalf("Claimed %+v, want the created event for both webhooks and the updated one for the second", claimed)
+	}
+
+	first := claimed[0]
+	if first.WebhookID != created.ID || first.Event.ID != events[0].ID || first.Event.Type != domain.EventOrderCreated ||
+		first.Event.OrderID != order.ID || first.Status != domain.DeliveryPending || first.Attempts != 0 || !first.Event.OccurredAt.Equal(events[0].OccurredAt) {
+		t.Errorf("First delivery = %+v", first)
+	}
+
+	if data, err := first.Event.OrderData(); err != nil || data.Order == nil || data.Order.Item != "Bag" {
+		t.Errorf("Delivered event data = %+v (%v)", data, err)
+	}
+
+	if again, err := repo.ClaimWebhookDeliveries(ctx, now, lease, 10); err != nil || len(again) != 0 {
+		t.Fatalf("Second claim = %+v (%v), want nothing while leased", again, err)
+	}
+
+	failed := domain.WebhookAttempt{DeliveryID: first.ID, At: now, StatusCode: 500, Error: "HTTP 500"}
+	if err = repo.RecordWebhookAttempt(ctx, failed, domain.DeliveryPending, now.Add(time.Second)); err != nil {
+		t.Fatalf("Error recording attempt: %v", err)
+	}
+
+	dead := domain.WebhookAttempt{DeliveryID: first.ID, At: now.Add(time.Second), Error: "connection refused"}
+	if err = repo.RecordWebhookAttempt(ctx, dead, domain.DeliveryDead, now.Add(time.Second)); err != nil {
+		t.Fatalf("Error recording attempt: %v", err)
+	}
+
+	succeeded := domain.WebhookAttempt{DeliveryID: claimed[1].ID, At: now, StatusCode: 204}
+	if err = repo.RecordWebhookAttempt(ctx, succeeded, domain.DeliverySucceeded, now); err != nil {
+		t.Fatalf("Error recording attempt: %v", err)
+	}
+
+	if err = repo.RecordWebhookAttempt(ctx, domain.WebhookAttempt{DeliveryID: 9999, At: now}, domain.DeliveryDead, now); !errors.Is(err, domain.ErrDeliveryNotFound) {
+		t.Errorf("RecordWebhookAttempt of a missing delivery = %v, want ErrDeliveryNotFound", err)
+	}
+
+	got, err := repo.GetWebhookDelivery(ctx, created.ID, first.ID)
+	if err != nil {
+		t.Fatalf("Error getting delivery: %v", err)
+	}
+
+	if got.Status != domain.DeliveryDead || got.Attempts != 2 || got.LastError != "connection refused" || len(got.History) != 2 ||
+		got.History[0].StatusCode != 500 || got.History[1].Error != "connection refused" || !got.History[1].At.Equal(dead.At) {
+		t.Errorf("Dead delivery = %+v", got)
+	}
+
+	if _, err = repo.GetWebhookDelivery(ctx, all.ID, first.ID); !errors.Is(err, domain.ErrDeliveryNotFound) {
+		t.Errorf("GetWebhookDelivery of another webhook = %v, want ErrDeliveryNotFound", err)
+	}
+
+	deliveries, err := repo.ListWebhookDeliveries(ctx, all.ID, "", 10)
+	if err != nil || len(deliveries) != 2 || deliveries[0].Event.ID != events[1].ID || deliveries[1].Status != domain.DeliverySucceeded {
+		t.Errorf("Deliveries of the second webhook = %+v (%v), want newest first", deliveries, err)
+	}
+
+	if deliveries, err = repo.ListWebhookDeliveries(ctx, created.ID, domain.DeliveryDead, 10); err != nil || len(deliveries) != 1 || deliveries[0].History != nil {
+		t.Errorf("Dead deliveries = %+v (%v)", deliveries, err)
+	}
+
+	if _, err = repo.ListWebhookDeliveries(ctx, 9999, "", 10); !errors.Is(err, domain.ErrWebhookNotFound) {
+		t.Errorf("ListWebhookDeliveries of a missing webhook = %v, want ErrWebhookNotFound", err)
+	}
+
+	later := now.Add(time.Hour)
+	if err = repo.RedeliverWebhookDelivery(ctx, created.ID, first.ID, later); err != nil {
+		t.Fatalf("Error redelivering: %v", err)
+	}
+
+	if claimed, err = repo.ClaimWebhookDeliveries(ctx, later, later.Add(time.Minute), 10); err != nil || len(claimed) != 2 {
+		t.Fatalf("Claim after redelivery = %+v (%v), want the redelivered and the leased one", claimed, err)
+	}
+
+	if claimed[0].ID != first.ID || claimed[0].Attempts != 0 || claimed[0].Status != domain.DeliveryPending {
+		t.Errorf("Redelivered delivery = %+v", claimed[0])
+	}
+
+	if err = repo.RedeliverWebhookDelivery(ctx, all.ID, first.ID, later); !errors.Is(err, domain.ErrDeliveryNotFound) {
+		t.Errorf("RedeliverWebhookDelivery of another webhook = %v, want ErrDeliveryNotFound", err)
+	}
+
+	if err = repo.DeleteWebhook(ctx, created.ID); err != nil {
+		t.Fatalf("Error deleting webhook: %v", err)
+	}
+
+	if err = repo.RecordWebhookAttempt(ctx, domain.WebhookAttempt{DeliveryID: first.ID, At: later}, domain.DeliverySucceeded, later); !errors.Is(err, domain.ErrDeliveryNotFound) {
+		t.Errorf("RecordWebhookAttempt after the webhook was deleted = %v, want ErrDeliveryNotFound", err)
+	}
+}
+
+func testConcurrentCreates(t *testing.T, repo Repository) {
+	const n = 20
+
+	var (
+		wg  sync.WaitGroup
+		mu  sync.Mutex
+		ids = map[int]bool{}
+	)
+
+	for i := range n {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			order, err := repo.CreateOrder(context.Background(), newOrder(fmt.Sprintf("Item %d", i), 100))
+			if err != nil {
+				t.Errorf("Error creating order: %v", err)
+				return
+			}
+
+			if _, err = repo.GetOrderByID(context.Background(), order.ID); err != nil {
+				t.Errorf("Error getting order: %v", err)
+			}
+
+			mu.Lock()
+			ids[order.ID] = true
+			mu.Unlock()
+		}()
+	}
+
+	wg.Wait()
+
+	if len(ids) != n {
+		t.Errorf("%d distinct IDs issued for %d orders", len(ids), n)
+	}
+
+	if got := listAll(t, repo, domain.ListOrdersQuery{PageSize: 7}); len(got) != n {
+		t.Errorf("Listed %d orders, want %d", len(got), n)
+	}
+}
+
+func testConcurrentTransitions(t *testing.T, repo Repository) {
+	const n = 10
+
+	order := mustCreate(t, repo, newOrder("Bag", 100))
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		succeeded int
+	)
+
+	for range n {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			err := repo.ChangeOrderStatus(context.Background(), order.ID, transition(domain.StatusPending, domain.StatusConfirmed))
+			if err != nil && !errors.Is(err, domain.ErrOrderStatusChanged) {
+				t.Errorf("Unexpected error: %v", err)
+				return
+			}
+
+			if err == nil {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if succeeded != 1 {
+		t.Errorf("%d concurrent transitions from the same status succeeded, want 1", succeeded)
+	}
+
+	if history, err := repo.ListStatusHistory(context.Background(), order.ID); err != nil || len(history) != 1 {
+		t.Errorf("History = %+v (%v), want one transition", history, err)
+	}
+}
@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/dyammarcano/fullcycle_clean_architecture/internal/domain"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// translateSQLiteError maps driver errors onto the domain error kinds, like
+// translatePostgresError does for Postgres
+func translateSQLiteError(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return err
+	}
+
+	switch sqliteErr.Code() {
+	case sqlite3.SQLITE_CONSTRAINT_UNIQUE, sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY:
+		return domain.WrapError(domain.ErrConflict, "order conflicts with stored data", err)
+	}
+
+	switch sqliteErr.Code() & 0xff {
+	case sqlite3.SQLITE_CONSTRAINT, sqlite3.SQLITE_MISMATCH, sqlite3.SQLITE_TOOBIG:
+		return domain.WrapError(domain.ErrValidation, "order rejected by storage constraints", err)
+	case sqlite3.SQLITE_BUSY, sqlite3.SQLITE_LOCKED, sqlite3.SQLITE_CANTOPEN, sqlite3.SQLITE_FULL, sqlite3.SQLITE_IOERR, sqlite3.SQLITE_READONLY:
+		slog.Error(">>> Database unavailable: ", slog.String("error", err.Error()))
+
+		return domain.WrapError(domain.ErrUnavailable, "database unavailable", err)
+	}
+
+	return err
+}
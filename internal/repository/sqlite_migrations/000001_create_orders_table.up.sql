@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS orders
+(
+    id     INTEGER PRIMARY KEY AUTOINCREMENT,
+    item   TEXT    NOT NULL,
+    amount INTEGER NOT NULL
+);
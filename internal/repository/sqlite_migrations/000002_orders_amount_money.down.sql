@@ -0,0 +1,4 @@
+-- Reverting truncates fractional amounts and drops the currency.
+ALTER TABLE orders DROP COLUMN currency;
+ALTER TABLE orders RENAME COLUMN amount_units TO amount;
+UPDATE orders SET amount = amount / 100;
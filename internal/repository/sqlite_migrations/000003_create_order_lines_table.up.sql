@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS order_lines
+(
+    order_id         INTEGER NOT NULL REFERENCES orders (id) ON DELETE CASCADE,
+    line_no          INTEGER NOT NULL,
+    sku              TEXT    NOT NULL,
+    description      TEXT    NOT NULL DEFAULT '',
+    quantity         INTEGER NOT NULL CHECK (quantity > 0),
+    unit_price_units INTEGER NOT NULL,
+    currency         TEXT    NOT NULL,
+    PRIMARY KEY (order_id, line_no)
+);
+
+-- Orders created before line items existed become a single line holding their amount.
+INSERT INTO order_lines (order_id, line_no, sku, description, quantity, unit_price_units, currency)
+SELECT id, 1, 'ORDER-' || id, item, 1, amount_units, currency
+FROM orders;
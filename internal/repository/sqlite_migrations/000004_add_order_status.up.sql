@@ -0,0 +1,15 @@
+ALTER TABLE orders
+    ADD COLUMN status TEXT NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'confirmed', 'paid', 'shipped', 'delivered', 'cancelled', 'refunded'));
+
+CREATE TABLE IF NOT EXISTS order_status_history
+(
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    order_id    INTEGER  NOT NULL REFERENCES orders (id) ON DELETE CASCADE,
+    from_status TEXT     NOT NULL,
+    to_status   TEXT     NOT NULL,
+    reason      TEXT     NOT NULL DEFAULT '',
+    changed_at  DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS order_status_history_order_id_idx ON order_status_history (order_id, id);
@@ -0,0 +1,5 @@
+CREATE INDEX IF NOT EXISTS orders_item_id_idx ON orders (item, id);
+
+CREATE INDEX IF NOT EXISTS orders_currency_amount_id_idx ON orders (currency, amount_units, id);
+
+CREATE INDEX IF NOT EXISTS orders_amount_id_idx ON orders (amount_units, id);
@@ -0,0 +1,8 @@
+-- Soft-deleted orders would become live again once the column is gone.
+DELETE FROM orders WHERE deleted_at IS NOT NULL;
+
+DROP INDEX IF EXISTS orders_deleted_at_idx;
+
+ALTER TABLE orders DROP COLUMN deleted_at;
+ALTER TABLE orders DROP COLUMN updated_at;
+ALTER TABLE orders DROP COLUMN created_at;
@@ -0,0 +1,9 @@
+-- SQLite cannot add a column with a non-constant default, so existing orders are
+-- stamped by the UPDATE below; new orders always get their times from the repository.
+ALTER TABLE orders ADD COLUMN created_at DATETIME NOT NULL DEFAULT '1970-01-01 00:00:00';
+ALTER TABLE orders ADD COLUMN updated_at DATETIME NOT NULL DEFAULT '1970-01-01 00:00:00';
+ALTER TABLE orders ADD COLUMN deleted_at DATETIME;
+
+UPDATE orders SET created_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS orders_deleted_at_idx ON orders (deleted_at) WHERE deleted_at IS NOT NULL;
@@ -0,0 +1,300 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/dyammarcano/fullcycle_clean_architecture/internal/domain"
+)
+
+func (r *OrderMemoryRepository) CreateWebhook(_ context.Context, webhook *domain.Webhook) error {
+	r.lock()
+	defer r.unlock()
+
+	r.lastWebhookID++
+	webhook.ID = r.lastWebhookID
+	webhook.CreatedAt = now()
+	webhook.UpdatedAt = webhook.CreatedAt
+	r.saveWebhook(webhook.ID)
+	r.webhooks[webhook.ID] = cloneWebhook(webhook)
+	r.dirty = true
+
+	return nil
+}
+
+func (r *OrderMemoryRepository) GetWebhook(_ context.Context, id int) (*domain.Webhook, error) {
+	r.rlock()
+	defer r.runlock()
+
+	webhook, ok := r.webhooks[id]
+	if !ok {
+		return nil, domain.ErrWebhookNotFound
+	}
+
+	return cloneWebhook(webhook), nil
+}
+
+func (r *OrderMemoryRepository) ListWebhooks(_ context.Context) ([]domain.Webhook, error) {
+	r.rlock()
+	defer r.runlock()
+
+	webhooks := make([]domain.Webhook, 0, len(r.webhooks))
+	for _, webhook := range r.webhooks {
+		webhooks = append(webhooks, *cloneWebhook(webhook))
+	}
+
+	slices.SortFunc(webhooks, func(a, b domain.Webhook) int { return a.ID - b.ID })
+
+	return webhooks, nil
+}
+
+func (r *OrderMemoryRepository) UpdateWebhook(_ context.Context, webhook *domain.Webhook) error {
+	r.lock()
+	defer r.unlock()
+
+	stored, ok := r.webhooks[webhook.ID]
+	if !ok {
+		return domain.ErrWebhookNotFound
+	}
+
+	webhook.CreatedAt, webhook.UpdatedAt = stored.CreatedAt, now()
+	r.saveWebhook(webhook.ID)
+	r.webhooks[webhook.ID] = cloneWebhook(webhook)
+	r.dirty = true
+
+	return nil
+}
+
+func (r *OrderMemoryRepository) DeleteWebhook(_ context.Context, id int) error {
+	r.lock()
+	defer r.unlock()
+
+	if _, ok := r.webhooks[id]; !ok {
+		return domain.ErrWebhookNotFound
+	}
+
+	r.saveWebhook(id)
+	r.saveDeliveries()
+	delete(r.webhooks, id)
+	r.deliveries = slices.DeleteFunc(r.deliveries, func(d domain.WebhookDelivery) bool { return d.WebhookID == id })
+	r.dirty = true
+
+	return nil
+}
+
+func (r *OrderMemoryRepository) EnqueueWebhookDeliveries(_ context.Context, event domain.Event, now time.Time) error {
+	r.lock()
+	defer r.unlock()
+
+	ids := make([]int, 0, len(r.webhooks))
+	for id, webhook := range r.webhooks {
+		if webhook.Subscribes(event.Type) {
+			ids = append(ids, id)
+		}
+	}
+
+	slices.Sort(ids)
+	r.saveDeliveryCount()
+
+	for _, id := range ids {
+		queued := slices.ContainsFunc(r.deliveries, func(d domain.WebhookDelivery) bool {
+			return d.WebhookID == id && d.Event.ID == event.ID
+		})
+		if queued {
+			continue
+		}
+
+		r.lastDeliveryID++
+		r.deliveries = append(r.deliveries, domain.WebhookDelivery{
+			ID:            r.lastDeliveryID,
+			WebhookID:     id,
+			Event:         event,
+			Status:        domain.DeliveryPending,
+			NextAttemptAt: now,
+			CreatedAt:     now,
+			UpdatedAt:     now,
+		})
+		r.dirty = true
+	}
+
+	return nil
+}
+
+func (r *OrderMemoryRepository) ClaimWebhookDeliveries(_ context.Context, now, leaseUntil time.Time, limit int) ([]domain.WebhookDelivery, error) {
+	r.lock()
+	defer r.unlock()
+
+	claimed := make([]domain.WebhookDelivery, 0)
+
+	for i := range r.deliveries {
+		if len(claimed) == limit {
+			break
+		}
+
+		if d := &r.deliveries[i]; d.Status == domain.DeliveryPending && !d.NextAttemptAt.After(now) {
+			r.saveDelivery(i)
+			d.NextAttemptAt = leaseUntil
+			claimed = append(claimed, withoutHistory(*d))
+		}
+	}
+
+	return claimed, nil
+}
+
+func (r *OrderMemoryRepository) RecordWebhookAttempt(_ context.Context, attempt domain.WebhookAttempt, status domain.DeliveryStatus, nextAttemptAt time.Time) error {
+	r.lock()
+	defer r.unlock()
+
+	i := slices.IndexFunc(r.deliveries, func(d domain.WebhookDelivery) bool { return d.ID == attempt.DeliveryID })
+	if i < 0 {
+		return domain.ErrDeliveryNotFound
+	}
+
+	r.saveDelivery(i)
+
+	d := &r.deliveries[i]
+	d.History = append(d.History, attempt)
+	d.Attempts++
+	d.Status, d.NextAttemptAt, d.LastError, d.UpdatedAt = status, nextAttemptAt, attempt.Error, attempt.At
+	r.dirty = true
+
+	return nil
+}
+
+func (r *OrderMemoryRepository) ListWebhookDeliveries(_ context.Context, webhookID int, status domain.DeliveryStatus, limit int) ([]domain.WebhookDelivery, error) {
+	r.rlock()
+	defer r.runlock()
+
+	if _, ok := r.webhooks[webhookID]; !ok {
+		return nil, domain.ErrWebhookNotFound
+	}
+
+	deliveries := make([]domain.WebhookDelivery, 0)
+
+	for i := len(r.deliveries) - 1; i >= 0 && len(deliveries) < limit; i-- {
+		if d := r.deliveries[i]; d.WebhookID == webhookID && (status == "" || d.Status == status) {
+			deliveries = append(deliveries, withoutHistory(d))
+		}
+	}
+
+	return deliveries, nil
+}
+
+func (r *OrderMemoryRepository) GetWebhookDelivery(_ context.Context, webhookID int, id int64) (*domain.WebhookDelivery, error) {
+	r.rlock()
+	defer r.runlock()
+
+	i := r.deliveryIndex(webhookID, id)
+	if i < 0 {
+		return nil, domain.ErrDeliveryNotFound
+	}
+
+	delivery := r.deliveries[i]
+	delivery.History = slices.Clone(delivery.History)
+
+	return &delivery, nil
+}
+
+func (r *OrderMemoryRepository) RedeliverWebhookDelivery(_ context.Context, webhookID int, id int64, now time.Time) error {
+	r.lock()
+	defer r.unlock()
+
+	i := r.deliveryIndex(webhookID, id)
+	if i < 0 {
+		return domain.ErrDeliveryNotFound
+	}
+
+	r.saveDelivery(i)
+
+	d := &r.deliveries[i]
+	d.Status, d.Attempts, d.NextAttemptAt, d.UpdatedAt = domain.DeliveryPending, 0, now, now
+	r.dirty = true
+
+	return nil
+}
+
+// deliveryIndex returns the index of a delivery of the webhook, or -1. The caller
+// holds the lock.
+func (r *OrderMemoryRepository) deliveryIndex(webhookID int, id int64) int {
+	return slices.IndexFunc(r.deliveries, func(d domain.WebhookDelivery) bool {
+		return d.ID == id && d.WebhookID == webhookID
+	})
+}
+
+// withoutHistory returns delivery without its attempts, as listings and claims answer it
+func withoutHistory(delivery domain.WebhookDelivery) domain.WebhookDelivery {
+	delivery.History = nil
+	return delivery
+}
+
+// cloneWebhook returns a deep copy of webhook
+func cloneWebhook(webhook *domain.Webhook) *domain.Webhook {
+	clone := *webhook
+	clone.EventTypes = slices.Clone(webhook.EventTypes)
+
+	return &clone
+}
+
+// saveWebhook logs how to restore the stored webhook with the ID, missing or not.
+// The caller holds the lock.
+func (r *OrderMemoryRepository) saveWebhook(id int) {
+	if r.undo == nil {
+		return
+	}
+
+	webhook, ok := r.webhooks[id]
+	if ok {
+		webhook = cloneWebhook(webhook)
+	}
+
+	*r.undo = append(*r.undo, func() {
+		if ok {
+			r.webhooks[id] = webhook
+		} else {
+			delete(r.webhooks, id)
+		}
+	})
+}
+
+// saveDeliveryCount logs how to drop the deliveries appended to the queue. The
+// caller holds the lock.
+func (r *OrderMemoryRepository) saveDeliveryCount() {
+	if r.undo == nil {
+		return
+	}
+
+	n := len(r.deliveries)
+	*r.undo = append(*r.undo, func() { r.deliveries = r.deliveries[:n] })
+}
+
+// saveDelivery logs how to restore the i-th delivery of the queue. Attempts are
+// only ever appended, so its history is kept. The caller holds the lock.
+func (r *OrderMemoryRepository) saveDelivery(i int) {
+	if r.undo == nil {
+		return
+	}
+
+	delivery := r.deliveries[i]
+	*r.undo = append(*r.undo, func() { r.deliveries[i] = delivery })
+}
+
+// saveDeliveries logs how to restore the whole queue. The caller holds the lock.
+func (r *OrderMemoryRepository) saveDeliveries() {
+	if r.undo == nil {
+		return
+	}
+
+	deliveries := cloneDeliveries(r.deliveries)
+	*r.undo = append(*r.undo, func() { r.deliveries = deliveries })
+}
+
+// cloneDeliveries returns a deep copy of deliveries
+func cloneDeliveries(deliveries []domain.WebhookDelivery) []domain.WebhookDelivery {
+	clone := slices.Clone(deliveries)
+	for i := range clone {
+		clone[i].History = slices.Clone(clone[i].History)
+	}
+
+	return clone
+}
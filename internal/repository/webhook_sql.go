@@ -0,0 +1,385 @@
+package repository
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dyammarcano/fullcycle_clean_architecture/internal/domain"
+)
+
+// webhookColumns are the webhooks columns read by scanWebhook, in its order
+const webhookColumns = "id, url, event_types, secret, created_at, updated_at"
+
+// deliveryColumns are the webhook_deliveries columns read by scanDelivery, in its order
+const deliveryColumns = "id, webhook_id, event_id, event_type, order_id, order_version, occurred_at, data, " +
+	"status, attempts, next_attempt_at, last_error, created_at, updated_at"
+
+// joinEventTypes stores the event types of a webhook as a comma separated list
+func joinEventTypes(types []domain.EventType) string {
+	parts := make([]string, len(types))
+	for i, t := range types {
+		parts[i] = string(t)
+	}
+
+	return strings.Join(parts, ",")
+}
+
+// scanWebhook reads the webhookColumns of one row
+func scanWebhook(row rowScanner) (*domain.Webhook, error) {
+	var (
+		webhook    domain.Webhook
+		eventTypes string
+	)
+
+	if err := row.Scan(&webhook.ID, &webhook.URL, &eventTypes, &webhook.Secret, &webhook.CreatedAt, &webhook.UpdatedAt); err != nil {
+		return nil, err
+	}
+
+	webhook.EventTypes = []domain.EventType{}
+	if eventTypes != "" {
+		for _, t := range strings.Split(eventTypes, ",") {
+			webhook.EventTypes = append(webhook.EventTypes, domain.EventType(t))
+		}
+	}
+
+	webhook.CreatedAt = webhook.CreatedAt.UTC()
+	webhook.UpdatedAt = webhook.UpdatedAt.UTC()
+
+	return &webhook, nil
+}
+
+// scanDelivery reads the deliveryColumns of one row
+func scanDelivery(row rowScanner) (domain.WebhookDelivery, error) {
+	var (
+		d    domain.WebhookDelivery
+		data string
+	)
+
+	if err := row.Scan(&d.ID, &d.WebhookID, &d.Event.ID, &d.Event.Type, &d.Event.OrderID, &d.Event.OrderVersion,
+		&d.Event.OccurredAt, &data, &d.Status, &d.Attempts, &d.NextAttemptAt, &d.LastError, &d.CreatedAt, &d.UpdatedAt); err != nil {
+		return d, err
+	}
+
+	d.Event.OccurredAt = d.Event.OccurredAt.UTC()
+	d.Event.Data = json.RawMessage(data)
+	d.NextAttemptAt = d.NextAttemptAt.UTC()
+	d.CreatedAt = d.CreatedAt.UTC()
+	d.UpdatedAt = d.UpdatedAt.UTC()
+
+	return d, nil
+}
+
+// scanDeliveries reads every row with scanDelivery
+func scanDeliveries(rows *sql.Rows) ([]domain.WebhookDelivery, error) {
+	deliveries := make([]domain.WebhookDelivery, 0)
+
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		deliveries = append(deliveries, delivery)
+	}
+
+	return deliveries, rows.Err()
+}
+
+// scanAttempts reads the at, status_code and error columns of every row
+func scanAttempts(rows *sql.Rows, deliveryID int64) ([]domain.WebhookAttempt, error) {
+	attempts := make([]domain.WebhookAttempt, 0)
+
+	for rows.Next() {
+		attempt := domain.WebhookAttempt{DeliveryID: deliveryID}
+		if err := rows.Scan(&attempt.At, &attempt.StatusCode, &attempt.Error); err != nil {
+			return nil, err
+		}
+
+		attempt.At = attempt.At.UTC()
+		attempts = append(attempts, attempt)
+	}
+
+	return attempts, rows.Err()
+}
+
+// rowsFound reports whether result affected at least one row
+func rowsFound(result sql.Result) (bool, error) {
+	affected, err := result.RowsAffected()
+
+	return affected > 0, err
+}
+
+func (r *sqlRepository) CreateWebhook(ctx context.Context, webhook *domain.Webhook) error {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	createdAt := now()
+
+	row := r.conn().QueryRowContext(ctx,
+		`INSERT INTO webhooks(url, event_types, secret, created_at, updated_at) VALUES($1, $2, $3, $4, $5) RETURNING id`,
+		webhook.URL, joinEventTypes(webhook.EventTypes), webhook.Secret, createdAt, createdAt)
+	if err := row.Scan(&webhook.ID); err != nil {
+		return r.translateError(ctx, err)
+	}
+
+	webhook.CreatedAt, webhook.UpdatedAt = createdAt, createdAt
+
+	return nil
+}
+
+func (r *sqlRepository) GetWebhook(ctx context.Context, id int) (*domain.Webhook, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	webhook, err := scanWebhook(r.conn().QueryRowContext(ctx, "SELECT "+webhookColumns+" FROM webhooks WHERE id = $1", id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrWebhookNotFound
+		}
+
+		return nil, r.translateError(ctx, err)
+	}
+
+	return webhook, nil
+}
+
+func (r *sqlRepository) ListWebhooks(ctx context.Context) ([]domain.Webhook, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	return r.listWebhooks(ctx, r.conn())
+}
+
+func (r *sqlRepository) listWebhooks(ctx context.Context, conn sqlConn) ([]domain.Webhook, error) {
+	rows, err := conn.QueryContext(ctx, "SELECT "+webhookColumns+" FROM webhooks ORDER BY id")
+	if err != nil {
+		return nil, r.translateError(ctx, err)
+	}
+	defer closeRows(rows)
+
+	webhooks := make([]domain.Webhook, 0)
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, r.translateError(ctx, err)
+		}
+
+		webhooks = append(webhooks, *webhook)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, r.translateError(ctx, err)
+	}
+
+	return webhooks, nil
+}
+
+func (r *sqlRepository) UpdateWebhook(ctx context.Context, webhook *domain.Webhook) error {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	updatedAt := now()
+
+	row := r.conn().QueryRowContext(ctx,
+		`UPDATE webhooks SET url = $1, event_types = $2, secret = $3, updated_at = $4 WHERE id = $5 RETURNING created_at`,
+		webhook.URL, joinEventTypes(webhook.EventTypes), webhook.Secret, updatedAt, webhook.ID)
+	if err := row.Scan(&webhook.CreatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.ErrWebhookNotFound
+		}
+
+		return r.translateError(ctx, err)
+	}
+
+	webhook.CreatedAt, webhook.UpdatedAt = webhook.CreatedAt.UTC(), updatedAt
+
+	return nil
+}
+
+func (r *sqlRepository) DeleteWebhook(ctx context.Context, id int) error {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	// Deliveries and attempts go with the webhook through ON DELETE CASCADE
+	result, err := r.conn().ExecContext(ctx, `DELETE FROM webhooks WHERE id = $1`, id)
+	if err != nil {
+		return r.translateError(ctx, err)
+	}
+
+	found, err := rowsFound(result)
+	if err != nil {
+		return r.translateError(ctx, err)
+	}
+
+	if !found {
+		return domain.ErrWebhookNotFound
+	}
+
+	return nil
+}
+
+func (r *sqlRepository) EnqueueWebhookDeliveries(ctx context.Context, event domain.Event, now time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	return r.withTx(ctx, func(tx sqlConn) error {
+		webhooks, err := r.listWebhooks(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		for _, webhook := range webhooks {
+			if !webhook.Subscribes(event.Type) {
+				continue
+			}
+
+			_, err = tx.ExecContext(ctx,
+				`INSERT INTO webhook_deliveries(webhook_id, event_id, event_type, order_id, order_version, occurred_at, data,
+				status, next_attempt_at, created_at, updated_at)
+				VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) ON CONFLICT (webhook_id, event_id) DO NOTHING`,
+				webhook.ID, event.ID, event.Type, event.OrderID, event.OrderVersion, event.OccurredAt.UTC(), string(event.Data),
+				domain.DeliveryPending, now.UTC(), now.UTC(), now.UTC())
+			if err != nil {
+				return r.translateError(ctx, err)
+			}
+		}
+
+		return nil
+	})
+}
+
+func (r *sqlRepository) ClaimWebhookDeliveries(ctx context.Context, now, leaseUntil time.Time, limit int) ([]domain.WebhookDelivery, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	// Where the dialect supports it, SKIP LOCKED lets several workers claim disjoint
+	// batches concurrently
+	rows, err := r.conn().QueryContext(ctx,
+		`UPDATE webhook_deliveries SET next_attempt_at = $1
+		WHERE id IN (SELECT id FROM webhook_deliveries WHERE status = $2 AND next_attempt_at <= $3 ORDER BY id LIMIT $4`+r.dialect.skipLocked+`)
+		RETURNING `+deliveryColumns, leaseUntil.UTC(), domain.DeliveryPending, now.UTC(), limit)
+	if err != nil {
+		return nil, r.translateError(ctx, err)
+	}
+	defer closeRows(rows)
+
+	deliveries, err := scanDeliveries(rows)
+	if err != nil {
+		return nil, r.translateError(ctx, err)
+	}
+
+	slices.SortFunc(deliveries, func(a, b domain.WebhookDelivery) int { return cmp.Compare(a.ID, b.ID) })
+
+	return deliveries, nil
+}
+
+func (r *sqlRepository) RecordWebhookAttempt(ctx context.Context, attempt domain.WebhookAttempt, status domain.DeliveryStatus, nextAttemptAt time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	return r.withTx(ctx, func(tx sqlConn) error {
+		result, err := tx.ExecContext(ctx,
+			`UPDATE webhook_deliveries SET status = $1, attempts = attempts + 1, next_attempt_at = $2, last_error = $3, updated_at = $4
+			WHERE id = $5`,
+			status, nextAttemptAt.UTC(), attempt.Error, attempt.At.UTC(), attempt.DeliveryID)
+		if err != nil {
+			return r.translateError(ctx, err)
+		}
+
+		found, err := rowsFound(result)
+		if err != nil {
+			return r.translateError(ctx, err)
+		}
+
+		if !found {
+			return domain.ErrDeliveryNotFound
+		}
+
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO webhook_attempts(delivery_id, at, status_code, error) VALUES($1, $2, $3, $4)`,
+			attempt.DeliveryID, attempt.At.UTC(), attempt.StatusCode, attempt.Error)
+
+		return r.translateError(ctx, err)
+	})
+}
+
+func (r *sqlRepository) ListWebhookDeliveries(ctx context.Context, webhookID int, status domain.DeliveryStatus, limit int) ([]domain.WebhookDelivery, error) {
+	if _, err := r.GetWebhook(ctx, webhookID); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	rows, err := r.conn().QueryContext(ctx,
+		"SELECT "+deliveryColumns+" FROM webhook_deliveries WHERE webhook_id = $1 AND ($2 = '' OR status = $2) ORDER BY id DESC LIMIT $3",
+		webhookID, status, limit)
+	if err != nil {
+		return nil, r.translateError(ctx, err)
+	}
+	defer closeRows(rows)
+
+	deliveries, err := scanDeliveries(rows)
+	if err != nil {
+		return nil, r.translateError(ctx, err)
+	}
+
+	return deliveries, nil
+}
+
+func (r *sqlRepository) GetWebhookDelivery(ctx context.Context, webhookID int, id int64) (*domain.WebhookDelivery, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	row := r.conn().QueryRowContext(ctx, "SELECT "+deliveryColumns+" FROM webhook_deliveries WHERE id = $1 AND webhook_id = $2", id, webhookID)
+
+	delivery, err := scanDelivery(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrDeliveryNotFound
+		}
+
+		return nil, r.translateError(ctx, err)
+	}
+
+	rows, err := r.conn().QueryContext(ctx, `SELECT at, status_code, error FROM webhook_attempts WHERE delivery_id = $1 ORDER BY id`, id)
+	if err != nil {
+		return nil, r.translateError(ctx, err)
+	}
+	defer closeRows(rows)
+
+	if delivery.History, err = scanAttempts(rows, id); err != nil {
+		return nil, r.translateError(ctx, err)
+	}
+
+	return &delivery, nil
+}
+
+func (r *sqlRepository) RedeliverWebhookDelivery(ctx context.Context, webhookID int, id int64, now time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	result, err := r.conn().ExecContext(ctx,
+		`UPDATE webhook_deliveries SET status = $1, attempts = 0, next_attempt_at = $2, updated_at = $3 WHERE id = $4 AND webhook_id = $5`,
+		domain.DeliveryPending, now.UTC(), now.UTC(), id, webhookID)
+	if err != nil {
+		return r.translateError(ctx, err)
+	}
+
+	found, err := rowsFound(result)
+	if err != nil {
+		return r.translateError(ctx, err)
+	}
+
+	if !found {
+		return domain.ErrDeliveryNotFound
+	}
+
+	return nil
+}
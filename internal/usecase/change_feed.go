@@ -0,0 +1,198 @@
+package usecase
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dyammarcano/fullcycle_clean_architecture/internal/domain"
+)
+
+// DefaultChangeBufferSize is the number of changes a ChangeFeed keeps for resuming
+const DefaultChangeBufferSize = 1024
+
+// Change is an order event as seen by the subscribers of a ChangeFeed
+type Change struct {
+	// Seq orders the changes of the feed; its string form is the resume cursor
+	Seq   uint64
+	Event domain.Event
+}
+
+// Cursor returns the token that resumes a subscription right after the change
+func (c Change) Cursor() string {
+	return strconv.FormatUint(c.Seq, 10)
+}
+
+// ChangeFilter selects changes by order ID and event type; an empty list matches all
+type ChangeFilter struct {
+	OrderIDs []int
+	Types    []domain.EventType
+}
+
+// Matches reports whether event passes the filter
+func (f ChangeFilter) Matches(event domain.Event) bool {
+	return (len(f.OrderIDs) == 0 || slices.Contains(f.OrderIDs, event.OrderID)) &&
+		(len(f.Types) == 0 || slices.Contains(f.Types, event.Type))
+}
+
+// ChangeFeed fans the changes committed by the use case out to in-process
+// subscribers, such as streaming endpoints. It keeps the latest changes in a
+// bounded buffer so subscriptions can resume from a cursor; a subscriber that falls
+// further behind than the buffer loses its place rather than slowing the writers.
+// Only the changes of this process are seen.
+//
+// The use case publishes after the commit, so two concurrent writes to an order
+// can reach the feed in the reverse order of their commits; the feed drops an
+// event that is not newer than the last version published for its order.
+type ChangeFeed struct {
+	mu sync.Mutex
+	// buffer is a ring of the latest changes, the oldest at start
+	buffer []Change
+	start  int
+	// last is the Seq of the latest change
+	last uint64
+	// wake is closed and replaced whenever a change is added
+	wake chan struct{}
+	// versions is the latest order version of the orders with buffered changes
+	versions map[int]int
+}
+
+// Publish adds event to the feed, which makes the feed a domain.EventPublisher.
+// An event older than the last one published for the same order is dropped.
+func (f *ChangeFeed) Publish(_ context.Context, event domain.Event) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if latest, ok := f.versions[event.OrderID]; ok && event.OrderVersion > 0 && event.OrderVersion <= latest {
+		return nil
+	}
+
+	f.last++
+	change := Change{Seq: f.last, Event: event}
+
+	if len(f.buffer) < cap(f.buffer) {
+		f.buffer = append(f.buffer, change)
+	} else {
+		// Forget the version of an order once its latest change leaves the buffer
+		evicted := f.buffer[f.start].Event
+		if f.versions[evicted.OrderID] == evicted.OrderVersion {
+			delete(f.versions, evicted.OrderID)
+		}
+
+		f.buffer[f.start] = change
+		f.start = (f.start + 1) % len(f.buffer)
+	}
+
+	f.versions[event.OrderID] = event.OrderVersion
+
+	close(f.wake)
+	f.wake = make(chan struct{})
+
+	return nil
+}
+
+// Subscribe returns a subscription to the changes matching filter published from
+// now on
+func (f *ChangeFeed) Subscribe(filter ChangeFilter) *ChangeSubscription {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return &ChangeSubscription{feed: f, filter: filter, cursor: f.last}
+}
+
+// Resume returns a subscription to the changes matching filter published after
+// the one cursor points at. It fails with ErrChangesExpired when those changes
+// are no longer buffered, or were published by another process run.
+func (f *ChangeFeed) Resume(filter ChangeFilter, cursor string) (*ChangeSubscription, error) {
+	seq, err := strconv.ParseUint(cursor, 10, 64)
+	if err != nil {
+		return nil, domain.NewValidationError(domain.FieldError{Field: "cursor", Rule: "cursor", Message: "must be a cursor returned with a change"})
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if seq+1 < f.oldest() || seq > f.last {
+		return nil, domain.ErrChangesExpired
+	}
+
+	return &ChangeSubscription{feed: f, filter: filter, cursor: seq}, nil
+}
+
+// oldest returns the Seq of the oldest buffered change, or the next one when the
+// buffer is empty
+func (f *ChangeFeed) oldest() uint64 {
+	if len(f.buffer) == 0 {
+		return f.last + 1
+	}
+
+	return f.buffer[f.start].Seq
+}
+
+// ChangeSubscription reads the changes of a feed in order. It is not safe for
+// concurrent use.
+type ChangeSubscription struct {
+	feed   *ChangeFeed
+	filter ChangeFilter
+	// cursor is the Seq of the latest change read
+	cursor uint64
+}
+
+// Cursor returns the token that resumes after the latest change read, or the
+// position the subscription started at
+func (s *ChangeSubscription) Cursor() string {
+	return strconv.FormatUint(s.cursor, 10)
+}
+
+// Next returns the next matching change, waiting for one until ctx is done. It
+// fails with ErrChangesExpired once the subscriber fell so far behind that the
+// changes it has not read were dropped from the buffer.
+func (s *ChangeSubscription) Next(ctx context.Context) (Change, error) {
+	f := s.feed
+
+	for {
+		f.mu.Lock()
+
+		if s.cursor+1 < f.oldest() {
+			f.mu.Unlock()
+			return Change{}, domain.ErrChangesExpired
+		}
+
+		for s.cursor < f.last {
+			s.cursor++
+
+			change := f.buffer[(f.start+int(s.cursor-f.oldest()))%len(f.buffer)]
+			if s.filter.Matches(change.Event) {
+				f.mu.Unlock()
+				return change, nil
+			}
+		}
+
+		wake := f.wake
+		f.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return Change{}, ctx.Err()
+		case <-wake:
+		}
+	}
+}
+
+// NewChangeFeed returns a feed buffering the latest size changes,
+// DefaultChangeBufferSize when size is zero. Sequence numbers start from the
+// current time, so cursors of a previous process run do not resume.
+func NewChangeFeed(size int) *ChangeFeed {
+	if size <= 0 {
+		size = DefaultChangeBufferSize
+	}
+
+	return &ChangeFeed{
+		buffer:   make([]Change, 0, size),
+		last:     uint64(time.Now().UnixMicro()),
+		wake:     make(chan struct{}),
+		versions: make(map[int]int, size),
+	}
+}
@@ -0,0 +1,202 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dyammarcano/fullcycle_clean_architecture/internal/domain"
+	"github.com/dyammarcano/fullcycle_clean_architecture/internal/repository"
+)
+
+func TestChangeFeed(t *testing.T) {
+	ctx := context.Background()
+	feed := NewChangeFeed(3)
+
+	publish := func(orderID int, eventType domain.EventType) {
+		_ = feed.Publish(ctx, domain.Event{Type: eventType, OrderID: orderID})
+	}
+
+	start := feed.Subscribe(ChangeFilter{})
+	cursor := start.Cursor()
+
+	all := feed.Subscribe(ChangeFilter{})
+	filtered := feed.Subscribe(ChangeFilter{OrderIDs: []int{2}, Types: []domain.EventType{domain.EventOrderUpdated}})
+
+	publish(1, domain.EventOrderCreated)
+	publish(2, domain.EventOrderCreated)
+	publish(2, domain.EventOrderUpdated)
+
+	first, err := all.Next(ctx)
+	if err != nil || first.Event.OrderID != 1 {
+		t.Fatalf("Next = %+v, %v, want the first change", first, err)
+	}
+
+	if change, err := filtered.Next(ctx); err != nil || change.Event.OrderID != 2 || change.Event.Type != domain.EventOrderUpdated {
+		t.Fatalf("Filtered Next = %+v, %v, want the update of order 2", change, err)
+	}
+
+	resumed, err := feed.Resume(ChangeFilter{}, first.Cursor())
+	if err != nil {
+		t.Fatalf("Error resuming: %v", err)
+	}
+
+	if change, _ := resumed.Next(ctx); change.Seq != first.Seq+1 {
+		t.Fatalf("Resumed at %d, want %d", change.Seq, first.Seq+1)
+	}
+
+	// The buffer holds 3 changes: the first one is dropped
+	publish(3, domain.EventOrderCreated)
+
+	if _, err = feed.Resume(ChangeFilter{}, cursor); !errors.Is(err, domain.ErrChangesExpired) {
+		t.Fatalf("Resume before the buffer = %v, want ErrChangesExpired", err)
+	}
+
+	publish(4, domain.EventOrderCreated)
+
+	if _, err = start.Next(ctx); !errors.Is(err, domain.ErrChangesExpired) {
+		t.Fatalf("Next of a subscriber behind the buffer = %v, want ErrChangesExpired", err)
+	}
+
+	if _, err = feed.Resume(ChangeFilter{}, "12345"); !errors.Is(err, domain.ErrChangesExpired) {
+		t.Fatalf("Resume with a cursor of another run = %v, want ErrChangesExpired", err)
+	}
+
+	if _, err = feed.Resume(ChangeFilter{}, "abc"); !errors.Is(err, domain.ErrValidation) {
+		t.Fatalf("Resume with an invalid cursor = %v, want a validation error", err)
+	}
+
+	waiting := feed.Subscribe(ChangeFilter{})
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		publish(5, domain.EventOrderDeleted)
+	}()
+
+	if change, err := waiting.Next(ctx); err != nil || change.Event.OrderID != 5 {
+		t.Fatalf("Next = %+v, %v, want the change published while waiting", change, err)
+	}
+
+	timeout, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+
+	if _, err = waiting.Next(timeout); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Next without changes = %v, want the context error", err)
+	}
+}
+
+func TestChangeFeedStaleVersions(t *testing.T) {
+	ctx := context.Background()
+	feed := NewChangeFeed(2)
+	sub := feed.Subscribe(ChangeFilter{})
+
+	publish := func(orderID, version int) {
+		_ = feed.Publish(ctx, domain.Event{Type: domain.EventOrderUpdated, OrderID: orderID, OrderVersion: version})
+	}
+
+	// Version 3 committed first but reached the feed after version 4
+	publish(1, 4)
+	publish(1, 3)
+	publish(2, 1)
+
+	for _, want := range []domain.Event{{OrderID: 1, OrderVersion: 4}, {OrderID: 2, OrderVersion: 1}} {
+		change, err := sub.Next(ctx)
+		if err != nil || change.Event.OrderID != want.OrderID || change.Event.OrderVersion != want.OrderVersion {
+			t.Fatalf("Next = %+v, %v, want order %d version %d", change.Event, err, want.OrderID, want.OrderVersion)
+		}
+	}
+
+	// Once the changes of order 1 left the buffer its version is forgotten
+	publish(2, 2)
+
+	if len(feed.versions) != 1 {
+		t.Errorf("Expected only the buffered orders to be tracked, got %v", feed.versions)
+	}
+}
+
+// pausingRepository runs afterCommit, once, when a transaction has committed
+type pausingRepository struct {
+	domain.OrderRepository
+
+	mu          sync.Mutex
+	afterCommit func()
+}
+
+func (r *pausingRepository) Transact(ctx context.Context, fn func(repo domain.OrderRepository, outbox domain.EventOutbox) error) error {
+	err := r.OrderRepository.Transact(ctx, fn)
+
+	r.mu.Lock()
+	afterCommit := r.afterCommit
+	r.afterCommit = nil
+	r.mu.Unlock()
+
+	if afterCommit != nil {
+		afterCommit()
+	}
+
+	return err
+}
+
+func TestChangeFeedConcurrentWrites(t *testing.T) {
+	ctx := context.Background()
+
+	memory, err := repository.NewOrderMemoryRepository()
+	if err != nil {
+		t.Fatalf("Error creating repository: %v", err)
+	}
+
+	repo := &pausingRepository{OrderRepository: memory}
+	useCase := NewOrderUseCase(repo)
+
+	created, err := useCase.CreateOrder(ctx, []byte(`{"item":"Bag","lines":[{"sku":"BAG-1","quantity":1,"unitPrice":{"value":"10.00","currency":"USD"}}]}`))
+	if err != nil {
+		t.Fatalf("Error creating order: %v", err)
+	}
+
+	sub, err := useCase.WatchChanges(ChangeFilter{OrderIDs: []int{created.ID}}, "")
+	if err != nil {
+		t.Fatalf("Error watching: %v", err)
+	}
+
+	update := func(item string) {
+		if _, err := useCase.UpdateOrder(ctx, created.ID, []byte(`{"item":"`+item+`","lines":[{"sku":"HAT-1","quantity":1,"unitPrice":{"value":"5.00","currency":"USD"}}]}`)); err != nil {
+			t.Errorf("Error updating order: %v", err)
+		}
+	}
+
+	committed, resume := make(chan struct{}), make(chan struct{})
+	repo.afterCommit = func() {
+		close(committed)
+		<-resume
+	}
+
+	// The first update commits version 2 but is held before publishing, while the
+	// second one commits and publishes version 3
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+		update("Hat")
+	}()
+
+	<-committed
+	update("Cap")
+	close(resume)
+	wg.Wait()
+
+	change, err := sub.Next(ctx)
+	if err != nil || change.Event.OrderVersion != created.Version+2 {
+		t.Fatalf("Next = %+v, %v, want version %d", change.Event, err, created.Version+2)
+	}
+
+	timeout, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+
+	if stale, err := sub.Next(timeout); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the older version to be dropped, got %+v", stale.Event)
+	}
+}
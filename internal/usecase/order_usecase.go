@@ -1,51 +1,289 @@
 package usecase
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
 
 	"github.com/dyammarcano/fullcycle_clean_architecture/internal/domain"
+	"github.com/dyammarcano/fullcycle_clean_architecture/internal/entity"
 )
 
+// OrderUseCase applies the order rules. Every change is stored together with a
+// domain event in the outbox of the repository, in the same transaction, and the
+// event is published to Changes once committed.
 type OrderUseCase struct {
 	OrderRepo domain.OrderRepository
+	// Changes notifies the streaming endpoints of the committed changes
+	Changes *ChangeFeed
 }
 
-func (o *OrderUseCase) ListOrders() ([]*domain.Order, error) {
-	return o.OrderRepo.ListOrders()
+// ListOrders returns one page of orders. pageToken is the NextPageToken of the
+// previous page of the same listing, empty for the first page.
+func (o *OrderUseCase) ListOrders(ctx context.Context, query domain.ListOrdersQuery, pageToken string) (*domain.OrderPage, error) {
+	query.Filter.Deleted = false
+
+	return o.listOrders(ctx, query, pageToken)
 }
 
-func (o *OrderUseCase) CreateOrder(orderBytes []byte) (*domain.Order, error) {
-	order := &domain.Order{}
-	if err := json.Unmarshal(orderBytes, order); err != nil {
+// ListDeletedOrders returns one page of soft-deleted orders, paginated like
+// ListOrders. It is meant for administrators only.
+func (o *OrderUseCase) ListDeletedOrders(ctx context.Context, query domain.ListOrdersQuery, pageToken string) (*domain.OrderPage, error) {
+	query.Filter.Deleted = true
+
+	return o.listOrders(ctx, query, pageToken)
+}
+
+func (o *OrderUseCase) listOrders(ctx context.Context, query domain.ListOrdersQuery, pageToken string) (*domain.OrderPage, error) {
+	if err := entity.ValidateListOrdersQuery(&query); err != nil {
+		return nil, err
+	}
+
+	if pageToken != "" {
+		after, err := domain.DecodePageToken(query, pageToken)
+		if err != nil {
+			return nil, err
+		}
+
+		query.After = after
+	}
+
+	page, err := o.OrderRepo.ListOrders(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	if page.HasMore && len(page.Orders) > 0 {
+		last := page.Orders[len(page.Orders)-1]
+		page.NextPageToken = domain.EncodePageToken(query, domain.CursorFor(last, query.SortBy))
+	}
+
+	return page, nil
+}
+
+func (o *OrderUseCase) CreateOrder(ctx context.Context, orderBytes []byte) (*domain.Order, error) {
+	order, err := decodeOrder(orderBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	// The repository issues the ID, so one sent by the client is ignored
+	order.ID = 0
+
+	valid, err := entity.NewOrder(order.ID, order.Item, order.Lines)
+	if err != nil {
 		return nil, err
 	}
 
-	return o.OrderRepo.CreateOrder(order)
+	order.Amount = valid.Amount
+	order.Status = domain.StatusPending
+
+	return o.writeOrder(ctx, domain.EventOrderCreated, func(repo domain.OrderRepository) (*domain.Order, *domain.StatusTransition, error) {
+		created, err := repo.CreateOrder(ctx, order)
+		return created, nil, err
+	})
 }
 
-func (o *OrderUseCase) GetOrderByID(id int) (*domain.Order, error) {
-	return o.OrderRepo.GetOrderByID(id)
+func (o *OrderUseCase) GetOrderByID(ctx context.Context, id int) (*domain.Order, error) {
+	return o.OrderRepo.GetOrderByID(ctx, id)
 }
 
-func (o *OrderUseCase) UpdateOrder(id int, orderBytes []byte) (*domain.Order, error) {
-	order := &domain.Order{}
-	if err := json.Unmarshal(orderBytes, order); err != nil {
+// UpdateOrder replaces the item and lines of an order. A non zero version in the
+// order makes the update conditional on the stored version.
+func (o *OrderUseCase) UpdateOrder(ctx context.Context, id int, orderBytes []byte) (*domain.Order, error) {
+	order, err := decodeOrder(orderBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = entity.ValidateVersion(order.Version); err != nil {
+		return nil, err
+	}
+
+	valid, err := entity.NewOrder(id, order.Item, order.Lines)
+	if err != nil {
+		return nil, err
+	}
+
+	order.Amount = valid.Amount
+
+	return o.writeOrder(ctx, domain.EventOrderUpdated, func(repo domain.OrderRepository) (*domain.Order, *domain.StatusTransition, error) {
+		if err := repo.UpdateOrder(ctx, id, order); err != nil {
+			return nil, nil, err
+		}
+
+		updated, err := repo.GetOrderByID(ctx, id)
+		return updated, nil, err
+	})
+}
+
+// DeleteOrder soft-deletes an order; it can be restored until it is purged. A non
+// zero version makes the delete conditional on the stored version.
+func (o *OrderUseCase) DeleteOrder(ctx context.Context, id int, version int) error {
+	if err := entity.ValidateVersion(version); err != nil {
+		return err
+	}
+
+	_, err := o.writeOrder(ctx, domain.EventOrderDeleted, func(repo domain.OrderRepository) (*domain.Order, *domain.StatusTransition, error) {
+		deleted, err := repo.DeleteOrder(ctx, id, version)
+		return deleted, nil, err
+	})
+
+	return err
+}
+
+// RestoreOrder brings back a soft-deleted order. It is meant for administrators only.
+func (o *OrderUseCase) RestoreOrder(ctx context.Context, id int) (*domain.Order, error) {
+	return o.writeOrder(ctx, domain.EventOrderRestored, func(repo domain.OrderRepository) (*domain.Order, *domain.StatusTransition, error) {
+		if err := repo.RestoreOrder(ctx, id); err != nil {
+			return nil, nil, err
+		}
+
+		restored, err := repo.GetOrderByID(ctx, id)
+		return restored, nil, err
+	})
+}
+
+// PurgeDeletedOrders permanently removes the orders soft-deleted more than
+// retention ago and returns how many were removed
+func (o *OrderUseCase) PurgeDeletedOrders(ctx context.Context, retention time.Duration) (int, error) {
+	if err := entity.ValidateRetention(retention); err != nil {
+		return 0, err
+	}
+
+	return o.OrderRepo.PurgeDeletedOrders(ctx, time.Now().Add(-retention))
+}
+
+// TransitionOrder moves an order to the next status when the lifecycle allows it
+// and records the transition with its reason
+func (o *OrderUseCase) TransitionOrder(ctx context.Context, id int, next domain.OrderStatus, reason string) (*domain.Order, error) {
+	if err := entity.ValidateTransitionReason(reason); err != nil {
+		return nil, err
+	}
+
+	return o.writeOrder(ctx, domain.EventOrderStatusChanged, func(repo domain.OrderRepository) (*domain.Order, *domain.StatusTransition, error) {
+		order, err := repo.GetOrderByID(ctx, id)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		transition, err := domain.NewStatusTransition(order.Status, next, reason, time.Now().UTC())
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if err = repo.ChangeOrderStatus(ctx, id, transition); err != nil {
+			return nil, nil, err
+		}
+
+		changed, err := repo.GetOrderByID(ctx, id)
+		return changed, &transition, err
+	})
+}
+
+// ConfirmOrder moves a pending order to confirmed
+func (o *OrderUseCase) ConfirmOrder(ctx context.Context, id int, reason string) (*domain.Order, error) {
+	return o.TransitionOrder(ctx, id, domain.StatusConfirmed, reason)
+}
+
+// PayOrder moves a confirmed order to paid
+func (o *OrderUseCase) PayOrder(ctx context.Context, id int, reason string) (*domain.Order, error) {
+	return o.TransitionOrder(ctx, id, domain.StatusPaid, reason)
+}
+
+// ShipOrder moves a paid order to shipped
+func (o *OrderUseCase) ShipOrder(ctx context.Context, id int, reason string) (*domain.Order, error) {
+	return o.TransitionOrder(ctx, id, domain.StatusShipped, reason)
+}
+
+// DeliverOrder moves a shipped order to delivered
+func (o *OrderUseCase) DeliverOrder(ctx context.Context, id int, reason string) (*domain.Order, error) {
+	return o.TransitionOrder(ctx, id, domain.StatusDelivered, reason)
+}
+
+// CancelOrder cancels an order that has not been paid yet
+func (o *OrderUseCase) CancelOrder(ctx context.Context, id int, reason string) (*domain.Order, error) {
+	return o.TransitionOrder(ctx, id, domain.StatusCancelled, reason)
+}
+
+// RefundOrder refunds a paid or delivered order
+func (o *OrderUseCase) RefundOrder(ctx context.Context, id int, reason string) (*domain.Order, error) {
+	return o.TransitionOrder(ctx, id, domain.StatusRefunded, reason)
+}
+
+// ListStatusHistory returns the status transitions of an order, oldest first
+func (o *OrderUseCase) ListStatusHistory(ctx context.Context, id int) ([]domain.StatusTransition, error) {
+	return o.OrderRepo.ListStatusHistory(ctx, id)
+}
+
+// WatchChanges subscribes to the committed order changes matching filter. With a
+// cursor the subscription resumes after the change it names, failing with
+// ErrChangesExpired when that change is no longer buffered; otherwise it starts
+// with the next change.
+func (o *OrderUseCase) WatchChanges(filter ChangeFilter, cursor string) (*ChangeSubscription, error) {
+	if err := entity.ValidateChangeFilter(filter.OrderIDs, filter.Types); err != nil {
 		return nil, err
 	}
 
-	if err := o.OrderRepo.UpdateOrder(id, order); err != nil {
+	if cursor == "" {
+		return o.Changes.Subscribe(filter), nil
+	}
+
+	return o.Changes.Resume(filter, cursor)
+}
+
+// writeOrder runs write in a repository transaction and stores, in the same
+// transaction, an event of eventType about the order and transition it returns
+func (o *OrderUseCase) writeOrder(ctx context.Context, eventType domain.EventType,
+	write func(repo domain.OrderRepository) (*domain.Order, *domain.StatusTransition, error)) (*domain.Order, error) {
+	var (
+		order  *domain.Order
+		events []domain.Event
+	)
+
+	err := o.OrderRepo.Transact(ctx, func(repo domain.OrderRepository, outbox domain.EventOutbox) error {
+		written, transition, err := write(repo)
+		if err != nil {
+			return err
+		}
+
+		event, err := domain.NewOrderEvent(eventType, written, transition)
+		if err != nil {
+			return err
+		}
+
+		order, events = written, []domain.Event{event}
+
+		// AppendEvents sets the outbox ID of the event
+		return outbox.AppendEvents(ctx, events...)
+	})
+	if err != nil {
 		return nil, err
 	}
 
-	order.ID = id
+	for _, event := range events {
+		_ = o.Changes.Publish(ctx, event)
+	}
 
 	return order, nil
 }
 
-func (o *OrderUseCase) DeleteOrder(id int) error {
-	return o.OrderRepo.DeleteOrder(id)
+// decodeOrder reads a JSON encoded order, reporting malformed input as a validation error
+func decodeOrder(orderBytes []byte) (*domain.Order, error) {
+	order := &domain.Order{}
+	if err := json.Unmarshal(orderBytes, order); err != nil {
+		if errors.Is(err, domain.ErrValidation) {
+			return nil, err
+		}
+
+		return nil, fmt.Errorf("%w: %w", domain.ErrValidation, err)
+	}
+
+	return order, nil
 }
 
 func NewOrderUseCase(repo domain.OrderRepository) *OrderUseCase {
-	return &OrderUseCase{OrderRepo: repo}
+	return &OrderUseCase{OrderRepo: repo, Changes: NewChangeFeed(DefaultChangeBufferSize)}
 }
@@ -0,0 +1,143 @@
+package usecase
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/dyammarcano/fullcycle_clean_architecture/internal/domain"
+)
+
+// Defaults of RelayOptions fields left at zero
+const (
+	DefaultRelayInterval   = time.Second
+	DefaultRelayBatchSize  = 100
+	DefaultRelayLease      = 30 * time.Second
+	DefaultRelayMinBackoff = time.Second
+	DefaultRelayMaxBackoff = 5 * time.Minute
+)
+
+// RelayOptions tunes an OutboxRelay
+type RelayOptions struct {
+	// Interval is how often the outbox is polled for due events
+	Interval time.Duration
+	// BatchSize bounds the events claimed at once
+	BatchSize int
+	// Lease hides claimed events from other relays while they are being published
+	Lease time.Duration
+	// MinBackoff and MaxBackoff bound the delay before a failed event is retried,
+	// which doubles with every failure
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+// OutboxRelay delivers the events of an outbox to a publisher. An event leaves the
+// outbox only once it was published, so each one is delivered at least once; a
+// failed event is retried with exponential backoff and does not hold back the
+// others, so consumers must order events by OrderVersion.
+type OutboxRelay struct {
+	Outbox    domain.EventOutbox
+	Publisher domain.EventPublisher
+
+	options RelayOptions
+	now     func() time.Time
+	poller  *poller
+}
+
+// Start relays events every interval until Shutdown is called
+func (r *OutboxRelay) Start() error {
+	return r.poller.run("Outbox relay", func(ctx context.Context) error {
+		_, err := r.RelayPending(ctx)
+		return err
+	})
+}
+
+// Shutdown stops the relay and waits for the batch being published, at most until
+// ctx is done. Events left unpublished are retried once their lease expires.
+func (r *OutboxRelay) Shutdown(ctx context.Context) error {
+	return r.poller.shutdown(ctx)
+}
+
+// RelayPending publishes the due events until none is left and returns how many
+// were delivered
+func (r *OutboxRelay) RelayPending(ctx context.Context) (int, error) {
+	delivered := 0
+
+	for {
+		now := r.now()
+
+		events, err := r.Outbox.ClaimEvents(ctx, now, now.Add(r.options.Lease), r.options.BatchSize)
+		if err != nil {
+			return delivered, err
+		}
+
+		for _, event := range events {
+			if err = r.Publisher.Publish(ctx, event.Event); err != nil {
+				if ctx.Err() != nil {
+					return delivered, ctx.Err()
+				}
+
+				slog.Warn("Publishing event failed",
+					slog.Int64("event_id", event.ID),
+					slog.String("type", string(event.Type)),
+					slog.Int("attempts", event.Attempts+1),
+					slog.String("error", err.Error()),
+				)
+
+				retryAt := r.now().Add(r.backoff(event.Attempts + 1))
+				if err = r.Outbox.MarkEventFailed(ctx, event.ID, retryAt, err.Error()); err != nil {
+					return delivered, err
+				}
+
+				continue
+			}
+
+			if err = r.Outbox.MarkEventDelivered(ctx, event.ID); err != nil {
+				return delivered, err
+			}
+
+			delivered++
+		}
+
+		if len(events) < r.options.BatchSize {
+			return delivered, nil
+		}
+	}
+}
+
+// backoff returns the delay before the retry that follows the given number of failures
+func (r *OutboxRelay) backoff(failures int) time.Duration {
+	return exponentialBackoff(r.options.MinBackoff, r.options.MaxBackoff, failures)
+}
+
+// NewOutboxRelay returns a relay from outbox to publisher. Options left at zero
+// take their defaults.
+func NewOutboxRelay(outbox domain.EventOutbox, publisher domain.EventPublisher, options RelayOptions) *OutboxRelay {
+	if options.Interval <= 0 {
+		options.Interval = DefaultRelayInterval
+	}
+
+	if options.BatchSize <= 0 {
+		options.BatchSize = DefaultRelayBatchSize
+	}
+
+	if options.Lease <= 0 {
+		options.Lease = DefaultRelayLease
+	}
+
+	if options.MinBackoff <= 0 {
+		options.MinBackoff = DefaultRelayMinBackoff
+	}
+
+	if options.MaxBackoff < options.MinBackoff {
+		options.MaxBackoff = max(DefaultRelayMaxBackoff, options.MinBackoff)
+	}
+
+	return &OutboxRelay{
+		Outbox:    outbox,
+		Publisher: publisher,
+		options:   options,
+		now:       time.Now,
+		poller:    newPoller(options.Interval),
+	}
+}
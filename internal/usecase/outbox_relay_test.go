@@ -0,0 +1,118 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/dyammarcano/fullcycle_clean_architecture/internal/domain"
+	"github.com/dyammarcano/fullcycle_clean_architecture/internal/repository"
+)
+
+// flakyPublisher fails the first failures publications and records the others
+type flakyPublisher struct {
+	failures  int
+	published []domain.Event
+}
+
+func (p *flakyPublisher) Publish(_ context.Context, event domain.Event) error {
+	if p.failures > 0 {
+		p.failures--
+		return errors.New("broker unavailable")
+	}
+
+	p.published = append(p.published, event)
+
+	return nil
+}
+
+func TestOutboxRelay(t *testing.T) {
+	ctx := context.Background()
+
+	repo, err := repository.NewOrderMemoryRepository()
+	if err != nil {
+		t.Fatalf("Error creating repository: %v", err)
+	}
+
+	useCase := NewOrderUseCase(repo)
+
+	created, err := useCase.CreateOrder(ctx, []byte(`{"item":"Bag","lines":[{"sku":"BAG-1","quantity":1,"unitPrice":{"value":"10.00","currency":"USD"}}]}`))
+	if err != nil {
+		t.Fatalf("Error creating order: %v", err)
+	}
+
+	if _, err = useCase.ConfirmOrder(ctx, created.ID, ""); err != nil {
+		t.Fatalf("Error confirming order: %v", err)
+	}
+
+	if err = useCase.DeleteOrder(ctx, created.ID, 0); err != nil {
+		t.Fatalf("Error deleting order: %v", err)
+	}
+
+	if _, err = useCase.UpdateOrder(ctx, created.ID, []byte(`{"item":"Hat"}`)); err == nil {
+		t.Fatal("Expected an invalid update to fail")
+	}
+
+	publisher := &flakyPublisher{failures: 1}
+	relay := NewOutboxRelay(repo, publisher, RelayOptions{MinBackoff: time.Second, MaxBackoff: 4 * time.Second})
+
+	now := time.Now()
+	relay.now = func() time.Time { return now }
+
+	delivered, err := relay.RelayPending(ctx)
+	if err != nil || delivered != 2 {
+		t.Fatalf("RelayPending = %d, %v, want the 2 events after the failed one", delivered, err)
+	}
+
+	now = now.Add(time.Second)
+
+	if delivered, err = relay.RelayPending(ctx); err != nil || delivered != 1 {
+		t.Fatalf("RelayPending after the backoff = %d, %v, want the retried event", delivered, err)
+	}
+
+	var types []domain.EventType
+	for _, event := range publisher.published {
+		types = append(types, event.Type)
+	}
+
+	want := []domain.EventType{domain.EventOrderStatusChanged, domain.EventOrderDeleted, domain.EventOrderCreated}
+	if !slices.Equal(types, want) {
+		t.Fatalf("Published %v, want %v", types, want)
+	}
+
+	changed, err := publisher.published[0].OrderData()
+	if err != nil || changed.Transition == nil || changed.Transition.To != domain.StatusConfirmed || changed.Order.Version != 2 {
+		t.Errorf("Status change data = %+v (%v)", changed, err)
+	}
+
+	if deleted := publisher.published[1]; deleted.OrderVersion != 3 {
+		t.Errorf("Deleted event version = %d, want 3", deleted.OrderVersion)
+	}
+
+	stored, err := repo.ListOrders(ctx, domain.ListOrdersQuery{Filter: domain.OrderFilter{Deleted: true}})
+	if err != nil || len(stored.Orders) != 1 {
+		t.Fatalf("Deleted orders = %+v (%v)", stored, err)
+	}
+
+	deleted, err := publisher.published[1].OrderData()
+	if err != nil || deleted.Order.DeletedAt == nil || !deleted.Order.DeletedAt.Equal(*stored.Orders[0].DeletedAt) ||
+		!deleted.Order.UpdatedAt.Equal(stored.Orders[0].UpdatedAt) {
+		t.Errorf("Deleted event data = %+v (%v), want the stored order %+v", deleted, err, stored.Orders[0])
+	}
+
+	if delivered, err = relay.RelayPending(ctx); err != nil || delivered != 0 {
+		t.Errorf("RelayPending with an empty outbox = %d, %v", delivered, err)
+	}
+}
+
+func TestOutboxRelayBackoff(t *testing.T) {
+	relay := NewOutboxRelay(nil, nil, RelayOptions{MinBackoff: time.Second, MaxBackoff: 5 * time.Second})
+
+	for failures, want := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 4: 5 * time.Second, 30: 5 * time.Second} {
+		if got := relay.backoff(failures); got != want {
+			t.Errorf("backoff(%d) = %v, want %v", failures, got, want)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package usecase
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// poller runs a batch function every interval until it is shut down. It backs the
+// Start and Shutdown methods of the background workers.
+type poller struct {
+	interval time.Duration
+
+	// ctx is cancelled by shutdown; done is closed when run returns
+	ctx     context.Context
+	stop    context.CancelFunc
+	done    chan struct{}
+	mu      sync.Mutex
+	started bool
+}
+
+func newPoller(interval time.Duration) *poller {
+	ctx, stop := context.WithCancel(context.Background())
+
+	return &poller{interval: interval, ctx: ctx, stop: stop, done: make(chan struct{})}
+}
+
+// run calls batch right away and then every interval until shutdown is called,
+// logging its failures under name
+func (p *poller) run(name string, batch func(ctx context.Context) error) error {
+	p.mu.Lock()
+	p.started = true
+	p.mu.Unlock()
+
+	defer close(p.done)
+
+	ctx := p.ctx
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := batch(ctx); err != nil && ctx.Err() == nil {
+			slog.Warn(name+" failed", slog.String("error", err.Error()))
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// shutdown stops run and waits for the running batch, at most until ctx is done
+func (p *poller) shutdown(ctx context.Context) error {
+	p.stop()
+
+	p.mu.Lock()
+	started := p.started
+	p.mu.Unlock()
+
+	if !started {
+		return nil
+	}
+
+	select {
+	case <-p.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// exponentialBackoff returns the delay before the retry that follows the given
+// number of failures: minDelay doubled with every failure, at most maxDelay
+func exponentialBackoff(minDelay, maxDelay time.Duration, failures int) time.Duration {
+	delay := minDelay
+	for i := 1; i < failures && delay < maxDelay; i++ {
+		delay *= 2
+	}
+
+	return min(delay, maxDelay)
+}
@@ -0,0 +1,150 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/dyammarcano/fullcycle_clean_architecture/internal/domain"
+	"github.com/dyammarcano/fullcycle_clean_architecture/internal/entity"
+)
+
+// DefaultDeliveriesPerPage is the number of deliveries listed when no limit is given
+const DefaultDeliveriesPerPage = 50
+
+// WebhookUseCase manages webhooks and their deliveries. It is also the publisher
+// that queues the order events relayed from the outbox for every subscribed webhook.
+type WebhookUseCase struct {
+	Repo domain.WebhookRepository
+
+	now func() time.Time
+}
+
+// CreateWebhook stores a webhook and returns it with its secret, generated when
+// none was given. The secret is not shown again.
+func (w *WebhookUseCase) CreateWebhook(ctx context.Context, webhook *domain.Webhook) (*domain.Webhook, error) {
+	if err := entity.ValidateWebhook(webhook); err != nil {
+		return nil, err
+	}
+
+	created := *webhook
+	if created.Secret == "" {
+		secret, err := newWebhookSecret()
+		if err != nil {
+			return nil, err
+		}
+
+		created.Secret = secret
+	}
+
+	if err := w.Repo.CreateWebhook(ctx, &created); err != nil {
+		return nil, err
+	}
+
+	return &created, nil
+}
+
+func (w *WebhookUseCase) GetWebhook(ctx context.Context, id int) (*domain.Webhook, error) {
+	webhook, err := w.Repo.GetWebhook(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	webhook.Secret = ""
+
+	return webhook, nil
+}
+
+func (w *WebhookUseCase) ListWebhooks(ctx context.Context) ([]domain.Webhook, error) {
+	webhooks, err := w.Repo.ListWebhooks(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range webhooks {
+		webhooks[i].Secret = ""
+	}
+
+	return webhooks, nil
+}
+
+// UpdateWebhook replaces the URL and event types of a webhook, and its secret
+// unless the new one is empty
+func (w *WebhookUseCase) UpdateWebhook(ctx context.Context, id int, webhook *domain.Webhook) (*domain.Webhook, error) {
+	if err := entity.ValidateWebhook(webhook); err != nil {
+		return nil, err
+	}
+
+	updated := *webhook
+	updated.ID = id
+
+	if updated.Secret == "" {
+		current, err := w.Repo.GetWebhook(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		updated.Secret = current.Secret
+	}
+
+	if err := w.Repo.UpdateWebhook(ctx, &updated); err != nil {
+		return nil, err
+	}
+
+	updated.Secret = ""
+
+	return &updated, nil
+}
+
+func (w *WebhookUseCase) DeleteWebhook(ctx context.Context, id int) error {
+	return w.Repo.DeleteWebhook(ctx, id)
+}
+
+// ListDeliveries returns the latest deliveries of a webhook, only those in status
+// unless it is empty. A limit of zero lists DefaultDeliveriesPerPage deliveries.
+func (w *WebhookUseCase) ListDeliveries(ctx context.Context, webhookID int, status domain.DeliveryStatus, limit int) ([]domain.WebhookDelivery, error) {
+	if err := entity.ValidateDeliveriesQuery(status, limit); err != nil {
+		return nil, err
+	}
+
+	if limit == 0 {
+		limit = DefaultDeliveriesPerPage
+	}
+
+	return w.Repo.ListWebhookDeliveries(ctx, webhookID, status, limit)
+}
+
+// GetDelivery returns a delivery of a webhook with the history of its attempts
+func (w *WebhookUseCase) GetDelivery(ctx context.Context, webhookID int, id int64) (*domain.WebhookDelivery, error) {
+	return w.Repo.GetWebhookDelivery(ctx, webhookID, id)
+}
+
+// RedeliverDelivery queues a delivery to be sent right away, also when it is dead
+// or succeeded, and returns it
+func (w *WebhookUseCase) RedeliverDelivery(ctx context.Context, webhookID int, id int64) (*domain.WebhookDelivery, error) {
+	if err := w.Repo.RedeliverWebhookDelivery(ctx, webhookID, id, w.now()); err != nil {
+		return nil, err
+	}
+
+	return w.Repo.GetWebhookDelivery(ctx, webhookID, id)
+}
+
+// Publish queues event for every webhook subscribed to its type
+func (w *WebhookUseCase) Publish(ctx context.Context, event domain.Event) error {
+	return w.Repo.EnqueueWebhookDeliveries(ctx, event, w.now())
+}
+
+// newWebhookSecret returns a random secret for a webhook created without one
+func newWebhookSecret() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+func NewWebhookUseCase(repo domain.WebhookRepository) *WebhookUseCase {
+	return &WebhookUseCase{Repo: repo, now: time.Now}
+}
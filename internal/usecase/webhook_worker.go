@@ -0,0 +1,215 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/dyammarcano/fullcycle_clean_architecture/internal/domain"
+)
+
+// Defaults of WebhookOptions fields left at zero
+const (
+	DefaultWebhookInterval    = time.Second
+	DefaultWebhookBatchSize   = 20
+	DefaultWebhookLease       = time.Minute
+	DefaultWebhookMinBackoff  = 5 * time.Second
+	DefaultWebhookMaxBackoff  = time.Hour
+	DefaultWebhookMaxAttempts = 8
+)
+
+// WebhookOptions tunes a WebhookWorker
+type WebhookOptions struct {
+	// Interval is how often the queue is polled for due deliveries
+	Interval time.Duration
+	// BatchSize bounds the deliveries claimed, and sent concurrently, at once
+	BatchSize int
+	// Lease hides claimed deliveries from other workers while they are being sent;
+	// it must be longer than the timeout of the sender
+	Lease time.Duration
+	// MinBackoff and MaxBackoff bound the delay before a failed delivery is retried,
+	// which doubles with every failure
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// MaxAttempts is the number of failures after which a delivery is dead
+	MaxAttempts int
+}
+
+// WebhookWorker sends the queued webhook deliveries. A failed delivery is retried
+// with exponential backoff until MaxAttempts requests failed, then it is dead and
+// only sent again when it is redelivered.
+type WebhookWorker struct {
+	Repo   domain.WebhookRepository
+	Sender domain.WebhookSender
+
+	options WebhookOptions
+	now     func() time.Time
+	poller  *poller
+}
+
+// Start sends deliveries every interval until Shutdown is called
+func (w *WebhookWorker) Start() error {
+	return w.poller.run("Webhook delivery", func(ctx context.Context) error {
+		_, err := w.DeliverPending(ctx)
+		return err
+	})
+}
+
+// Shutdown stops the worker and waits for the batch being sent, at most until ctx
+// is done. Deliveries left unsent are retried once their lease expires.
+func (w *WebhookWorker) Shutdown(ctx context.Context) error {
+	return w.poller.shutdown(ctx)
+}
+
+// DeliverPending sends the due deliveries until none is left and returns how many
+// succeeded
+func (w *WebhookWorker) DeliverPending(ctx context.Context) (int, error) {
+	succeeded := 0
+
+	for {
+		now := w.now()
+
+		deliveries, err := w.Repo.ClaimWebhookDeliveries(ctx, now, now.Add(w.options.Lease), w.options.BatchSize)
+		if err != nil {
+			return succeeded, err
+		}
+
+		var (
+			wg   sync.WaitGroup
+			mu   sync.Mutex
+			errs []error
+		)
+
+		for _, delivery := range deliveries {
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+
+				ok, err := w.deliver(ctx, delivery)
+
+				mu.Lock()
+				defer mu.Unlock()
+
+				if ok {
+					succeeded++
+				}
+
+				if err != nil {
+					errs = append(errs, err)
+				}
+			}()
+		}
+
+		wg.Wait()
+
+		if err = errors.Join(errs...); err != nil {
+			return succeeded, err
+		}
+
+		if ctx.Err() != nil {
+			return succeeded, ctx.Err()
+		}
+
+		if len(deliveries) < w.options.BatchSize {
+			return succeeded, nil
+		}
+	}
+}
+
+// deliver sends one delivery and records the attempt, reporting whether it succeeded
+func (w *WebhookWorker) deliver(ctx context.Context, delivery domain.WebhookDelivery) (bool, error) {
+	webhook, err := w.Repo.GetWebhook(ctx, delivery.WebhookID)
+	if err != nil {
+		// The delivery went with its webhook
+		if errors.Is(err, domain.ErrWebhookNotFound) {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	statusCode, err := w.Sender.Send(ctx, webhook, &delivery)
+	if err != nil && ctx.Err() != nil {
+		// The lease expires and the delivery is sent again, without counting an attempt
+		return false, nil
+	}
+
+	now := w.now()
+	attempt := domain.WebhookAttempt{DeliveryID: delivery.ID, At: now, StatusCode: statusCode}
+	status, nextAttemptAt := domain.DeliverySucceeded, now
+
+	if err != nil {
+		attempt.Error = err.Error()
+
+		failures := delivery.Attempts + 1
+		if failures >= w.options.MaxAttempts {
+			status = domain.DeliveryDead
+		} else {
+			status, nextAttemptAt = domain.DeliveryPending, now.Add(w.backoff(failures))
+		}
+
+		slog.Warn("Webhook delivery failed",
+			slog.Int64("delivery_id", delivery.ID),
+			slog.Int("webhook_id", webhook.ID),
+			slog.String("type", string(delivery.Event.Type)),
+			slog.Int("attempts", failures),
+			slog.String("status", string(status)),
+			slog.String("error", err.Error()),
+		)
+	}
+
+	if err = w.Repo.RecordWebhookAttempt(ctx, attempt, status, nextAttemptAt); err != nil {
+		// The delivery went with its webhook while it was being sent
+		if errors.Is(err, domain.ErrDeliveryNotFound) {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	return status == domain.DeliverySucceeded, nil
+}
+
+// backoff returns the delay before the retry that follows the given number of failures
+func (w *WebhookWorker) backoff(failures int) time.Duration {
+	return exponentialBackoff(w.options.MinBackoff, w.options.MaxBackoff, failures)
+}
+
+// NewWebhookWorker returns a worker sending the deliveries queued in repo through
+// sender. Options left at zero take their defaults.
+func NewWebhookWorker(repo domain.WebhookRepository, sender domain.WebhookSender, options WebhookOptions) *WebhookWorker {
+	if options.Interval <= 0 {
+		options.Interval = DefaultWebhookInterval
+	}
+
+	if options.BatchSize <= 0 {
+		options.BatchSize = DefaultWebhookBatchSize
+	}
+
+	if options.Lease <= 0 {
+		options.Lease = DefaultWebhookLease
+	}
+
+	if options.MinBackoff <= 0 {
+		options.MinBackoff = DefaultWebhookMinBackoff
+	}
+
+	if options.MaxBackoff < options.MinBackoff {
+		options.MaxBackoff = max(DefaultWebhookMaxBackoff, options.MinBackoff)
+	}
+
+	if options.MaxAttempts <= 0 {
+		options.MaxAttempts = DefaultWebhookMaxAttempts
+	}
+
+	return &WebhookWorker{
+		Repo:    repo,
+		Sender:  sender,
+		options: options,
+		now:     time.Now,
+		poller:  newPoller(options.Interval),
+	}
+}
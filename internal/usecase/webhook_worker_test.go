@@ -0,0 +1,127 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dyammarcano/fullcycle_clean_architecture/internal/domain"
+	"github.com/dyammarcano/fullcycle_clean_architecture/internal/repository"
+)
+
+// failingSender answers 500 to the first failures requests and 204 to the others
+type failingSender struct {
+	mu       sync.Mutex
+	failures int
+	sent     []domain.WebhookDelivery
+}
+
+func (s *failingSender) Send(_ context.Context, _ *domain.Webhook, delivery *domain.WebhookDelivery) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sent = append(s.sent, *delivery)
+
+	if s.failures > 0 {
+		s.failures--
+		return 500, errors.New("unexpected status 500")
+	}
+
+	return 204, nil
+}
+
+func TestWebhookWorker(t *testing.T) {
+	ctx := context.Background()
+
+	repo, err := repository.NewOrderMemoryRepository()
+	if err != nil {
+		t.Fatalf("Error creating repository: %v", err)
+	}
+
+	webhooks := NewWebhookUseCase(repo)
+
+	created, err := webhooks.CreateWebhook(ctx, &domain.Webhook{URL: "https://example.com/hook", EventTypes: []domain.EventType{domain.EventOrderCreated}})
+	if err != nil {
+		t.Fatalf("Error creating webhook: %v", err)
+	}
+
+	if created.Secret == "" {
+		t.Fatal("Expected a secret to be generated")
+	}
+
+	if got, _ := webhooks.GetWebhook(ctx, created.ID); got.Secret != "" {
+		t.Fatal("Expected the secret to be hidden once created")
+	}
+
+	orders := NewOrderUseCase(repo)
+
+	order, err := orders.CreateOrder(ctx, []byte(`{"item":"Bag","lines":[{"sku":"BAG-1","quantity":1,"unitPrice":{"value":"10.00","currency":"USD"}}]}`))
+	if err != nil {
+		t.Fatalf("Error creating order: %v", err)
+	}
+
+	if _, err = orders.ConfirmOrder(ctx, order.ID, ""); err != nil {
+		t.Fatalf("Error confirming order: %v", err)
+	}
+
+	if _, err = NewOutboxRelay(repo, webhooks, RelayOptions{}).RelayPending(ctx); err != nil {
+		t.Fatalf("Error relaying events: %v", err)
+	}
+
+	sender := &failingSender{failures: 3}
+	worker := NewWebhookWorker(repo, sender, WebhookOptions{MinBackoff: time.Second, MaxBackoff: time.Minute, MaxAttempts: 3})
+
+	now := time.Now()
+	worker.now = func() time.Time { return now }
+
+	// Two failures back off one and then two seconds, the third one is final
+	for i, wait := range []time.Duration{time.Second, 2 * time.Second, 0} {
+		if succeeded, err := worker.DeliverPending(ctx); err != nil || succeeded != 0 {
+			t.Fatalf("DeliverPending #%d = %d, %v, want a failed attempt", i+1, succeeded, err)
+		}
+
+		if len(sender.sent) != i+1 {
+			t.Fatalf("Sent %d requests after attempt #%d, want only the order.created event", len(sender.sent), i+1)
+		}
+
+		now = now.Add(wait)
+	}
+
+	deliveries, err := webhooks.ListDeliveries(ctx, created.ID, domain.DeliveryDead, 0)
+	if err != nil || len(deliveries) != 1 {
+		t.Fatalf("ListDeliveries(dead) = %v, %v, want the failed delivery", deliveries, err)
+	}
+
+	if deliveries[0].Event.Type != domain.EventOrderCreated || deliveries[0].Attempts != 3 {
+		t.Fatalf("Dead delivery = %+v, want order.created after 3 attempts", deliveries[0])
+	}
+
+	if succeeded, _ := worker.DeliverPending(ctx); succeeded != 0 || len(sender.sent) != 3 {
+		t.Fatal("Expected a dead delivery not to be sent again")
+	}
+
+	redelivered, err := webhooks.RedeliverDelivery(ctx, created.ID, deliveries[0].ID)
+	if err != nil || redelivered.Status != domain.DeliveryPending || redelivered.Attempts != 0 || len(redelivered.History) != 3 {
+		t.Fatalf("RedeliverDelivery = %+v, %v, want a pending delivery keeping its history", redelivered, err)
+	}
+
+	if succeeded, err := worker.DeliverPending(ctx); err != nil || succeeded != 1 {
+		t.Fatalf("DeliverPending after redelivery = %d, %v, want the delivery to succeed", succeeded, err)
+	}
+
+	delivery, err := webhooks.GetDelivery(ctx, created.ID, deliveries[0].ID)
+	if err != nil {
+		t.Fatalf("Error getting delivery: %v", err)
+	}
+
+	last := delivery.History[len(delivery.History)-1]
+	if delivery.Status != domain.DeliverySucceeded || len(delivery.History) != 4 || last.StatusCode != 204 || last.Error != "" {
+		t.Fatalf("Delivery = %+v, want it succeeded after 4 attempts", delivery)
+	}
+
+	if _, err = webhooks.ListDeliveries(ctx, created.ID, "failed", 500); !errors.Is(err, domain.ErrValidation) {
+		t.Fatalf("ListDeliveries with an invalid query = %v, want a validation error", err)
+	}
+}
@@ -0,0 +1,323 @@
+package events
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// amqpMessage is a message published to the stand-in broker
+type amqpMessage struct {
+	exchange, routingKey   string
+	contentType, messageID string
+	body                   []byte
+}
+
+// amqpBroker is a stand-in AMQP 0-9-1 broker speaking just enough of the protocol
+// for a confirming publisher: the handshake, channel and exchange declaration,
+// publishes, which it acks, or nacks when nack is set, and closing
+type amqpBroker struct {
+	listener net.Listener
+
+	mu        sync.Mutex
+	conns     []net.Conn
+	exchanges []string
+	messages  []amqpMessage
+	nack      bool
+}
+
+func startAMQPBroker(t *testing.T) *amqpBroker {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Error listening: %v", err)
+	}
+
+	b := &amqpBroker{listener: listener}
+	t.Cleanup(func() { _ = listener.Close(); b.dropConnections() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			b.mu.Lock()
+			b.conns = append(b.conns, conn)
+			b.mu.Unlock()
+
+			go func() { _ = b.serve(conn) }()
+		}
+	}()
+
+	return b
+}
+
+func (b *amqpBroker) url() string {
+	return "amqp://guest:guest@" + b.listener.Addr().String() + "/"
+}
+
+// dropConnections closes the client connections without the closing handshake
+func (b *amqpBroker) dropConnections() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, conn := range b.conns {
+		_ = conn.Close()
+	}
+
+	b.conns = nil
+}
+
+func (b *amqpBroker) published() []amqpMessage {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return append([]amqpMessage(nil), b.messages...)
+}
+
+func (b *amqpBroker) serve(conn net.Conn) error {
+	defer func() { _ = conn.Close() }()
+
+	r := bufio.NewReader(conn)
+
+	header := make([]byte, 8)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return err
+	}
+
+	// Connection.Start: version 0-9, no server properties, PLAIN and en_US
+	if err := writeAMQPMethod(conn, 0, 10, 10, []byte{0, 9}, amqpTable(), amqpLongString("PLAIN"), amqpLongString("en_US")); err != nil {
+		return err
+	}
+
+	var (
+		pending     *amqpMessage
+		bodySize    uint64
+		deliveryTag uint64
+	)
+
+	for {
+		frameType, channel, payload, err := readAMQPFrame(r)
+		if err != nil {
+			return err
+		}
+
+		switch frameType {
+		case 1:
+			args := amqpReader(payload[4:])
+
+			switch method := [2]uint16{binary.BigEndian.Uint16(payload), binary.BigEndian.Uint16(payload[2:])}; method {
+			case [2]uint16{10, 11}: // Connection.StartOk, answered with Tune: no channel limit, 128KiB frames, no heartbeats
+				err = writeAMQPMethod(conn, 0, 10, 30, amqpShort(0), binary.BigEndian.AppendUint32(nil, 131072), amqpShort(0))
+			case [2]uint16{10, 40}: // Connection.Open
+				err = writeAMQPMethod(conn, 0, 10, 41, amqpShortString(""))
+			case [2]uint16{10, 50}: // Connection.Close
+				return writeAMQPMethod(conn, 0, 10, 51)
+			case [2]uint16{20, 10}: // Channel.Open
+				err = writeAMQPMethod(conn, channel, 20, 11, amqpLongString(""))
+			case [2]uint16{20, 40}: // Channel.Close
+				err = writeAMQPMethod(conn, channel, 20, 41)
+			case [2]uint16{85, 10}: // Confirm.Select
+				err = writeAMQPMethod(conn, channel, 85, 11)
+			case [2]uint16{40, 10}: // Exchange.Declare
+				args.short()
+
+				b.mu.Lock()
+				b.exchanges = append(b.exchanges, args.shortString())
+				b.mu.Unlock()
+
+				err = writeAMQPMethod(conn, channel, 40, 11)
+			case [2]uint16{60, 40}: // Basic.Publish, followed by the header and body frames
+				args.short()
+				pending = &amqpMessage{exchange: args.shortString(), routingKey: args.shortString()}
+			}
+		case 2:
+			header := amqpReader(payload[4:])
+			bodySize = header.longLong()
+			flags := header.short()
+
+			for bit := uint16(15); bit > 6; bit-- {
+				if flags&(1<<bit) == 0 {
+					continue
+				}
+
+				switch bit {
+				case 15:
+					pending.contentType = header.shortString()
+				case 13:
+					header.table()
+				case 12, 11:
+					header.octet()
+				case 7:
+					pending.messageID = header.shortString()
+				default:
+					header.shortString()
+				}
+			}
+		case 3:
+			pending.body = append(pending.body, payload...)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if pending != nil && frameType != 1 && uint64(len(pending.body)) == bodySize {
+			b.mu.Lock()
+			b.messages = append(b.messages, *pending)
+			nack := b.nack
+			b.mu.Unlock()
+
+			pending = nil
+			deliveryTag++
+
+			if nack {
+				err = writeAMQPMethod(conn, channel, 60, 120, binary.BigEndian.AppendUint64(nil, deliveryTag), []byte{0})
+			} else {
+				err = writeAMQPMethod(conn, channel, 60, 80, binary.BigEndian.AppendUint64(nil, deliveryTag), []byte{0})
+			}
+
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func readAMQPFrame(r io.Reader) (frameType byte, channel uint16, payload []byte, err error) {
+	header := make([]byte, 7)
+	if _, err = io.ReadFull(r, header); err != nil {
+		return 0, 0, nil, err
+	}
+
+	payload = make([]byte, binary.BigEndian.Uint32(header[3:])+1)
+	if _, err = io.ReadFull(r, payload); err != nil {
+		return 0, 0, nil, err
+	}
+
+	if payload[len(payload)-1] != 0xCE {
+		return 0, 0, nil, errors.New("missing frame end")
+	}
+
+	return header[0], binary.BigEndian.Uint16(header[1:]), payload[:len(payload)-1], nil
+}
+
+func writeAMQPMethod(w io.Writer, channel, class, method uint16, args ...[]byte) error {
+	payload := binary.BigEndian.AppendUint16(binary.BigEndian.AppendUint16(nil, class), method)
+	for _, arg := range args {
+		payload = append(payload, arg...)
+	}
+
+	frame := append([]byte{1}, binary.BigEndian.AppendUint16(nil, channel)...)
+	frame = binary.BigEndian.AppendUint32(frame, uint32(len(payload)))
+	frame = append(append(frame, payload...), 0xCE)
+
+	_, err := w.Write(frame)
+
+	return err
+}
+
+func amqpShort(v uint16) []byte { return binary.BigEndian.AppendUint16(nil, v) }
+
+func amqpTable() []byte { return binary.BigEndian.AppendUint32(nil, 0) }
+
+func amqpShortString(s string) []byte { return append([]byte{byte(len(s))}, s...) }
+
+func amqpLongString(s string) []byte {
+	return append(binary.BigEndian.AppendUint32(nil, uint32(len(s))), s...)
+}
+
+// amqpReader decodes the fields of a method or header frame
+type amqpReader []byte
+
+func (r *amqpReader) take(n int) []byte {
+	v := (*r)[:n]
+	*r = (*r)[n:]
+
+	return v
+}
+
+func (r *amqpReader) octet() byte { return r.take(1)[0] }
+
+func (r *amqpReader) short() uint16 { return binary.BigEndian.Uint16(r.take(2)) }
+
+func (r *amqpReader) longLong() uint64 { return binary.BigEndian.Uint64(r.take(8)) }
+
+func (r *amqpReader) shortString() string { return string(r.take(int(r.octet()))) }
+
+func (r *amqpReader) table() { r.take(int(binary.BigEndian.Uint32(r.take(4)))) }
+
+func TestAMQPPublisher(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	broker := startAMQPBroker(t)
+
+	publisher, err := NewAMQPPublisher(AMQPOptions{URL: broker.url(), Exchange: "orders"})
+	if err != nil {
+		t.Fatalf("Error connecting: %v", err)
+	}
+
+	defer func() { _ = publisher.Close() }()
+
+	if err = publisher.Publish(ctx, testEvent("1", "order.created")); err != nil {
+		t.Fatalf("Error publishing event: %v", err)
+	}
+
+	broker.mu.Lock()
+	broker.nack = true
+	broker.mu.Unlock()
+
+	if err = publisher.Publish(ctx, testEvent("2", "order.updated")); err == nil {
+		t.Error("Expected a nacked event to fail")
+	}
+
+	broker.mu.Lock()
+	broker.nack = false
+	broker.mu.Unlock()
+
+	broker.dropConnections()
+
+	for !publisher.channel.IsClosed() {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if err = publisher.Publish(ctx, testEvent("3", "order.deleted")); err != nil {
+		t.Fatalf("Error publishing event after the connection was lost: %v", err)
+	}
+
+	messages := broker.published()
+	if len(messages) != 3 {
+		t.Fatalf("Broker received %d messages, want 3", len(messages))
+	}
+
+	first := messages[0]
+	if first.exchange != "orders" || first.routingKey != "order.created" || first.contentType != ContentType || first.messageID != "1" {
+		t.Errorf("Unexpected message %+v", first)
+	}
+
+	if event, err := Unmarshal(first.body); err != nil || event.ID != "1" || string(event.Data) != `{"order":{"id":1}}` {
+		t.Errorf("Unexpected body %s (%v)", first.body, err)
+	}
+
+	if messages[2].routingKey != "order.deleted" {
+		t.Errorf("Unexpected message after reconnecting %+v", messages[2])
+	}
+
+	broker.mu.Lock()
+	exchanges := broker.exchanges
+	broker.mu.Unlock()
+
+	if len(exchanges) != 2 || exchanges[0] != "orders" {
+		t.Errorf("Declared exchanges %v, want orders once per connection", exchanges)
+	}
+}
@@ -0,0 +1,96 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// Handler consumes an event
+type Handler func(ctx context.Context, event Event) error
+
+// Dispatcher is an in-process Publisher that hands every event to the handlers
+// subscribed to its type, synchronously and in subscription order. It is meant
+// for tests and for consumers living in the same process.
+type Dispatcher struct {
+	mu            sync.RWMutex
+	subscriptions []*subscription
+	closed        bool
+}
+
+type subscription struct {
+	eventType string
+	handler   Handler
+}
+
+// NewDispatcher returns a dispatcher without subscriptions
+func NewDispatcher() *Dispatcher {
+	return &Dispatcher{}
+}
+
+// Subscribe calls handler for the events of eventType, or for every event when
+// eventType is empty, until the returned function is called
+func (d *Dispatcher) Subscribe(eventType string, handler Handler) (unsubscribe func()) {
+	sub := &subscription{eventType: eventType, handler: handler}
+
+	d.mu.Lock()
+	d.subscriptions = append(d.subscriptions, sub)
+	d.mu.Unlock()
+
+	return func() {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+
+		for i, s := range d.subscriptions {
+			if s == sub {
+				d.subscriptions = append(d.subscriptions[:i:i], d.subscriptions[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Publish calls every matching handler, even after one fails, and joins their errors
+func (d *Dispatcher) Publish(ctx context.Context, event Event) error {
+	if err := event.Validate(); err != nil {
+		return err
+	}
+
+	d.mu.RLock()
+	if d.closed {
+		d.mu.RUnlock()
+		return errors.New("events: dispatcher is closed")
+	}
+
+	var handlers []Handler
+	for _, sub := range d.subscriptions {
+		if sub.eventType == "" || sub.eventType == event.Type {
+			handlers = append(handlers, sub.handler)
+		}
+	}
+	d.mu.RUnlock()
+
+	var errs []error
+	for _, handler := range handlers {
+		if err := ctx.Err(); err != nil {
+			return errors.Join(append(errs, err)...)
+		}
+
+		if err := handler(ctx, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Close drops the subscriptions and rejects further events
+func (d *Dispatcher) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.closed = true
+	d.subscriptions = nil
+
+	return nil
+}
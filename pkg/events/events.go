@@ -0,0 +1,79 @@
+// Package events publishes application events, encoded as CloudEvents 1.0 JSON, to
+// an in-process dispatcher or to an AMQP 0-9-1 or NATS broker
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+const (
+	// SpecVersion is the CloudEvents version of every event
+	SpecVersion = "1.0"
+	// ContentType is the media type of an event in structured JSON mode
+	ContentType = "application/cloudevents+json"
+)
+
+// Event is a CloudEvent. Data holds the JSON payload, described by DataContentType.
+type Event struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	Subject         string          `json:"subject,omitempty"`
+	Time            time.Time       `json:"time,omitzero"`
+	DataContentType string          `json:"datacontenttype,omitempty"`
+	Data            json.RawMessage `json:"data,omitempty"`
+}
+
+// Validate reports whether the required context attributes are set
+func (e Event) Validate() error {
+	var errs []error
+
+	if e.SpecVersion != SpecVersion {
+		errs = append(errs, errors.New("specversion must be "+SpecVersion))
+	}
+
+	if e.ID == "" {
+		errs = append(errs, errors.New("id is required"))
+	}
+
+	if e.Source == "" {
+		errs = append(errs, errors.New("source is required"))
+	}
+
+	if e.Type == "" {
+		errs = append(errs, errors.New("type is required"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// Marshal validates event and encodes it in structured JSON mode
+func Marshal(event Event) ([]byte, error) {
+	if err := event.Validate(); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(event)
+}
+
+// Unmarshal decodes and validates an event in structured JSON mode
+func Unmarshal(data []byte) (Event, error) {
+	var event Event
+	if err := json.Unmarshal(data, &event); err != nil {
+		return Event{}, err
+	}
+
+	return event, event.Validate()
+}
+
+// Publisher sends events to their consumers. Publish returns once the transport
+// accepted the event, so an error means it may not have been delivered.
+type Publisher interface {
+	Publish(ctx context.Context, event Event) error
+	// Close releases the connection to the transport
+	Close() error
+}
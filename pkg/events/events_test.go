@@ -0,0 +1,89 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func testEvent(id, eventType string) Event {
+	return Event{
+		SpecVersion:     SpecVersion,
+		ID:              id,
+		Source:          "/orders",
+		Type:            eventType,
+		Subject:         "1",
+		Time:            time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC),
+		DataContentType: "application/json",
+		Data:            json.RawMessage(`{"order":{"id":1}}`),
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	data, err := Marshal(testEvent("7", "order.created"))
+	if err != nil {
+		t.Fatalf("Error marshalling event: %v", err)
+	}
+
+	want := `{"specversion":"1.0","id":"7","source":"/orders","type":"order.created","subject":"1",` +
+		`"time":"2025-03-01T12:00:00Z","datacontenttype":"application/json","data":{"order":{"id":1}}}`
+	if string(data) != want {
+		t.Errorf("Marshal = %s, want %s", data, want)
+	}
+
+	event, err := Unmarshal(data)
+	if err != nil || event.ID != "7" || !event.Time.Equal(testEvent("", "").Time) {
+		t.Errorf("Unmarshal = %+v, %v", event, err)
+	}
+
+	if _, err = Marshal(Event{SpecVersion: "0.3", ID: "7"}); err == nil {
+		t.Error("Expected an event without source and type and of another version to be rejected")
+	}
+}
+
+func TestDispatcher(t *testing.T) {
+	ctx := context.Background()
+	dispatcher := NewDispatcher()
+
+	var created, all []string
+	dispatcher.Subscribe("order.created", func(_ context.Context, event Event) error {
+		created = append(created, event.ID)
+		return errors.New("billing unavailable")
+	})
+
+	unsubscribe := dispatcher.Subscribe("", func(_ context.Context, event Event) error {
+		all = append(all, event.ID)
+		return nil
+	})
+
+	if err := dispatcher.Publish(ctx, testEvent("1", "order.created")); err == nil || err.Error() != "billing unavailable" {
+		t.Errorf("Publish error = %v, want the handler error", err)
+	}
+
+	if err := dispatcher.Publish(ctx, testEvent("2", "order.deleted")); err != nil {
+		t.Errorf("Error publishing event: %v", err)
+	}
+
+	unsubscribe()
+
+	if err := dispatcher.Publish(ctx, testEvent("3", "order.deleted")); err != nil {
+		t.Errorf("Error publishing event: %v", err)
+	}
+
+	if !slices.Equal(created, []string{"1"}) || !slices.Equal(all, []string{"1", "2"}) {
+		t.Errorf("Handled created %v and all %v", created, all)
+	}
+
+	if err := dispatcher.Publish(ctx, Event{}); err == nil {
+		t.Error("Expected an invalid event to be rejected")
+	}
+
+	_ = dispatcher.Close()
+
+	if err := dispatcher.Publish(ctx, testEvent("4", "order.created")); err == nil {
+		t.Error("Expected a closed dispatcher to reject events")
+	}
+}
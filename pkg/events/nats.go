@@ -0,0 +1,81 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// NATSOptions configures a NATSPublisher
+type NATSOptions struct {
+	// URL is the server address, as in nats://localhost:4222
+	URL string
+	// SubjectPrefix is prepended, with a dot, to the event type to form the subject
+	SubjectPrefix string
+	// DialTimeout bounds connecting to the server, 2s when zero
+	DialTimeout time.Duration
+}
+
+// NATSPublisher publishes events to a NATS server in structured JSON mode, with
+// the event ID in the Nats-Msg-Id header so JetStream streams drop duplicates.
+// Publish flushes the connection, so it returns once the server received the
+// event; the connection is re-established in the background when lost.
+type NATSPublisher struct {
+	options NATSOptions
+	conn    *nats.Conn
+}
+
+// NewNATSPublisher connects to the server
+func NewNATSPublisher(options NATSOptions) (*NATSPublisher, error) {
+	opts := []nats.Option{nats.Name("events publisher"), nats.MaxReconnects(-1)}
+	if options.DialTimeout > 0 {
+		opts = append(opts, nats.Timeout(options.DialTimeout))
+	}
+
+	conn, err := nats.Connect(options.URL, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("events: connecting to nats server: %w", err)
+	}
+
+	return &NATSPublisher{options: options, conn: conn}, nil
+}
+
+// Subject returns the subject event is published to
+func (p *NATSPublisher) Subject(event Event) string {
+	if p.options.SubjectPrefix == "" {
+		return event.Type
+	}
+
+	return p.options.SubjectPrefix + "." + event.Type
+}
+
+func (p *NATSPublisher) Publish(ctx context.Context, event Event) error {
+	body, err := Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	msg := nats.NewMsg(p.Subject(event))
+	msg.Header.Set(nats.MsgIdHdr, event.ID)
+	msg.Header.Set("Content-Type", ContentType)
+	msg.Data = body
+
+	if err = p.conn.PublishMsg(msg); err != nil {
+		return fmt.Errorf("events: publishing to nats server: %w", err)
+	}
+
+	if err = p.conn.FlushWithContext(ctx); err != nil {
+		return fmt.Errorf("events: flushing nats connection: %w", err)
+	}
+
+	return nil
+}
+
+// Close closes the connection; every published event was already flushed
+func (p *NATSPublisher) Close() error {
+	p.conn.Close()
+
+	return nil
+}
@@ -0,0 +1,162 @@
+package events
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// natsMessage is a message published to the stand-in server
+type natsMessage struct {
+	subject string
+	headers map[string]string
+	data    []byte
+}
+
+// natsServer is a stand-in NATS server speaking just enough of the client protocol
+// for a publisher: INFO, CONNECT, PING/PONG and PUB/HPUB
+type natsServer struct {
+	listener net.Listener
+
+	mu       sync.Mutex
+	messages []natsMessage
+}
+
+func startNATSServer(t *testing.T) *natsServer {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Error listening: %v", err)
+	}
+
+	s := &natsServer{listener: listener}
+	t.Cleanup(func() { _ = listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() { _ = s.serve(conn) }()
+		}
+	}()
+
+	return s
+}
+
+func (s *natsServer) url() string {
+	return "nats://" + s.listener.Addr().String()
+}
+
+func (s *natsServer) published() []natsMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]natsMessage(nil), s.messages...)
+}
+
+func (s *natsServer) serve(conn net.Conn) error {
+	defer func() { _ = conn.Close() }()
+
+	info := `INFO {"server_id":"stand-in","version":"2.10.0","proto":1,"headers":true,"max_payload":1048576}` + "\r\n"
+	if _, err := io.WriteString(conn, info); err != nil {
+		return err
+	}
+
+	r := bufio.NewReader(conn)
+
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return err
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch strings.ToUpper(fields[0]) {
+		case "PING":
+			_, err = io.WriteString(conn, "PONG\r\n")
+		case "PUB", "HPUB":
+			// PUB subject [reply] size, HPUB subject [reply] header-size total-size
+			total, _ := strconv.Atoi(fields[len(fields)-1])
+			headerSize := 0
+			if fields[0] == "HPUB" {
+				headerSize, _ = strconv.Atoi(fields[len(fields)-2])
+			}
+
+			payload := make([]byte, total+2)
+			if _, err = io.ReadFull(r, payload); err != nil {
+				return err
+			}
+
+			msg := natsMessage{subject: fields[1], headers: map[string]string{}, data: payload[headerSize:total]}
+			for _, header := range strings.Split(string(payload[:headerSize]), "\r\n")[1:] {
+				if key, value, ok := strings.Cut(header, ":"); ok {
+					msg.headers[key] = strings.TrimSpace(value)
+				}
+			}
+
+			s.mu.Lock()
+			s.messages = append(s.messages, msg)
+			s.mu.Unlock()
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func TestNATSPublisher(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	server := startNATSServer(t)
+
+	publisher, err := NewNATSPublisher(NATSOptions{URL: server.url(), SubjectPrefix: "shop"})
+	if err != nil {
+		t.Fatalf("Error connecting: %v", err)
+	}
+
+	defer func() { _ = publisher.Close() }()
+
+	for _, event := range []Event{testEvent("1", "order.created"), testEvent("2", "order.status_changed")} {
+		if err = publisher.Publish(ctx, event); err != nil {
+			t.Fatalf("Error publishing event: %v", err)
+		}
+	}
+
+	if err = publisher.Publish(ctx, Event{ID: "3"}); err == nil {
+		t.Error("Expected an invalid event to be rejected")
+	}
+
+	messages := server.published()
+	if len(messages) != 2 {
+		t.Fatalf("Server received %d messages, want 2", len(messages))
+	}
+
+	first := messages[0]
+	if first.subject != "shop.order.created" || first.headers["Nats-Msg-Id"] != "1" || first.headers["Content-Type"] != ContentType {
+		t.Errorf("Unexpected message %+v", first)
+	}
+
+	if event, err := Unmarshal(first.data); err != nil || event.Type != "order.created" || event.Subject != "1" {
+		t.Errorf("Unexpected data %s (%v)", first.data, err)
+	}
+
+	if messages[1].subject != "shop.order.status_changed" {
+		t.Errorf("Unexpected subject %q", messages[1].subject)
+	}
+}
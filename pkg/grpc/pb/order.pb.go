@@ -1,6 +1,6 @@
 // Code generated by protoc-gen-go. DO NOT EDIT.
 // versions:
-// 	protoc-gen-go v1.33.0
+// 	protoc-gen-go v1.36.8
 // 	protoc        v5.27.0
 // source: order.proto
 
@@ -9,8 +9,10 @@ package pb
 import (
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
 	reflect "reflect"
 	sync "sync"
+	unsafe "unsafe"
 )
 
 const (
@@ -20,19 +22,154 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+type OrderStatus int32
+
+const (
+	OrderStatus_ORDER_STATUS_UNSPECIFIED OrderStatus = 0
+	OrderStatus_ORDER_STATUS_PENDING     OrderStatus = 1
+	OrderStatus_ORDER_STATUS_CONFIRMED   OrderStatus = 2
+	OrderStatus_ORDER_STATUS_PAID        OrderStatus = 3
+	OrderStatus_ORDER_STATUS_SHIPPED     OrderStatus = 4
+	OrderStatus_ORDER_STATUS_DELIVERED   OrderStatus = 5
+	OrderStatus_ORDER_STATUS_CANCELLED   OrderStatus = 6
+	OrderStatus_ORDER_STATUS_REFUNDED    OrderStatus = 7
+)
+
+// Enum value maps for OrderStatus.
+var (
+	OrderStatus_name = map[int32]string{
+		0: "ORDER_STATUS_UNSPECIFIED",
+		1: "ORDER_STATUS_PENDING",
+		2: "ORDER_STATUS_CONFIRMED",
+		3: "ORDER_STATUS_PAID",
+		4: "ORDER_STATUS_SHIPPED",
+		5: "ORDER_STATUS_DELIVERED",
+		6: "ORDER_STATUS_CANCELLED",
+		7: "ORDER_STATUS_REFUNDED",
+	}
+	OrderStatus_value = map[string]int32{
+		"ORDER_STATUS_UNSPECIFIED": 0,
+		"ORDER_STATUS_PENDING":     1,
+		"ORDER_STATUS_CONFIRMED":   2,
+		"ORDER_STATUS_PAID":        3,
+		"ORDER_STATUS_SHIPPED":     4,
+		"ORDER_STATUS_DELIVERED":   5,
+		"ORDER_STATUS_CANCELLED":   6,
+		"ORDER_STATUS_REFUNDED":    7,
+	}
+)
+
+func (x OrderStatus) Enum() *OrderStatus {
+	p := new(OrderStatus)
+	*p = x
+	return p
+}
+
+func (x OrderStatus) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (OrderStatus) Descriptor() protoreflect.EnumDescriptor {
+	return file_order_proto_enumTypes[0].Descriptor()
+}
+
+func (OrderStatus) Type() protoreflect.EnumType {
+	return &file_order_proto_enumTypes[0]
+}
+
+func (x OrderStatus) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use OrderStatus.Descriptor instead.
+func (OrderStatus) EnumDescriptor() ([]byte, []int) {
+	return file_order_proto_rawDescGZIP(), []int{0}
+}
+
+type OrderChangeType int32
+
+const (
+	OrderChangeType_ORDER_CHANGE_TYPE_UNSPECIFIED       OrderChangeType = 0
+	OrderChangeType_ORDER_CHANGE_TYPE_SNAPSHOT          OrderChangeType = 1
+	OrderChangeType_ORDER_CHANGE_TYPE_SNAPSHOT_COMPLETE OrderChangeType = 2
+	OrderChangeType_ORDER_CHANGE_TYPE_CREATED           OrderChangeType = 3
+	OrderChangeType_ORDER_CHANGE_TYPE_UPDATED           OrderChangeType = 4
+	OrderChangeType_ORDER_CHANGE_TYPE_DELETED           OrderChangeType = 5
+	OrderChangeType_ORDER_CHANGE_TYPE_RESTORED          OrderChangeType = 6
+	OrderChangeType_ORDER_CHANGE_TYPE_STATUS_CHANGED    OrderChangeType = 7
+)
+
+// Enum value maps for OrderChangeType.
+var (
+	OrderChangeType_name = map[int32]string{
+		0: "ORDER_CHANGE_TYPE_UNSPECIFIED",
+		1: "ORDER_CHANGE_TYPE_SNAPSHOT",
+		2: "ORDER_CHANGE_TYPE_SNAPSHOT_COMPLETE",
+		3: "ORDER_CHANGE_TYPE_CREATED",
+		4: "ORDER_CHANGE_TYPE_UPDATED",
+		5: "ORDER_CHANGE_TYPE_DELETED",
+		6: "ORDER_CHANGE_TYPE_RESTORED",
+		7: "ORDER_CHANGE_TYPE_STATUS_CHANGED",
+	}
+	OrderChangeType_value = map[string]int32{
+		"ORDER_CHANGE_TYPE_UNSPECIFIED":       0,
+		"ORDER_CHANGE_TYPE_SNAPSHOT":          1,
+		"ORDER_CHANGE_TYPE_SNAPSHOT_COMPLETE": 2,
+		"ORDER_CHANGE_TYPE_CREATED":           3,
+		"ORDER_CHANGE_TYPE_UPDATED":           4,
+		"ORDER_CHANGE_TYPE_DELETED":           5,
+		"ORDER_CHANGE_TYPE_RESTORED":          6,
+		"ORDER_CHANGE_TYPE_STATUS_CHANGED":    7,
+	}
+)
+
+func (x OrderChangeType) Enum() *OrderChangeType {
+	p := new(OrderChangeType)
+	*p = x
+	return p
+}
+
+func (x OrderChangeType) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (OrderChangeType) Descriptor() protoreflect.EnumDescriptor {
+	return file_order_proto_enumTypes[1].Descriptor()
+}
+
+func (OrderChangeType) Type() protoreflect.EnumType {
+	return &file_order_proto_enumTypes[1]
+}
+
+func (x OrderChangeType) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use OrderChangeType.Descriptor instead.
+func (OrderChangeType) EnumDescriptor() ([]byte, []int) {
+	return file_order_proto_rawDescGZIP(), []int{1}
+}
+
+// ListOrdersRequest asks for one page of orders. Amount filters only match orders
+// in their currency; order_by is "id", "item" or "amount", optionally followed by
+// "asc" or "desc".
 type ListOrdersRequest struct {
-	state         protoimpl.MessageState
-	sizeCache     protoimpl.SizeCache
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PageSize      int32                  `protobuf:"varint,1,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	PageToken     string                 `protobuf:"bytes,2,opt,name=page_token,json=pageToken,proto3" json:"page_token,omitempty"`
+	ItemContains  string                 `protobuf:"bytes,3,opt,name=item_contains,json=itemContains,proto3" json:"item_contains,omitempty"`
+	MinAmount     *Money                 `protobuf:"bytes,4,opt,name=min_amount,json=minAmount,proto3" json:"min_amount,omitempty"`
+	MaxAmount     *Money                 `protobuf:"bytes,5,opt,name=max_amount,json=maxAmount,proto3" json:"max_amount,omitempty"`
+	OrderBy       string                 `protobuf:"bytes,6,opt,name=order_by,json=orderBy,proto3" json:"order_by,omitempty"`
 	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
 func (x *ListOrdersRequest) Reset() {
 	*x = ListOrdersRequest{}
-	if protoimpl.UnsafeEnabled {
-		mi := &file_order_proto_msgTypes[0]
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		ms.StoreMessageInfo(mi)
-	}
+	mi := &file_order_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
 }
 
 func (x *ListOrdersRequest) String() string {
@@ -43,7 +180,7 @@ func (*ListOrdersRequest) ProtoMessage() {}
 
 func (x *ListOrdersRequest) ProtoReflect() protoreflect.Message {
 	mi := &file_order_proto_msgTypes[0]
-	if protoimpl.UnsafeEnabled && x != nil {
+	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
 			ms.StoreMessageInfo(mi)
@@ -58,21 +195,62 @@ func (*ListOrdersRequest) Descriptor() ([]byte, []int) {
 	return file_order_proto_rawDescGZIP(), []int{0}
 }
 
+func (x *ListOrdersRequest) GetPageSize() int32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+func (x *ListOrdersRequest) GetPageToken() string {
+	if x != nil {
+		return x.PageToken
+	}
+	return ""
+}
+
+func (x *ListOrdersRequest) GetItemContains() string {
+	if x != nil {
+		return x.ItemContains
+	}
+	return ""
+}
+
+func (x *ListOrdersRequest) GetMinAmount() *Money {
+	if x != nil {
+		return x.MinAmount
+	}
+	return nil
+}
+
+func (x *ListOrdersRequest) GetMaxAmount() *Money {
+	if x != nil {
+		return x.MaxAmount
+	}
+	return nil
+}
+
+func (x *ListOrdersRequest) GetOrderBy() string {
+	if x != nil {
+		return x.OrderBy
+	}
+	return ""
+}
+
 type ListOrdersResponse struct {
-	state         protoimpl.MessageState
-	sizeCache     protoimpl.SizeCache
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Orders []*Order               `protobuf:"bytes,1,rep,name=orders,proto3" json:"orders,omitempty"`
+	// next_page_token resumes the listing, empty on the last page
+	NextPageToken string `protobuf:"bytes,2,opt,name=next_page_token,json=nextPageToken,proto3" json:"next_page_token,omitempty"`
 	unknownFields protoimpl.UnknownFields
-
-	Orders []*Order `protobuf:"bytes,1,rep,name=orders,proto3" json:"orders,omitempty"`
+	sizeCache     protoimpl.SizeCache
 }
 
 func (x *ListOrdersResponse) Reset() {
 	*x = ListOrdersResponse{}
-	if protoimpl.UnsafeEnabled {
-		mi := &file_order_proto_msgTypes[1]
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		ms.StoreMessageInfo(mi)
-	}
+	mi := &file_order_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
 }
 
 func (x *ListOrdersResponse) String() string {
@@ -83,7 +261,7 @@ func (*ListOrdersResponse) ProtoMessage() {}
 
 func (x *ListOrdersResponse) ProtoReflect() protoreflect.Message {
 	mi := &file_order_proto_msgTypes[1]
-	if protoimpl.UnsafeEnabled && x != nil {
+	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
 			ms.StoreMessageInfo(mi)
@@ -105,34 +283,136 @@ func (x *ListOrdersResponse) GetOrders() []*Order {
 	return nil
 }
 
-type Order struct {
-	state         protoimpl.MessageState
+func (x *ListOrdersResponse) GetNextPageToken() string {
+	if x != nil {
+		return x.NextPageToken
+	}
+	return ""
+}
+
+type CreateOrderRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Item          string                 `protobuf:"bytes,1,opt,name=item,proto3" json:"item,omitempty"`
+	Lines         []*OrderLine           `protobuf:"bytes,4,rep,name=lines,proto3" json:"lines,omitempty"`
+	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateOrderRequest) Reset() {
+	*x = CreateOrderRequest{}
+	mi := &file_order_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateOrderRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateOrderRequest) ProtoMessage() {}
+
+func (x *CreateOrderRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_order_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateOrderRequest.ProtoReflect.Descriptor instead.
+func (*CreateOrderRequest) Descriptor() ([]byte, []int) {
+	return file_order_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *CreateOrderRequest) GetItem() string {
+	if x != nil {
+		return x.Item
+	}
+	return ""
+}
+
+func (x *CreateOrderRequest) GetLines() []*OrderLine {
+	if x != nil {
+		return x.Lines
+	}
+	return nil
+}
+
+type GetOrderRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int32                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
 	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
 
-	Id     int32   `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
-	Item   string  `protobuf:"bytes,2,opt,name=item,proto3" json:"item,omitempty"`
-	Amount float32 `protobuf:"fixed32,3,opt,name=amount,proto3" json:"amount,omitempty"`
+func (x *GetOrderRequest) Reset() {
+	*x = GetOrderRequest{}
+	mi := &file_order_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
 }
 
-func (x *Order) Reset() {
-	*x = Order{}
-	if protoimpl.UnsafeEnabled {
-		mi := &file_order_proto_msgTypes[2]
+func (x *GetOrderRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetOrderRequest) ProtoMessage() {}
+
+func (x *GetOrderRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_order_proto_msgTypes[3]
+	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		ms.StoreMessageInfo(mi)
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
 	}
+	return mi.MessageOf(x)
 }
 
-func (x *Order) String() string {
+// Deprecated: Use GetOrderRequest.ProtoReflect.Descriptor instead.
+func (*GetOrderRequest) Descriptor() ([]byte, []int) {
+	return file_order_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *GetOrderRequest) GetId() int32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+type UpdateOrderRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    int32                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Item  string                 `protobuf:"bytes,2,opt,name=item,proto3" json:"item,omitempty"`
+	Lines []*OrderLine           `protobuf:"bytes,5,rep,name=lines,proto3" json:"lines,omitempty"`
+	// version, when set, must be the stored version or the call fails with ABORTED
+	Version       int32 `protobuf:"varint,6,opt,name=version,proto3" json:"version,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateOrderRequest) Reset() {
+	*x = UpdateOrderRequest{}
+	mi := &file_order_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateOrderRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*Order) ProtoMessage() {}
+func (*UpdateOrderRequest) ProtoMessage() {}
 
-func (x *Order) ProtoReflect() protoreflect.Message {
-	mi := &file_order_proto_msgTypes[2]
-	if protoimpl.UnsafeEnabled && x != nil {
+func (x *UpdateOrderRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_order_proto_msgTypes[4]
+	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
 			ms.StoreMessageInfo(mi)
@@ -142,147 +422,941 @@ func (x *Order) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use Order.ProtoReflect.Descriptor instead.
-func (*Order) Descriptor() ([]byte, []int) {
-	return file_order_proto_rawDescGZIP(), []int{2}
+// Deprecated: Use UpdateOrderRequest.ProtoReflect.Descriptor instead.
+func (*UpdateOrderRequest) Descriptor() ([]byte, []int) {
+	return file_order_proto_rawDescGZIP(), []int{4}
 }
 
-func (x *Order) GetId() int32 {
+func (x *UpdateOrderRequest) GetId() int32 {
 	if x != nil {
 		return x.Id
 	}
 	return 0
 }
 
-func (x *Order) GetItem() string {
+func (x *UpdateOrderRequest) GetItem() string {
 	if x != nil {
 		return x.Item
 	}
 	return ""
 }
 
-func (x *Order) GetAmount() float32 {
+func (x *UpdateOrderRequest) GetLines() []*OrderLine {
 	if x != nil {
-		return x.Amount
+		return x.Lines
+	}
+	return nil
+}
+
+func (x *UpdateOrderRequest) GetVersion() int32 {
+	if x != nil {
+		return x.Version
 	}
 	return 0
 }
 
-var File_order_proto protoreflect.FileDescriptor
+type DeleteOrderRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    int32                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	// version, when set, must be the stored version or the call fails with ABORTED
+	Version       int32 `protobuf:"varint,2,opt,name=version,proto3" json:"version,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
 
-var file_order_proto_rawDesc = []byte{
-	0x0a, 0x0b, 0x6f, 0x72, 0x64, 0x65, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x09, 0x66,
-	0x75, 0x6c, 0x6c, 0x63, 0x79, 0x63, 0x6c, 0x65, 0x22, 0x13, 0x0a, 0x11, 0x4c, 0x69, 0x73, 0x74,
-	0x4f, 0x72, 0x64, 0x65, 0x72, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x22, 0x3e, 0x0a,
-	0x12, 0x4c, 0x69, 0x73, 0x74, 0x4f, 0x72, 0x64, 0x65, 0x72, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f,
-	0x6e, 0x73, 0x65, 0x12, 0x28, 0x0a, 0x06, 0x6f, 0x72, 0x64, 0x65, 0x72, 0x73, 0x18, 0x01, 0x20,
-	0x03, 0x28, 0x0b, 0x32, 0x10, 0x2e, 0x66, 0x75, 0x6c, 0x6c, 0x63, 0x79, 0x63, 0x6c, 0x65, 0x2e,
-	0x4f, 0x72, 0x64, 0x65, 0x72, 0x52, 0x06, 0x6f, 0x72, 0x64, 0x65, 0x72, 0x73, 0x22, 0x43, 0x0a,
-	0x05, 0x4f, 0x72, 0x64, 0x65, 0x72, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01,
-	0x28, 0x05, 0x52, 0x02, 0x69, 0x64, 0x12, 0x12, 0x0a, 0x04, 0x69, 0x74, 0x65, 0x6d, 0x18, 0x02,
-	0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x69, 0x74, 0x65, 0x6d, 0x12, 0x16, 0x0a, 0x06, 0x61, 0x6d,
-	0x6f, 0x75, 0x6e, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x02, 0x52, 0x06, 0x61, 0x6d, 0x6f, 0x75,
-	0x6e, 0x74, 0x32, 0x59, 0x0a, 0x0c, 0x4f, 0x72, 0x64, 0x65, 0x72, 0x53, 0x65, 0x72, 0x76, 0x69,
-	0x63, 0x65, 0x12, 0x49, 0x0a, 0x0a, 0x4c, 0x69, 0x73, 0x74, 0x4f, 0x72, 0x64, 0x65, 0x72, 0x73,
-	0x12, 0x1c, 0x2e, 0x66, 0x75, 0x6c, 0x6c, 0x63, 0x79, 0x63, 0x6c, 0x65, 0x2e, 0x4c, 0x69, 0x73,
-	0x74, 0x4f, 0x72, 0x64, 0x65, 0x72, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1d,
-	0x2e, 0x66, 0x75, 0x6c, 0x6c, 0x63, 0x79, 0x63, 0x6c, 0x65, 0x2e, 0x4c, 0x69, 0x73, 0x74, 0x4f,
-	0x72, 0x64, 0x65, 0x72, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x42, 0x41, 0x5a,
-	0x3f, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x64, 0x79, 0x61, 0x6d,
-	0x6d, 0x61, 0x72, 0x63, 0x61, 0x6e, 0x6f, 0x2f, 0x66, 0x75, 0x6c, 0x6c, 0x63, 0x79, 0x63, 0x6c,
-	0x65, 0x5f, 0x63, 0x6c, 0x65, 0x61, 0x6e, 0x5f, 0x61, 0x72, 0x63, 0x68, 0x69, 0x74, 0x65, 0x63,
-	0x74, 0x75, 0x72, 0x65, 0x2f, 0x70, 0x6b, 0x67, 0x2f, 0x67, 0x72, 0x70, 0x63, 0x2f, 0x70, 0x62,
-	0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+func (x *DeleteOrderRequest) Reset() {
+	*x = DeleteOrderRequest{}
+	mi := &file_order_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
 }
 
-var (
-	file_order_proto_rawDescOnce sync.Once
-	file_order_proto_rawDescData = file_order_proto_rawDesc
-)
+func (x *DeleteOrderRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
 
-func file_order_proto_rawDescGZIP() []byte {
-	file_order_proto_rawDescOnce.Do(func() {
-		file_order_proto_rawDescData = protoimpl.X.CompressGZIP(file_order_proto_rawDescData)
-	})
-	return file_order_proto_rawDescData
+func (*DeleteOrderRequest) ProtoMessage() {}
+
+func (x *DeleteOrderRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_order_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
 }
 
-var file_order_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
-var file_order_proto_goTypes = []interface{}{
-	(*ListOrdersRequest)(nil),  // 0: fullcycle.ListOrdersRequest
-	(*ListOrdersResponse)(nil), // 1: fullcycle.ListOrdersResponse
-	(*Order)(nil),              // 2: fullcycle.Order
+// Deprecated: Use DeleteOrderRequest.ProtoReflect.Descriptor instead.
+func (*DeleteOrderRequest) Descriptor() ([]byte, []int) {
+	return file_order_proto_rawDescGZIP(), []int{5}
 }
-var file_order_proto_depIdxs = []int32{
-	2, // 0: fullcycle.ListOrdersResponse.orders:type_name -> fullcycle.Order
-	0, // 1: fullcycle.OrderService.ListOrders:input_type -> fullcycle.ListOrdersRequest
-	1, // 2: fullcycle.OrderService.ListOrders:output_type -> fullcycle.ListOrdersResponse
-	2, // [2:3] is the sub-list for method output_type
-	1, // [1:2] is the sub-list for method input_type
-	1, // [1:1] is the sub-list for extension type_name
-	1, // [1:1] is the sub-list for extension extendee
-	0, // [0:1] is the sub-list for field type_name
+
+func (x *DeleteOrderRequest) GetId() int32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
 }
 
-func init() { file_order_proto_init() }
-func file_order_proto_init() {
-	if File_order_proto != nil {
-		return
+func (x *DeleteOrderRequest) GetVersion() int32 {
+	if x != nil {
+		return x.Version
 	}
-	if !protoimpl.UnsafeEnabled {
-		file_order_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*ListOrdersRequest); i {
-			case 0:
-				return &v.state
-			case 1:
-				return &v.sizeCache
-			case 2:
-				return &v.unknownFields
-			default:
-				return nil
-			}
-		}
-		file_order_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*ListOrdersResponse); i {
-			case 0:
-				return &v.state
-			case 1:
-				return &v.sizeCache
-			case 2:
-				return &v.unknownFields
-			default:
-				return nil
-			}
+	return 0
+}
+
+type DeleteOrderResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteOrderResponse) Reset() {
+	*x = DeleteOrderResponse{}
+	mi := &file_order_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteOrderResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteOrderResponse) ProtoMessage() {}
+
+func (x *DeleteOrderResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_order_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
 		}
-		file_order_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*Order); i {
-			case 0:
-				return &v.state
-			case 1:
-				return &v.sizeCache
-			case 2:
-				return &v.unknownFields
-			default:
-				return nil
-			}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteOrderResponse.ProtoReflect.Descriptor instead.
+func (*DeleteOrderResponse) Descriptor() ([]byte, []int) {
+	return file_order_proto_rawDescGZIP(), []int{6}
+}
+
+type TransitionOrderRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int32                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Status        OrderStatus            `protobuf:"varint,2,opt,name=status,proto3,enum=fullcycle.OrderStatus" json:"status,omitempty"`
+	Reason        string                 `protobuf:"bytes,3,opt,name=reason,proto3" json:"reason,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TransitionOrderRequest) Reset() {
+	*x = TransitionOrderRequest{}
+	mi := &file_order_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TransitionOrderRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TransitionOrderRequest) ProtoMessage() {}
+
+func (x *TransitionOrderRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_order_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
 		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TransitionOrderRequest.ProtoReflect.Descriptor instead.
+func (*TransitionOrderRequest) Descriptor() ([]byte, []int) {
+	return file_order_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *TransitionOrderRequest) GetId() int32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *TransitionOrderRequest) GetStatus() OrderStatus {
+	if x != nil {
+		return x.Status
+	}
+	return OrderStatus_ORDER_STATUS_UNSPECIFIED
+}
+
+func (x *TransitionOrderRequest) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+type GetOrderHistoryRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int32                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetOrderHistoryRequest) Reset() {
+	*x = GetOrderHistoryRequest{}
+	mi := &file_order_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetOrderHistoryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetOrderHistoryRequest) ProtoMessage() {}
+
+func (x *GetOrderHistoryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_order_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetOrderHistoryRequest.ProtoReflect.Descriptor instead.
+func (*GetOrderHistoryRequest) Descriptor() ([]byte, []int) {
+	return file_order_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *GetOrderHistoryRequest) GetId() int32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+type GetOrderHistoryResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Transitions   []*StatusTransition    `protobuf:"bytes,1,rep,name=transitions,proto3" json:"transitions,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetOrderHistoryResponse) Reset() {
+	*x = GetOrderHistoryResponse{}
+	mi := &file_order_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetOrderHistoryResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetOrderHistoryResponse) ProtoMessage() {}
+
+func (x *GetOrderHistoryResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_order_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetOrderHistoryResponse.ProtoReflect.Descriptor instead.
+func (*GetOrderHistoryResponse) Descriptor() ([]byte, []int) {
+	return file_order_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *GetOrderHistoryResponse) GetTransitions() []*StatusTransition {
+	if x != nil {
+		return x.Transitions
+	}
+	return nil
+}
+
+type StatusTransition struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	From          OrderStatus            `protobuf:"varint,1,opt,name=from,proto3,enum=fullcycle.OrderStatus" json:"from,omitempty"`
+	To            OrderStatus            `protobuf:"varint,2,opt,name=to,proto3,enum=fullcycle.OrderStatus" json:"to,omitempty"`
+	Reason        string                 `protobuf:"bytes,3,opt,name=reason,proto3" json:"reason,omitempty"`
+	At            *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=at,proto3" json:"at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StatusTransition) Reset() {
+	*x = StatusTransition{}
+	mi := &file_order_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StatusTransition) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StatusTransition) ProtoMessage() {}
+
+func (x *StatusTransition) ProtoReflect() protoreflect.Message {
+	mi := &file_order_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StatusTransition.ProtoReflect.Descriptor instead.
+func (*StatusTransition) Descriptor() ([]byte, []int) {
+	return file_order_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *StatusTransition) GetFrom() OrderStatus {
+	if x != nil {
+		return x.From
+	}
+	return OrderStatus_ORDER_STATUS_UNSPECIFIED
+}
+
+func (x *StatusTransition) GetTo() OrderStatus {
+	if x != nil {
+		return x.To
+	}
+	return OrderStatus_ORDER_STATUS_UNSPECIFIED
+}
+
+func (x *StatusTransition) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *StatusTransition) GetAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.At
+	}
+	return nil
+}
+
+// WatchOrdersRequest subscribes to order changes. With resume_token the stream
+// continues right after the message the token came with; it fails with
+// FAILED_PRECONDITION once that change is no longer buffered, or when a client
+// reads so slowly that the buffer overtakes it, and the client then watches again
+// with include_snapshot.
+type WatchOrdersRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// order_ids and types filter the changes; an empty list matches every change
+	OrderIds []int32           `protobuf:"varint,1,rep,packed,name=order_ids,json=orderIds,proto3" json:"order_ids,omitempty"`
+	Types    []OrderChangeType `protobuf:"varint,2,rep,packed,name=types,proto3,enum=fullcycle.OrderChangeType" json:"types,omitempty"`
+	// include_snapshot sends the current orders as SNAPSHOT changes, closed by a
+	// SNAPSHOT_COMPLETE one, before the changes. It is ignored when resuming.
+	IncludeSnapshot bool   `protobuf:"varint,3,opt,name=include_snapshot,json=includeSnapshot,proto3" json:"include_snapshot,omitempty"`
+	ResumeToken     string `protobuf:"bytes,4,opt,name=resume_token,json=resumeToken,proto3" json:"resume_token,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *WatchOrdersRequest) Reset() {
+	*x = WatchOrdersRequest{}
+	mi := &file_order_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchOrdersRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchOrdersRequest) ProtoMessage() {}
+
+func (x *WatchOrdersRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_order_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchOrdersRequest.ProtoReflect.Descriptor instead.
+func (*WatchOrdersRequest) Descriptor() ([]byte, []int) {
+	return file_order_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *WatchOrdersRequest) GetOrderIds() []int32 {
+	if x != nil {
+		return x.OrderIds
+	}
+	return nil
+}
+
+func (x *WatchOrdersRequest) GetTypes() []OrderChangeType {
+	if x != nil {
+		return x.Types
+	}
+	return nil
+}
+
+func (x *WatchOrdersRequest) GetIncludeSnapshot() bool {
+	if x != nil {
+		return x.IncludeSnapshot
+	}
+	return false
+}
+
+func (x *WatchOrdersRequest) GetResumeToken() string {
+	if x != nil {
+		return x.ResumeToken
+	}
+	return ""
+}
+
+type OrderChange struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Type  OrderChangeType        `protobuf:"varint,1,opt,name=type,proto3,enum=fullcycle.OrderChangeType" json:"type,omitempty"`
+	// order is the order after the change, unset for SNAPSHOT_COMPLETE
+	Order *Order `protobuf:"bytes,2,opt,name=order,proto3" json:"order,omitempty"`
+	// transition is the transition applied by STATUS_CHANGED changes
+	Transition *StatusTransition `protobuf:"bytes,3,opt,name=transition,proto3" json:"transition,omitempty"`
+	// event_id is the ID of the domain event, zero for the snapshot
+	EventId    int64                  `protobuf:"varint,4,opt,name=event_id,json=eventId,proto3" json:"event_id,omitempty"`
+	OccurredAt *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=occurred_at,json=occurredAt,proto3" json:"occurred_at,omitempty"`
+	// resume_token resumes the stream right after this message
+	ResumeToken   string `protobuf:"bytes,6,opt,name=resume_token,json=resumeToken,proto3" json:"resume_token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *OrderChange) Reset() {
+	*x = OrderChange{}
+	mi := &file_order_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *OrderChange) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OrderChange) ProtoMessage() {}
+
+func (x *OrderChange) ProtoReflect() protoreflect.Message {
+	mi := &file_order_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OrderChange.ProtoReflect.Descriptor instead.
+func (*OrderChange) Descriptor() ([]byte, []int) {
+	return file_order_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *OrderChange) GetType() OrderChangeType {
+	if x != nil {
+		return x.Type
+	}
+	return OrderChangeType_ORDER_CHANGE_TYPE_UNSPECIFIED
+}
+
+func (x *OrderChange) GetOrder() *Order {
+	if x != nil {
+		return x.Order
+	}
+	return nil
+}
+
+func (x *OrderChange) GetTransition() *StatusTransition {
+	if x != nil {
+		return x.Transition
+	}
+	return nil
+}
+
+func (x *OrderChange) GetEventId() int64 {
+	if x != nil {
+		return x.EventId
+	}
+	return 0
+}
+
+func (x *OrderChange) GetOccurredAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.OccurredAt
+	}
+	return nil
+}
+
+func (x *OrderChange) GetResumeToken() string {
+	if x != nil {
+		return x.ResumeToken
+	}
+	return ""
+}
+
+type Order struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    int32                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Item  string                 `protobuf:"bytes,2,opt,name=item,proto3" json:"item,omitempty"`
+	// amount is the order total computed from the lines
+	Amount    *Money                 `protobuf:"bytes,4,opt,name=amount,proto3" json:"amount,omitempty"`
+	Lines     []*OrderLine           `protobuf:"bytes,5,rep,name=lines,proto3" json:"lines,omitempty"`
+	Status    OrderStatus            `protobuf:"varint,6,opt,name=status,proto3,enum=fullcycle.OrderStatus" json:"status,omitempty"`
+	CreatedAt *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	UpdatedAt *timestamppb.Timestamp `protobuf:"bytes,8,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	// version grows by one on every write of the order
+	Version       int32 `protobuf:"varint,9,opt,name=version,proto3" json:"version,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Order) Reset() {
+	*x = Order{}
+	mi := &file_order_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Order) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Order) ProtoMessage() {}
+
+func (x *Order) ProtoReflect() protoreflect.Message {
+	mi := &file_order_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Order.ProtoReflect.Descriptor instead.
+func (*Order) Descriptor() ([]byte, []int) {
+	return file_order_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *Order) GetId() int32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Order) GetItem() string {
+	if x != nil {
+		return x.Item
+	}
+	return ""
+}
+
+func (x *Order) GetAmount() *Money {
+	if x != nil {
+		return x.Amount
+	}
+	return nil
+}
+
+func (x *Order) GetLines() []*OrderLine {
+	if x != nil {
+		return x.Lines
+	}
+	return nil
+}
+
+func (x *Order) GetStatus() OrderStatus {
+	if x != nil {
+		return x.Status
+	}
+	return OrderStatus_ORDER_STATUS_UNSPECIFIED
+}
+
+func (x *Order) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *Order) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+func (x *Order) GetVersion() int32 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+type OrderLine struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Sku           string                 `protobuf:"bytes,1,opt,name=sku,proto3" json:"sku,omitempty"`
+	Description   string                 `protobuf:"bytes,2,opt,name=description,proto3" json:"description,omitempty"`
+	Quantity      int32                  `protobuf:"varint,3,opt,name=quantity,proto3" json:"quantity,omitempty"`
+	UnitPrice     *Money                 `protobuf:"bytes,4,opt,name=unit_price,json=unitPrice,proto3" json:"unit_price,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *OrderLine) Reset() {
+	*x = OrderLine{}
+	mi := &file_order_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *OrderLine) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OrderLine) ProtoMessage() {}
+
+func (x *OrderLine) ProtoReflect() protoreflect.Message {
+	mi := &file_order_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OrderLine.ProtoReflect.Descriptor instead.
+func (*OrderLine) Descriptor() ([]byte, []int) {
+	return file_order_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *OrderLine) GetSku() string {
+	if x != nil {
+		return x.Sku
+	}
+	return ""
+}
+
+func (x *OrderLine) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *OrderLine) GetQuantity() int32 {
+	if x != nil {
+		return x.Quantity
+	}
+	return 0
+}
+
+func (x *OrderLine) GetUnitPrice() *Money {
+	if x != nil {
+		return x.UnitPrice
+	}
+	return nil
+}
+
+// Money is an exact amount in the minor unit of its currency (e.g. 10050 USD is 100.50)
+type Money struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Units         int64                  `protobuf:"varint,1,opt,name=units,proto3" json:"units,omitempty"`
+	CurrencyCode  string                 `protobuf:"bytes,2,opt,name=currency_code,json=currencyCode,proto3" json:"currency_code,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Money) Reset() {
+	*x = Money{}
+	mi := &file_order_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Money) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Money) ProtoMessage() {}
+
+func (x *Money) ProtoReflect() protoreflect.Message {
+	mi := &file_order_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Money.ProtoReflect.Descriptor instead.
+func (*Money) Descriptor() ([]byte, []int) {
+	return file_order_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *Money) GetUnits() int64 {
+	if x != nil {
+		return x.Units
+	}
+	return 0
+}
+
+func (x *Money) GetCurrencyCode() string {
+	if x != nil {
+		return x.CurrencyCode
+	}
+	return ""
+}
+
+var File_order_proto protoreflect.FileDescriptor
+
+const file_order_proto_rawDesc = "" +
+	"\n" +
+	"\vorder.proto\x12\tfullcycle\x1a\x1fgoogle/protobuf/timestamp.proto\"\xf1\x01\n" +
+	"\x11ListOrdersRequest\x12\x1b\n" +
+	"\tpage_size\x18\x01 \x01(\x05R\bpageSize\x12\x1d\n" +
+	"\n" +
+	"page_token\x18\x02 \x01(\tR\tpageToken\x12#\n" +
+	"\ritem_contains\x18\x03 \x01(\tR\fitemContains\x12/\n" +
+	"\n" +
+	"min_amount\x18\x04 \x01(\v2\x10.fullcycle.MoneyR\tminAmount\x12/\n" +
+	"\n" +
+	"max_amount\x18\x05 \x01(\v2\x10.fullcycle.MoneyR\tmaxAmount\x12\x19\n" +
+	"\border_by\x18\x06 \x01(\tR\aorderBy\"f\n" +
+	"\x12ListOrdersResponse\x12(\n" +
+	"\x06orders\x18\x01 \x03(\v2\x10.fullcycle.OrderR\x06orders\x12&\n" +
+	"\x0fnext_page_token\x18\x02 \x01(\tR\rnextPageToken\"`\n" +
+	"\x12CreateOrderRequest\x12\x12\n" +
+	"\x04item\x18\x01 \x01(\tR\x04item\x12*\n" +
+	"\x05lines\x18\x04 \x03(\v2\x14.fullcycle.OrderLineR\x05linesJ\x04\b\x02\x10\x03J\x04\b\x03\x10\x04\"!\n" +
+	"\x0fGetOrderRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x05R\x02id\"\x8a\x01\n" +
+	"\x12UpdateOrderRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x05R\x02id\x12\x12\n" +
+	"\x04item\x18\x02 \x01(\tR\x04item\x12*\n" +
+	"\x05lines\x18\x05 \x03(\v2\x14.fullcycle.OrderLineR\x05lines\x12\x18\n" +
+	"\aversion\x18\x06 \x01(\x05R\aversionJ\x04\b\x03\x10\x04J\x04\b\x04\x10\x05\">\n" +
+	"\x12DeleteOrderRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x05R\x02id\x12\x18\n" +
+	"\aversion\x18\x02 \x01(\x05R\aversion\"\x15\n" +
+	"\x13DeleteOrderResponse\"p\n" +
+	"\x16TransitionOrderRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x05R\x02id\x12.\n" +
+	"\x06status\x18\x02 \x01(\x0e2\x16.fullcycle.OrderStatusR\x06status\x12\x16\n" +
+	"\x06reason\x18\x03 \x01(\tR\x06reason\"(\n" +
+	"\x16GetOrderHistoryRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x05R\x02id\"X\n" +
+	"\x17GetOrderHistoryResponse\x12=\n" +
+	"\vtransitions\x18\x01 \x03(\v2\x1b.fullcycle.StatusTransitionR\vtransitions\"\xaa\x01\n" +
+	"\x10StatusTransition\x12*\n" +
+	"\x04from\x18\x01 \x01(\x0e2\x16.fullcycle.OrderStatusR\x04from\x12&\n" +
+	"\x02to\x18\x02 \x01(\x0e2\x16.fullcycle.OrderStatusR\x02to\x12\x16\n" +
+	"\x06reason\x18\x03 \x01(\tR\x06reason\x12*\n" +
+	"\x02at\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\x02at\"\xb1\x01\n" +
+	"\x12WatchOrdersRequest\x12\x1b\n" +
+	"\torder_ids\x18\x01 \x03(\x05R\borderIds\x120\n" +
+	"\x05types\x18\x02 \x03(\x0e2\x1a.fullcycle.OrderChangeTypeR\x05types\x12)\n" +
+	"\x10include_snapshot\x18\x03 \x01(\bR\x0fincludeSnapshot\x12!\n" +
+	"\fresume_token\x18\x04 \x01(\tR\vresumeToken\"\x9d\x02\n" +
+	"\vOrderChange\x12.\n" +
+	"\x04type\x18\x01 \x01(\x0e2\x1a.fullcycle.OrderChangeTypeR\x04type\x12&\n" +
+	"\x05order\x18\x02 \x01(\v2\x10.fullcycle.OrderR\x05order\x12;\n" +
+	"\n" +
+	"transition\x18\x03 \x01(\v2\x1b.fullcycle.StatusTransitionR\n" +
+	"transition\x12\x19\n" +
+	"\bevent_id\x18\x04 \x01(\x03R\aeventId\x12;\n" +
+	"\voccurred_at\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"occurredAt\x12!\n" +
+	"\fresume_token\x18\x06 \x01(\tR\vresumeToken\"\xc7\x02\n" +
+	"\x05Order\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x05R\x02id\x12\x12\n" +
+	"\x04item\x18\x02 \x01(\tR\x04item\x12(\n" +
+	"\x06amount\x18\x04 \x01(\v2\x10.fullcycle.MoneyR\x06amount\x12*\n" +
+	"\x05lines\x18\x05 \x03(\v2\x14.fullcycle.OrderLineR\x05lines\x12.\n" +
+	"\x06status\x18\x06 \x01(\x0e2\x16.fullcycle.OrderStatusR\x06status\x129\n" +
+	"\n" +
+	"created_at\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x129\n" +
+	"\n" +
+	"updated_at\x18\b \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAt\x12\x18\n" +
+	"\aversion\x18\t \x01(\x05R\aversionJ\x04\b\x03\x10\x04\"\x8c\x01\n" +
+	"\tOrderLine\x12\x10\n" +
+	"\x03sku\x18\x01 \x01(\tR\x03sku\x12 \n" +
+	"\vdescription\x18\x02 \x01(\tR\vdescription\x12\x1a\n" +
+	"\bquantity\x18\x03 \x01(\x05R\bquantity\x12/\n" +
+	"\n" +
+	"unit_price\x18\x04 \x01(\v2\x10.fullcycle.MoneyR\tunitPrice\"B\n" +
+	"\x05Money\x12\x14\n" +
+	"\x05units\x18\x01 \x01(\x03R\x05units\x12#\n" +
+	"\rcurrency_code\x18\x02 \x01(\tR\fcurrencyCode*\xe5\x01\n" +
+	"\vOrderStatus\x12\x1c\n" +
+	"\x18ORDER_STATUS_UNSPECIFIED\x10\x00\x12\x18\n" +
+	"\x14ORDER_STATUS_PENDING\x10\x01\x12\x1a\n" +
+	"\x16ORDER_STATUS_CONFIRMED\x10\x02\x12\x15\n" +
+	"\x11ORDER_STATUS_PAID\x10\x03\x12\x18\n" +
+	"\x14ORDER_STATUS_SHIPPED\x10\x04\x12\x1a\n" +
+	"\x16ORDER_STATUS_DELIVERED\x10\x05\x12\x1a\n" +
+	"\x16ORDER_STATUS_CANCELLED\x10\x06\x12\x19\n" +
+	"\x15ORDER_STATUS_REFUNDED\x10\a*\xa0\x02\n" +
+	"\x0fOrderChangeType\x12!\n" +
+	"\x1dORDER_CHANGE_TYPE_UNSPECIFIED\x10\x00\x12\x1e\n" +
+	"\x1aORDER_CHANGE_TYPE_SNAPSHOT\x10\x01\x12'\n" +
+	"#ORDER_CHANGE_TYPE_SNAPSHOT_COMPLETE\x10\x02\x12\x1d\n" +
+	"\x19ORDER_CHANGE_TYPE_CREATED\x10\x03\x12\x1d\n" +
+	"\x19ORDER_CHANGE_TYPE_UPDATED\x10\x04\x12\x1d\n" +
+	"\x19ORDER_CHANGE_TYPE_DELETED\x10\x05\x12\x1e\n" +
+	"\x1aORDER_CHANGE_TYPE_RESTORED\x10\x06\x12$\n" +
+	" ORDER_CHANGE_TYPE_STATUS_CHANGED\x10\a2\xcb\x04\n" +
+	"\fOrderService\x12I\n" +
+	"\n" +
+	"ListOrders\x12\x1c.fullcycle.ListOrdersRequest\x1a\x1d.fullcycle.ListOrdersResponse\x12>\n" +
+	"\vCreateOrder\x12\x1d.fullcycle.CreateOrderRequest\x1a\x10.fullcycle.Order\x128\n" +
+	"\bGetOrder\x12\x1a.fullcycle.GetOrderRequest\x1a\x10.fullcycle.Order\x12>\n" +
+	"\vUpdateOrder\x12\x1d.fullcycle.UpdateOrderRequest\x1a\x10.fullcycle.Order\x12L\n" +
+	"\vDeleteOrder\x12\x1d.fullcycle.DeleteOrderRequest\x1a\x1e.fullcycle.DeleteOrderResponse\x12F\n" +
+	"\x0fTransitionOrder\x12!.fullcycle.TransitionOrderRequest\x1a\x10.fullcycle.Order\x12X\n" +
+	"\x0fGetOrderHistory\x12!.fullcycle.GetOrderHistoryRequest\x1a\".fullcycle.GetOrderHistoryResponse\x12F\n" +
+	"\vWatchOrders\x12\x1d.fullcycle.WatchOrdersRequest\x1a\x16.fullcycle.OrderChange0\x01BAZ?github.com/dyammarcano/fullcycle_clean_architecture/pkg/grpc/pbb\x06proto3"
+
+var (
+	file_order_proto_rawDescOnce sync.Once
+	file_order_proto_rawDescData []byte
+)
+
+func file_order_proto_rawDescGZIP() []byte {
+	file_order_proto_rawDescOnce.Do(func() {
+		file_order_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_order_proto_rawDesc), len(file_order_proto_rawDesc)))
+	})
+	return file_order_proto_rawDescData
+}
+
+var file_order_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
+var file_order_proto_msgTypes = make([]protoimpl.MessageInfo, 16)
+var file_order_proto_goTypes = []any{
+	(OrderStatus)(0),                // 0: fullcycle.OrderStatus
+	(OrderChangeType)(0),            // 1: fullcycle.OrderChangeType
+	(*ListOrdersRequest)(nil),       // 2: fullcycle.ListOrdersRequest
+	(*ListOrdersResponse)(nil),      // 3: fullcycle.ListOrdersResponse
+	(*CreateOrderRequest)(nil),      // 4: fullcycle.CreateOrderRequest
+	(*GetOrderRequest)(nil),         // 5: fullcycle.GetOrderRequest
+	(*UpdateOrderRequest)(nil),      // 6: fullcycle.UpdateOrderRequest
+	(*DeleteOrderRequest)(nil),      // 7: fullcycle.DeleteOrderRequest
+	(*DeleteOrderResponse)(nil),     // 8: fullcycle.DeleteOrderResponse
+	(*TransitionOrderRequest)(nil),  // 9: fullcycle.TransitionOrderRequest
+	(*GetOrderHistoryRequest)(nil),  // 10: fullcycle.GetOrderHistoryRequest
+	(*GetOrderHistoryResponse)(nil), // 11: fullcycle.GetOrderHistoryResponse
+	(*StatusTransition)(nil),        // 12: fullcycle.StatusTransition
+	(*WatchOrdersRequest)(nil),      // 13: fullcycle.WatchOrdersRequest
+	(*OrderChange)(nil),             // 14: fullcycle.OrderChange
+	(*Order)(nil),                   // 15: fullcycle.Order
+	(*OrderLine)(nil),               // 16: fullcycle.OrderLine
+	(*Money)(nil),                   // 17: fullcycle.Money
+	(*timestamppb.Timestamp)(nil),   // 18: google.protobuf.Timestamp
+}
+var file_order_proto_depIdxs = []int32{
+	17, // 0: fullcycle.ListOrdersRequest.min_amount:type_name -> fullcycle.Money
+	17, // 1: fullcycle.ListOrdersRequest.max_amount:type_name -> fullcycle.Money
+	15, // 2: fullcycle.ListOrdersResponse.orders:type_name -> fullcycle.Order
+	16, // 3: fullcycle.CreateOrderRequest.lines:type_name -> fullcycle.OrderLine
+	16, // 4: fullcycle.UpdateOrderRequest.lines:type_name -> fullcycle.OrderLine
+	0,  // 5: fullcycle.TransitionOrderRequest.status:type_name -> fullcycle.OrderStatus
+	12, // 6: fullcycle.GetOrderHistoryResponse.transitions:type_name -> fullcycle.StatusTransition
+	0,  // 7: fullcycle.StatusTransition.from:type_name -> fullcycle.OrderStatus
+	0,  // 8: fullcycle.StatusTransition.to:type_name -> fullcycle.OrderStatus
+	18, // 9: fullcycle.StatusTransition.at:type_name -> google.protobuf.Timestamp
+	1,  // 10: fullcycle.WatchOrdersRequest.types:type_name -> fullcycle.OrderChangeType
+	1,  // 11: fullcycle.OrderChange.type:type_name -> fullcycle.OrderChangeType
+	15, // 12: fullcycle.OrderChange.order:type_name -> fullcycle.Order
+	12, // 13: fullcycle.OrderChange.transition:type_name -> fullcycle.StatusTransition
+	18, // 14: fullcycle.OrderChange.occurred_at:type_name -> google.protobuf.Timestamp
+	17, // 15: fullcycle.Order.amount:type_name -> fullcycle.Money
+	16, // 16: fullcycle.Order.lines:type_name -> fullcycle.OrderLine
+	0,  // 17: fullcycle.Order.status:type_name -> fullcycle.OrderStatus
+	18, // 18: fullcycle.Order.created_at:type_name -> google.protobuf.Timestamp
+	18, // 19: fullcycle.Order.updated_at:type_name -> google.protobuf.Timestamp
+	17, // 20: fullcycle.OrderLine.unit_price:type_name -> fullcycle.Money
+	2,  // 21: fullcycle.OrderService.ListOrders:input_type -> fullcycle.ListOrdersRequest
+	4,  // 22: fullcycle.OrderService.CreateOrder:input_type -> fullcycle.CreateOrderRequest
+	5,  // 23: fullcycle.OrderService.GetOrder:input_type -> fullcycle.GetOrderRequest
+	6,  // 24: fullcycle.OrderService.UpdateOrder:input_type -> fullcycle.UpdateOrderRequest
+	7,  // 25: fullcycle.OrderService.DeleteOrder:input_type -> fullcycle.DeleteOrderRequest
+	9,  // 26: fullcycle.OrderService.TransitionOrder:input_type -> fullcycle.TransitionOrderRequest
+	10, // 27: fullcycle.OrderService.GetOrderHistory:input_type -> fullcycle.GetOrderHistoryRequest
+	13, // 28: fullcycle.OrderService.WatchOrders:input_type -> fullcycle.WatchOrdersRequest
+	3,  // 29: fullcycle.OrderService.ListOrders:output_type -> fullcycle.ListOrdersResponse
+	15, // 30: fullcycle.OrderService.CreateOrder:output_type -> fullcycle.Order
+	15, // 31: fullcycle.OrderService.GetOrder:output_type -> fullcycle.Order
+	15, // 32: fullcycle.OrderService.UpdateOrder:output_type -> fullcycle.Order
+	8,  // 33: fullcycle.OrderService.DeleteOrder:output_type -> fullcycle.DeleteOrderResponse
+	15, // 34: fullcycle.OrderService.TransitionOrder:output_type -> fullcycle.Order
+	11, // 35: fullcycle.OrderService.GetOrderHistory:output_type -> fullcycle.GetOrderHistoryResponse
+	14, // 36: fullcycle.OrderService.WatchOrders:output_type -> fullcycle.OrderChange
+	29, // [29:37] is the sub-list for method output_type
+	21, // [21:29] is the sub-list for method input_type
+	21, // [21:21] is the sub-list for extension type_name
+	21, // [21:21] is the sub-list for extension extendee
+	0,  // [0:21] is the sub-list for field type_name
+}
+
+func init() { file_order_proto_init() }
+func file_order_proto_init() {
+	if File_order_proto != nil {
+		return
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
-			RawDescriptor: file_order_proto_rawDesc,
-			NumEnums:      0,
-			NumMessages:   3,
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_order_proto_rawDesc), len(file_order_proto_rawDesc)),
+			NumEnums:      2,
+			NumMessages:   16,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
 		GoTypes:           file_order_proto_goTypes,
 		DependencyIndexes: file_order_proto_depIdxs,
+		EnumInfos:         file_order_proto_enumTypes,
 		MessageInfos:      file_order_proto_msgTypes,
 	}.Build()
 	File_order_proto = out.File
-	file_order_proto_rawDesc = nil
 	file_order_proto_goTypes = nil
 	file_order_proto_depIdxs = nil
 }
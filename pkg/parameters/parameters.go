@@ -1,14 +1,119 @@
 package parameters
 
+import "time"
+
+const (
+	// DefaultQueryTimeout bounds each repository call when db.queryTimeout is not set
+	DefaultQueryTimeout = 5 * time.Second
+
+	// DefaultSoftDeleteRetention is how long soft-deleted orders are kept when softDeleteRetention is not set
+	DefaultSoftDeleteRetention = 30 * 24 * time.Hour
+
+	// DefaultSSLMode is the Postgres sslmode used when sslMode is not set
+	DefaultSSLMode = "disable"
+
+	// DefaultShutdownTimeout bounds the draining of in-flight requests when shutdownTimeout is not set
+	DefaultShutdownTimeout = 15 * time.Second
+
+	// DefaultHeartbeat is the interval of the keep-alive messages of streams when http.heartbeat is not set
+	DefaultHeartbeat = 15 * time.Second
+
+	// DefaultEventSource is the CloudEvents source of the order events when events.source is not set
+	DefaultEventSource = "/orders"
+)
+
 type Service struct {
-	Http     Http     `yaml:"http" mapstructure:"http" json:"http"`
-	Grpc     Grpc     `yaml:"grpc" mapstructure:"grpc" json:"grpc"`
-	Database Database `yaml:"db" mapstructure:"db" json:"db"`
+	Http            Http          `yaml:"http" mapstructure:"http" json:"http"`
+	Grpc            Grpc          `yaml:"grpc" mapstructure:"grpc" json:"grpc"`
+	Database        Database      `yaml:"db" mapstructure:"db" json:"db"`
+	Events          Events        `yaml:"events" mapstructure:"events" json:"events"`
+	ShutdownTimeout time.Duration `yaml:"shutdownTimeout" mapstructure:"shutdownTimeout" json:"shutdownTimeout"`
+}
+
+// GetShutdownTimeout returns the configured shutdown timeout or DefaultShutdownTimeout
+func (s Service) GetShutdownTimeout() time.Duration {
+	if s.ShutdownTimeout <= 0 {
+		return DefaultShutdownTimeout
+	}
+
+	return s.ShutdownTimeout
 }
 
 type Http struct {
 	Port int    `yaml:"port" mapstructure:"port" json:"port"`
 	Host string `yaml:"host" mapstructure:"host" json:"host"`
+	// AdminToken is the bearer token of the /admin routes; empty disables them
+	AdminToken string `yaml:"adminToken" mapstructure:"adminToken" json:"adminToken"`
+	// SubscriptionToken is the bearer token GraphQL subscription clients send in
+	// connection_init; empty lets any client subscribe
+	SubscriptionToken string `yaml:"subscriptionToken" mapstructure:"subscriptionToken" json:"subscriptionToken"`
+	// Heartbeat is how often an idle change stream sends a keep-alive message
+	Heartbeat time.Duration `yaml:"heartbeat" mapstructure:"heartbeat" json:"heartbeat"`
+}
+
+// GetHeartbeat returns the configured heartbeat interval or DefaultHeartbeat
+func (h Http) GetHeartbeat() time.Duration {
+	if h.Heartbeat <= 0 {
+		return DefaultHeartbeat
+	}
+
+	return h.Heartbeat
+}
+
+// Events configures the delivery of the order events stored in the outbox
+type Events struct {
+	// Publisher is the transport of the events: log (the default), amqp or nats
+	Publisher string `yaml:"publisher" mapstructure:"publisher" json:"publisher"`
+	// Source is the CloudEvents source attribute of every event
+	Source string `yaml:"source" mapstructure:"source" json:"source"`
+	AMQP   AMQP   `yaml:"amqp" mapstructure:"amqp" json:"amqp"`
+	NATS   NATS   `yaml:"nats" mapstructure:"nats" json:"nats"`
+	Relay  Relay  `yaml:"relay" mapstructure:"relay" json:"relay"`
+	// Webhooks tunes the delivery of the events to the webhooks managed under /admin/webhooks
+	Webhooks Webhooks `yaml:"webhooks" mapstructure:"webhooks" json:"webhooks"`
+}
+
+// GetSource returns the configured source or DefaultEventSource
+func (e Events) GetSource() string {
+	if e.Source == "" {
+		return DefaultEventSource
+	}
+
+	return e.Source
+}
+
+// AMQP locates the broker of the amqp publisher; events are routed by type through Exchange
+type AMQP struct {
+	URL      string `yaml:"url" mapstructure:"url" json:"url"`
+	Exchange string `yaml:"exchange" mapstructure:"exchange" json:"exchange"`
+}
+
+// NATS locates the server of the nats publisher; events go to SubjectPrefix.<type>
+type NATS struct {
+	URL           string `yaml:"url" mapstructure:"url" json:"url"`
+	SubjectPrefix string `yaml:"subjectPrefix" mapstructure:"subjectPrefix" json:"subjectPrefix"`
+}
+
+// Relay tunes the outbox relay; zero values take the relay defaults
+type Relay struct {
+	Interval   time.Duration `yaml:"interval" mapstructure:"interval" json:"interval"`
+	BatchSize  int           `yaml:"batchSize" mapstructure:"batchSize" json:"batchSize"`
+	Lease      time.Duration `yaml:"lease" mapstructure:"lease" json:"lease"`
+	MinBackoff time.Duration `yaml:"minBackoff" mapstructure:"minBackoff" json:"minBackoff"`
+	MaxBackoff time.Duration `yaml:"maxBackoff" mapstructure:"maxBackoff" json:"maxBackoff"`
+}
+
+// Webhooks tunes the webhook delivery worker; zero values take the worker defaults
+type Webhooks struct {
+	// Timeout bounds every delivery request
+	Timeout    time.Duration `yaml:"timeout" mapstructure:"timeout" json:"timeout"`
+	Interval   time.Duration `yaml:"interval" mapstructure:"interval" json:"interval"`
+	BatchSize  int           `yaml:"batchSize" mapstructure:"batchSize" json:"batchSize"`
+	Lease      time.Duration `yaml:"lease" mapstructure:"lease" json:"lease"`
+	MinBackoff time.Duration `yaml:"minBackoff" mapstructure:"minBackoff" json:"minBackoff"`
+	MaxBackoff time.Duration `yaml:"maxBackoff" mapstructure:"maxBackoff" json:"maxBackoff"`
+	// MaxAttempts is the number of failed requests after which a delivery is dead
+	MaxAttempts int `yaml:"maxAttempts" mapstructure:"maxAttempts" json:"maxAttempts"`
 }
 
 type Grpc struct {
@@ -17,9 +122,78 @@ type Grpc struct {
 }
 
 type Database struct {
+	Driver   string `yaml:"driver" mapstructure:"driver" json:"driver"`
 	Name     string `yaml:"name" mapstructure:"name" json:"name"`
 	Host     string `yaml:"host" mapstructure:"host" json:"host"`
 	Port     int    `yaml:"port" mapstructure:"port" json:"port"`
 	User     string `yaml:"user" mapstructure:"user" json:"user"`
 	Password string `yaml:"password" mapstructure:"password" json:"password"`
+	// DBName is the database to connect to; Name is used when it is empty
+	DBName string `yaml:"dbName" mapstructure:"dbName" json:"dbName"`
+	// DSN, a key=value string or a postgres:// URL, replaces every connection
+	// setting above and below; pool settings still apply
+	DSN  string `yaml:"dsn" mapstructure:"dsn" json:"dsn"`
+	Path string `yaml:"path" mapstructure:"path" json:"path"`
+
+	// SSLMode defaults to DefaultSSLMode; the certificate paths are passed as is
+	SSLMode     string `yaml:"sslMode" mapstructure:"sslMode" json:"sslMode"`
+	SSLRootCert string `yaml:"sslRootCert" mapstructure:"sslRootCert" json:"sslRootCert"`
+	SSLCert     string `yaml:"sslCert" mapstructure:"sslCert" json:"sslCert"`
+	SSLKey      string `yaml:"sslKey" mapstructure:"sslKey" json:"sslKey"`
+
+	ApplicationName string `yaml:"applicationName" mapstructure:"applicationName" json:"applicationName"`
+	// StatementTimeout makes the server abort statements running longer, zero keeps the server setting
+	StatementTimeout time.Duration `yaml:"statementTimeout" mapstructure:"statementTimeout" json:"statementTimeout"`
+
+	// Pool settings; zero keeps the database/sql defaults
+	MaxIdleConns    int           `yaml:"maxIdleConns" mapstructure:"maxIdleConns" json:"maxIdleConns"`
+	MaxOpenConns    int           `yaml:"maxOpenConns" mapstructure:"maxOpenConns" json:"maxOpenConns"`
+	ConnMaxLifetime time.Duration `yaml:"connMaxLifetime" mapstructure:"connMaxLifetime" json:"connMaxLifetime"`
+	ConnMaxIdleTime time.Duration `yaml:"connMaxIdleTime" mapstructure:"connMaxIdleTime" json:"connMaxIdleTime"`
+
+	QueryTimeout time.Duration `yaml:"queryTimeout" mapstructure:"queryTimeout" json:"queryTimeout"`
+	// AutoMigrate applies the pending migrations when the repository is opened;
+	// otherwise they are applied with the migrate command
+	AutoMigrate bool `yaml:"autoMigrate" mapstructure:"autoMigrate" json:"autoMigrate"`
+	// SoftDeleteRetention is how long soft-deleted orders are kept before the purge removes them
+	SoftDeleteRetention time.Duration `yaml:"softDeleteRetention" mapstructure:"softDeleteRetention" json:"softDeleteRetention"`
+	// SnapshotPath and SnapshotInterval persist the memory backend; empty keeps it in memory only
+	SnapshotPath     string        `yaml:"snapshotPath" mapstructure:"snapshotPath" json:"snapshotPath"`
+	SnapshotInterval time.Duration `yaml:"snapshotInterval" mapstructure:"snapshotInterval" json:"snapshotInterval"`
+}
+
+// GetDBName returns DBName, or Name for configurations written before dbName was read
+func (d Database) GetDBName() string {
+	if d.DBName == "" {
+		return d.Name
+	}
+
+	return d.DBName
+}
+
+// GetSoftDeleteRetention returns the configured retention or DefaultSoftDeleteRetention
+func (d Database) GetSoftDeleteRetention() time.Duration {
+	if d.SoftDeleteRetention <= 0 {
+		return DefaultSoftDeleteRetention
+	}
+
+	return d.SoftDeleteRetention
+}
+
+// GetSSLMode returns the configured sslmode or DefaultSSLMode
+func (d Database) GetSSLMode() string {
+	if d.SSLMode == "" {
+		return DefaultSSLMode
+	}
+
+	return d.SSLMode
+}
+
+// GetQueryTimeout returns the configured query timeout or DefaultQueryTimeout
+func (d Database) GetQueryTimeout() time.Duration {
+	if d.QueryTimeout <= 0 {
+		return DefaultQueryTimeout
+	}
+
+	return d.QueryTimeout
 }
@@ -35,3 +35,42 @@ func HelperJSON(w http.ResponseWriter, r *http.Request, data any) {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 	}
 }
+
+// ProblemField describes a single invalid field of a problem response
+type ProblemField struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Problem is an RFC 7807 problem details document
+type Problem struct {
+	Type      string         `json:"type"`
+	Title     string         `json:"title"`
+	Status    int            `json:"status"`
+	Detail    string         `json:"detail,omitempty"`
+	Instance  string         `json:"instance,omitempty"`
+	RequestID string         `json:"requestId,omitempty"`
+	Errors    []ProblemField `json:"errors,omitempty"`
+}
+
+// HelperProblem writes problem as an application/problem+json response
+func HelperProblem(w http.ResponseWriter, r *http.Request, problem *Problem) {
+	if problem.Type == "" {
+		problem.Type = "about:blank"
+	}
+
+	if problem.Title == "" {
+		problem.Title = http.StatusText(problem.Status)
+	}
+
+	if problem.Instance == "" {
+		problem.Instance = r.URL.RequestURI()
+	}
+
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(problem.Status)
+
+	_ = json.NewEncoder(w).Encode(problem)
+}